@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server"
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the archive database",
+	Long: `Inspect and upgrade the archive database schema. The MCP server applies
+pending migrations on startup; these commands do the same without starting it.`,
+}
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply pending schema migrations",
+	Long: `Apply all pending schema migrations. An existing database is copied to
+the backups directory next to it before it is changed.`,
+	RunE: mcpserver.RunDBMigrate,
+}
+
+var dbStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the schema version and migration status",
+	RunE:  mcpserver.RunDBStatus,
+}
+
+func init() {
+	dbCmd.PersistentFlags().StringP("config", "c", "config.yaml", "Path to configuration file")
+	dbCmd.AddCommand(dbMigrateCmd)
+	dbCmd.AddCommand(dbStatusCmd)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export stacking sets and query results",
+	Long: `Write archive data to files in export.directory from the configuration
+(default: exports next to the database).`,
+}
+
+var exportStackCmd = &cobra.Command{
+	Use:   "stack",
+	Short: "Export a stacking set of light frames",
+	Long: `Write the selected light frames as a stacking set: per-filter lists of
+absolute paths, a lights/<filter> folder of symlinks for PixInsight WBPP and a
+Siril script. Rejected frames are left out.`,
+	Example: `  astro-ai-archiver export stack --target NGC7000 --date-from 2025-09-01
+  astro-ai-archiver export stack --session 42 --name ngc7000-night1 --no-symlinks --no-siril`,
+	RunE: mcpserver.RunExport,
+}
+
+var exportQueryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "Export query results as CSV, JSON Lines or Parquet",
+	Long: `Stream the result of a read-only SQL query, or the frames matching the
+filter flags, to a CSV, JSON Lines or Parquet file. Filter exports contain all
+file columns plus quality metrics and grade; rejected frames are left out.`,
+	Example: `  astro-ai-archiver export query --target M31 --format parquet
+  astro-ai-archiver export query --sql "SELECT object, filter, SUM(exposure) FROM fits_files GROUP BY 1, 2" --name totals`,
+	RunE: mcpserver.RunExportQuery,
+}
+
+func init() {
+	exportCmd.PersistentFlags().StringP("config", "c", "config.yaml", "Path to configuration file")
+	exportCmd.AddCommand(exportStackCmd)
+	exportCmd.AddCommand(exportQueryCmd)
+
+	for _, cmd := range []*cobra.Command{exportStackCmd, exportQueryCmd} {
+		cmd.Flags().StringP("target", "t", "", "Target object name (partial match)")
+		cmd.Flags().StringP("filter", "f", "", "Filter name (exact match)")
+		cmd.Flags().String("telescope", "", "Telescope identifier (partial match)")
+		cmd.Flags().String("camera", "", "Camera identifier (partial match)")
+		cmd.Flags().String("date-from", "", "Start date (YYYY-MM-DD)")
+		cmd.Flags().String("date-to", "", "End date (YYYY-MM-DD)")
+		cmd.Flags().Float64("fwhm-max", 0, "Maximum median star FWHM in pixels")
+	}
+
+	exportStackCmd.Flags().Int64("session", 0, "Session id (see list_sessions)")
+	exportStackCmd.Flags().String("name", "", "Name of the export directory (default: target and time stamp)")
+	exportStackCmd.Flags().Bool("no-symlinks", false, "Do not write the lights/<filter> symlink tree")
+	exportStackCmd.Flags().Bool("no-siril", false, "Do not write the Siril script")
+
+	exportQueryCmd.Flags().String("sql", "", "Read-only SELECT query (instead of the filter flags)")
+	exportQueryCmd.Flags().String("format", "csv", "Output format: csv, jsonl or parquet")
+	exportQueryCmd.Flags().String("name", "", "File name without extension (default: export and time stamp)")
+}
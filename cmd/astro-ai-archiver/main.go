@@ -34,6 +34,12 @@ func main() {
 func init() {
 	// Add commands
 	rootCmd.AddCommand(mcpServerCmd)
+	rootCmd.AddCommand(dbCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(scanCmd)
+	rootCmd.AddCommand(queryCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(sqlCmd)
 
 	// Global flags (if needed)
 	rootCmd.PersistentFlags().StringP("log-level", "l", "info", "Log level (debug, info, warn, error, trace)")
@@ -0,0 +1,74 @@
+package astro
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed catalog.csv
+var catalogCSV string
+
+// CatalogObject is a deep-sky object of the bundled catalog. Coordinates are
+// J2000 in degrees.
+type CatalogObject struct {
+	Designation string
+	Names       []string
+	RA, Dec     float64
+}
+
+var (
+	catalogOnce  sync.Once
+	catalogIndex map[string]*CatalogObject
+)
+
+// LookupObject finds an object of the bundled catalog (the Messier objects
+// and popular NGC, IC and Sharpless targets) by designation or common name.
+// Case, spaces and punctuation are ignored, so "M 31", "m31" and
+// "Andromeda Galaxy" all match.
+func LookupObject(name string) (*CatalogObject, bool) {
+	catalogOnce.Do(loadCatalog)
+	obj, ok := catalogIndex[catalogKey(name)]
+	return obj, ok
+}
+
+// catalogKey reduces a name to lower-case letters and digits
+func catalogKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func loadCatalog() {
+	catalogIndex = map[string]*CatalogObject{}
+
+	r := csv.NewReader(strings.NewReader(catalogCSV))
+	r.Comment = '#'
+	records, err := r.ReadAll()
+	if err != nil {
+		panic("astro: invalid bundled catalog: " + err.Error())
+	}
+	for _, rec := range records[1:] {
+		ra, errRA := ParseRA(rec[1])
+		dec, errDec := ParseDec(rec[2])
+		if errRA != nil || errDec != nil {
+			panic("astro: invalid coordinates in bundled catalog for " + rec[0])
+		}
+		obj := &CatalogObject{Designation: rec[0], RA: ra, Dec: dec}
+		if rec[3] != "" {
+			obj.Names = strings.Split(rec[3], ";")
+		}
+		catalogIndex[catalogKey(obj.Designation)] = obj
+		for _, name := range obj.Names {
+			if key := catalogKey(name); catalogIndex[key] == nil {
+				catalogIndex[key] = obj
+			}
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package astro
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLookupObject(t *testing.T) {
+	for _, name := range []string{"M31", "m 31", "Andromeda Galaxy", "NGC 224"} {
+		obj, ok := LookupObject(name)
+		if !ok || obj.Designation != "M31" {
+			t.Errorf("LookupObject(%q) = %+v, %v; expected M31", name, obj, ok)
+			continue
+		}
+		if math.Abs(obj.RA-10.675) > 0.01 || math.Abs(obj.Dec-(41+16.0/60)) > 0.01 {
+			t.Errorf("M31 at %.3f, %.3f", obj.RA, obj.Dec)
+		}
+	}
+	if obj, ok := LookupObject("sh2-155"); !ok || obj.Names[0] != "Cave Nebula" {
+		t.Errorf("LookupObject(sh2-155) = %+v, %v", obj, ok)
+	}
+	if _, ok := LookupObject("NGC99999"); ok {
+		t.Error("LookupObject(NGC99999) should not match")
+	}
+}
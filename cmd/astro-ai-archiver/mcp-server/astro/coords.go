@@ -0,0 +1,159 @@
+package astro
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseRA parses a Right Ascension and returns it in degrees.
+//
+// Plain decimal numbers are taken as degrees ("83.82"). Sexagesimal values are
+// taken as hours, minutes and seconds and may be separated by colons, spaces or
+// h/m/s markers ("05:35:17.3", "5 35 17.3", "5h35m17.3s").
+func ParseRA(s string) (float64, error) {
+	s = normalizeCoordinate(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty right ascension")
+	}
+
+	var deg float64
+	if isDecimal(s) {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid right ascension %q: %w", s, err)
+		}
+		deg = v
+	} else {
+		hours, err := parseSexagesimal(s, "hms")
+		if err != nil {
+			return 0, fmt.Errorf("invalid right ascension %q: %w", s, err)
+		}
+		deg = hours * 15
+	}
+
+	if deg < 0 || deg >= 360 {
+		return 0, fmt.Errorf("right ascension %q is out of range (0-360° or 0-24h)", s)
+	}
+	return deg, nil
+}
+
+// ParseDec parses a Declination and returns it in degrees.
+//
+// Accepts decimal degrees ("-5.4") and sexagesimal degrees, arcminutes and
+// arcseconds separated by colons, spaces or d/°/'/" markers ("-05:23:28",
+// "−5°23′28″", "+41d16m09s").
+func ParseDec(s string) (float64, error) {
+	deg, err := ParseDegrees(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid declination: %w", err)
+	}
+	if deg < -90 || deg > 90 {
+		return 0, fmt.Errorf("declination %q is out of range (-90° to +90°)", s)
+	}
+	return deg, nil
+}
+
+// ParseDegrees parses an angle in decimal or sexagesimal degrees (see ParseDec)
+// without range checks. It is used for site latitude and longitude, which
+// capture software writes either as numbers or as "+52 05 00" strings.
+func ParseDegrees(s string) (float64, error) {
+	s = normalizeCoordinate(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty angle")
+	}
+
+	if isDecimal(s) {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid angle %q: %w", s, err)
+		}
+		return v, nil
+	}
+
+	v, err := parseSexagesimal(s, "dms")
+	if err != nil {
+		return 0, fmt.Errorf("invalid angle %q: %w", s, err)
+	}
+	return v, nil
+}
+
+// AngularSeparation returns the great-circle distance between two sky
+// positions in degrees, using the haversine formula (stable for small angles).
+func AngularSeparation(ra1, dec1, ra2, dec2 float64) float64 {
+	const rad = math.Pi / 180
+	dRA := (ra2 - ra1) * rad
+	dDec := (dec2 - dec1) * rad
+	a := math.Sin(dDec/2)*math.Sin(dDec/2) +
+		math.Cos(dec1*rad)*math.Cos(dec2*rad)*math.Sin(dRA/2)*math.Sin(dRA/2)
+	return 2 * math.Asin(math.Min(1, math.Sqrt(a))) / rad
+}
+
+// normalizeCoordinate trims the input and replaces typographic variants
+// (unicode minus, degree/prime symbols) with plain separators.
+func normalizeCoordinate(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.NewReplacer(
+		"−", "-", // unicode minus
+		"–", "-", // en dash
+		"°", " ",
+		"′", " ",
+		"″", " ",
+		"'", " ",
+		"\"", " ",
+	).Replace(s)
+	return strings.TrimSpace(s)
+}
+
+// isDecimal reports whether s is a single signed decimal number
+func isDecimal(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// parseSexagesimal parses up to three components (e.g. degrees, minutes,
+// seconds) into a single value. markers holds the optional unit letters for the
+// three components ("hms" or "dms"); a leading sign applies to the whole value.
+func parseSexagesimal(s, markers string) (float64, error) {
+	sign := 1.0
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign = -1
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	// Turn unit markers into separators: "5h35m17s" → "5 35 17 "
+	s = strings.Map(func(r rune) rune {
+		if r == ':' || strings.ContainsRune(markers, r) || strings.ContainsRune(strings.ToUpper(markers), r) {
+			return ' '
+		}
+		return r
+	}, s)
+
+	parts := strings.Fields(s)
+	if len(parts) == 0 || len(parts) > 3 {
+		return 0, fmt.Errorf("expected 1 to 3 components, got %d", len(parts))
+	}
+
+	value := 0.0
+	scale := 1.0
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid component %q", part)
+		}
+		if v < 0 {
+			return 0, fmt.Errorf("only the first component may carry a sign")
+		}
+		if i > 0 && v >= 60 {
+			return 0, fmt.Errorf("component %q must be below 60", part)
+		}
+		value += v / scale
+		scale *= 60
+	}
+
+	return sign * value, nil
+}
@@ -0,0 +1,103 @@
+package astro
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseRA(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected float64
+		wantErr  bool
+	}{
+		{name: "Decimal degrees", input: "83.8", expected: 83.8},
+		{name: "Colon separated hours", input: "05:35:17.3", expected: (5 + 35.0/60 + 17.3/3600) * 15},
+		{name: "Space separated hours", input: "5 35 17.3", expected: (5 + 35.0/60 + 17.3/3600) * 15},
+		{name: "Hms markers", input: "5h35m17.3s", expected: (5 + 35.0/60 + 17.3/3600) * 15},
+		{name: "Hours and minutes only", input: "23:30", expected: 23.5 * 15},
+		{name: "Out of range", input: "24:00:01", wantErr: true},
+		{name: "Minutes too large", input: "05:75:00", wantErr: true},
+		{name: "Empty", input: "", wantErr: true},
+		{name: "Garbage", input: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRA(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseRA(%q) = %f, expected an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRA(%q) returned error: %v", tt.input, err)
+			}
+			if math.Abs(got-tt.expected) > 1e-9 {
+				t.Errorf("ParseRA(%q) = %f, expected %f", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseDec(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected float64
+		wantErr  bool
+	}{
+		{name: "Decimal degrees", input: "-5.4", expected: -5.4},
+		{name: "Unicode minus decimal", input: "−5.4", expected: -5.4},
+		{name: "Colon separated", input: "-05:23:28", expected: -(5 + 23.0/60 + 28.0/3600)},
+		{name: "Degree symbols", input: "−5°23′28″", expected: -(5 + 23.0/60 + 28.0/3600)},
+		{name: "Ascii quotes", input: "+41°16'09\"", expected: 41 + 16.0/60 + 9.0/3600},
+		{name: "Dms markers", input: "+41d16m09s", expected: 41 + 16.0/60 + 9.0/3600},
+		{name: "Negative zero degrees", input: "-00:30:00", expected: -0.5},
+		{name: "Out of range", input: "91", wantErr: true},
+		{name: "Sign on later component", input: "5 -23 28", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDec(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseDec(%q) = %f, expected an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDec(%q) returned error: %v", tt.input, err)
+			}
+			if math.Abs(got-tt.expected) > 1e-9 {
+				t.Errorf("ParseDec(%q) = %f, expected %f", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAngularSeparation(t *testing.T) {
+	tests := []struct {
+		name                 string
+		ra1, dec1, ra2, dec2 float64
+		expected             float64
+	}{
+		{name: "Same position", ra1: 83.8, dec1: -5.4, ra2: 83.8, dec2: -5.4, expected: 0},
+		{name: "Along the equator", ra1: 10, dec1: 0, ra2: 12, dec2: 0, expected: 2},
+		{name: "Across RA zero", ra1: 359.5, dec1: 0, ra2: 0.5, dec2: 0, expected: 1},
+		{name: "Pole to equator", ra1: 0, dec1: 90, ra2: 123, dec2: 0, expected: 90},
+		{name: "RA shrinks with declination", ra1: 0, dec1: 60, ra2: 2, dec2: 60, expected: 0.99990},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AngularSeparation(tt.ra1, tt.dec1, tt.ra2, tt.dec2)
+			if math.Abs(got-tt.expected) > 1e-4 {
+				t.Errorf("AngularSeparation() = %f, expected %f", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+// Low-precision positions of the Sun and Moon after the Astronomical
+// Almanac's formulae, good to about 0.01° for the Sun and 0.3° for the Moon
+// between 1950 and 2050. That is plenty for moon avoidance and airmass and
+// needs no ephemeris files or network access.
+
+const (
+	rad           = math.Pi / 180
+	j2000         = 2451545.0   // Julian day of J2000.0
+	earthRadiusKm = 6378.14     // Equatorial radius
+	auKm          = 149597870.7 // Astronomical unit
+)
+
+// JulianDay returns the Julian day of t.
+func JulianDay(t time.Time) float64 {
+	return float64(t.UTC().UnixNano())/86400e9 + 2440587.5
+}
+
+// SunPosition returns the geocentric apparent RA and Dec of the Sun in degrees
+// and its distance in astronomical units.
+func SunPosition(jd float64) (ra, dec, distance float64) {
+	n := jd - j2000
+	l := 280.460 + 0.9856474*n
+	g := (357.528 + 0.9856003*n) * rad
+	lambda := l + 1.915*math.Sin(g) + 0.020*math.Sin(2*g)
+	distance = 1.00014 - 0.01671*math.Cos(g) - 0.00014*math.Cos(2*g)
+	ra, dec = eclipticToEquatorial(lambda, 0, jd)
+	return ra, dec, distance
+}
+
+// MoonPosition returns the geocentric RA and Dec of the Moon in degrees and
+// its distance in kilometres.
+func MoonPosition(jd float64) (ra, dec, distance float64) {
+	t := (jd - j2000) / 36525
+	term := func(a, b float64) float64 { return (a + b*t) * rad }
+
+	lambda := 218.32 + 481267.881*t +
+		6.29*math.Sin(term(135.0, 477198.87)) -
+		1.27*math.Sin(term(259.3, -413335.36)) +
+		0.66*math.Sin(term(235.7, 890534.22)) +
+		0.21*math.Sin(term(269.9, 954397.74)) -
+		0.19*math.Sin(term(357.5, 35999.05)) -
+		0.11*math.Sin(term(186.5, 966404.03))
+	beta := 5.13*math.Sin(term(93.3, 483202.02)) +
+		0.28*math.Sin(term(228.2, 960400.89)) -
+		0.28*math.Sin(term(318.3, 6003.15)) -
+		0.17*math.Sin(term(217.6, -407332.21))
+	parallax := 0.9508 +
+		0.0518*math.Cos(term(135.0, 477198.87)) +
+		0.0095*math.Cos(term(259.3, -413335.36)) +
+		0.0078*math.Cos(term(235.7, 890534.22)) +
+		0.0028*math.Cos(term(269.9, 954397.74))
+
+	ra, dec = eclipticToEquatorial(lambda, beta, jd)
+	return ra, dec, earthRadiusKm / math.Sin(parallax*rad)
+}
+
+// MoonIllumination returns the illuminated fraction of the Moon's disk, from
+// 0 (new) to 1 (full).
+func MoonIllumination(jd float64) float64 {
+	sunRA, sunDec, sunDistance := SunPosition(jd)
+	moonRA, moonDec, moonDistance := MoonPosition(jd)
+
+	elongation := AngularSeparation(sunRA, sunDec, moonRA, moonDec) * rad
+	r := sunDistance * auKm
+	phaseAngle := math.Atan2(r*math.Sin(elongation), moonDistance-r*math.Cos(elongation))
+	return (1 + math.Cos(phaseAngle)) / 2
+}
+
+// Horizontal converts RA and Dec (degrees) to altitude and azimuth (degrees,
+// azimuth from north through east) for an observer at latitude lat and
+// longitude lon (degrees, east positive). Refraction is ignored.
+func Horizontal(ra, dec, lat, lon, jd float64) (alt, az float64) {
+	hourAngle := (LocalSiderealTime(jd, lon) - ra) * rad
+	latRad, decRad := lat*rad, dec*rad
+
+	sinAlt := math.Sin(latRad)*math.Sin(decRad) + math.Cos(latRad)*math.Cos(decRad)*math.Cos(hourAngle)
+	alt = math.Asin(math.Max(-1, math.Min(1, sinAlt)))
+	az = math.Atan2(-math.Sin(hourAngle)*math.Cos(decRad),
+		math.Cos(latRad)*math.Sin(decRad)-math.Sin(latRad)*math.Cos(decRad)*math.Cos(hourAngle))
+	return alt / rad, NormalizeDegrees(az / rad)
+}
+
+// MoonAltitude returns the topocentric altitude of the Moon in degrees, which
+// differs from the geocentric one by up to a degree near the horizon.
+func MoonAltitude(lat, lon, jd float64) float64 {
+	ra, dec, distance := MoonPosition(jd)
+	alt, _ := Horizontal(ra, dec, lat, lon, jd)
+	parallax := math.Asin(earthRadiusKm/distance) / rad
+	return alt - parallax*math.Cos(alt*rad)
+}
+
+// LocalSiderealTime returns the local mean sidereal time in degrees.
+func LocalSiderealTime(jd, lon float64) float64 {
+	t := (jd - j2000) / 36525
+	gmst := 280.46061837 + 360.98564736629*(jd-j2000) + 0.000387933*t*t - t*t*t/38710000
+	return NormalizeDegrees(gmst + lon)
+}
+
+// Airmass returns the relative airmass at an altitude in degrees, after
+// Kasten and Young (1989), which stays finite down to the horizon. It
+// returns false for altitudes below the horizon.
+func Airmass(alt float64) (float64, bool) {
+	if alt < 0 {
+		return 0, false
+	}
+	return 1 / (math.Sin(alt*rad) + 0.50572*math.Pow(alt+6.07995, -1.6364)), true
+}
+
+// eclipticToEquatorial converts ecliptic longitude and latitude (degrees) to
+// RA and Dec (degrees) with the obliquity of date.
+func eclipticToEquatorial(lambda, beta, jd float64) (ra, dec float64) {
+	epsilon := (23.439 - 0.0000004*(jd-j2000)) * rad
+	l, b := lambda*rad, beta*rad
+	ra = math.Atan2(math.Sin(l)*math.Cos(epsilon)-math.Tan(b)*math.Sin(epsilon), math.Cos(l))
+	dec = math.Asin(math.Sin(b)*math.Cos(epsilon) + math.Cos(b)*math.Sin(epsilon)*math.Sin(l))
+	return NormalizeDegrees(ra / rad), dec / rad
+}
+
+// AstronomicalTwilight is the altitude of the Sun's centre at the start and
+// end of astronomical darkness
+const AstronomicalTwilight = -18.0
+
+// SunAltitude returns the altitude of the Sun's centre in degrees.
+func SunAltitude(lat, lon, jd float64) float64 {
+	ra, dec, _ := SunPosition(jd)
+	alt, _ := Horizontal(ra, dec, lat, lon, jd)
+	return alt
+}
+
+// Darkness returns the first period of astronomical darkness (the Sun more
+// than 18° below the horizon) within the 24 hours after from, to the nearest
+// second. Start is from when it is already dark then and end is from + 24h
+// when it is still dark. It returns false when it never gets dark, as in
+// summer at high latitudes.
+func Darkness(from time.Time, lat, lon float64) (start, end time.Time, ok bool) {
+	const step = 10 * time.Minute
+	dark := func(t time.Time) bool {
+		return SunAltitude(lat, lon, JulianDay(t)) < AstronomicalTwilight
+	}
+	// crossing narrows down the moment between a and b where dark(t) flips
+	crossing := func(a, b time.Time) time.Time {
+		before := dark(a)
+		for b.Sub(a) > time.Second {
+			mid := a.Add(b.Sub(a) / 2)
+			if dark(mid) == before {
+				a = mid
+			} else {
+				b = mid
+			}
+		}
+		return b
+	}
+
+	until := from.Add(24 * time.Hour)
+	prev, prevDark := from, dark(from)
+	if prevDark {
+		start, ok = from, true
+	}
+	for t := from.Add(step); !t.After(until); t = t.Add(step) {
+		isDark := dark(t)
+		switch {
+		case isDark && !prevDark && !ok:
+			start, ok = crossing(prev, t), true
+		case !isDark && prevDark && ok:
+			return start, crossing(prev, t), true
+		}
+		prev, prevDark = t, isDark
+	}
+	if ok {
+		end = until
+	}
+	return start, end, ok
+}
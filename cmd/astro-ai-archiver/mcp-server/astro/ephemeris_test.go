@@ -0,0 +1,85 @@
+package astro
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Reference values are from Meeus, Astronomical Algorithms (2nd ed.)
+
+func TestJulianDay(t *testing.T) {
+	jd := JulianDay(time.Date(1987, 4, 10, 19, 21, 0, 0, time.UTC))
+	if math.Abs(jd-2446896.30625) > 1e-6 {
+		t.Errorf("JulianDay = %.6f, expected 2446896.30625", jd)
+	}
+}
+
+func TestSunAndMoonPosition(t *testing.T) {
+	// Example 25.a: 1992 October 13, 0h TD
+	ra, dec, _ := SunPosition(2448908.5)
+	if math.Abs(ra-198.38083) > 0.02 || math.Abs(dec+7.78507) > 0.02 {
+		t.Errorf("SunPosition = %.4f, %.4f; expected 198.3808, -7.7851", ra, dec)
+	}
+
+	// Example 47.a: 1992 April 12, 0h TD
+	ra, dec, distance := MoonPosition(2448724.5)
+	if AngularSeparation(ra, dec, 134.688470, 13.768368) > 0.3 || math.Abs(distance-368409.7) > 1000 {
+		t.Errorf("MoonPosition = %.4f, %.4f, %.0f km; expected 134.6885, 13.7684, 368410 km", ra, dec, distance)
+	}
+
+	// Example 48.a: same date, 67.86% illuminated
+	if k := MoonIllumination(2448724.5); math.Abs(k-0.6786) > 0.01 {
+		t.Errorf("MoonIllumination = %.4f, expected 0.6786", k)
+	}
+}
+
+func TestHorizontal(t *testing.T) {
+	// Example 13.b: Venus from the US Naval Observatory, 1987 April 10, 19:21 UT.
+	// Meeus counts azimuth from the south; 68.0337° there is 248.0337° from north.
+	lat := 38 + 55.0/60 + 17.0/3600
+	lon := -(77 + 3.0/60 + 56.0/3600)
+	alt, az := Horizontal(347.3193375, -6.719892, lat, lon, 2446896.30625)
+	if math.Abs(alt-15.1249) > 0.01 || math.Abs(az-248.0337) > 0.01 {
+		t.Errorf("Horizontal = %.4f, %.4f; expected 15.1249, 248.0337", alt, az)
+	}
+}
+
+func TestAirmass(t *testing.T) {
+	if x, ok := Airmass(90); !ok || math.Abs(x-1) > 0.001 {
+		t.Errorf("Airmass(90) = %.4f, %v; expected 1", x, ok)
+	}
+	if x, _ := Airmass(30); math.Abs(x-1.995) > 0.01 {
+		t.Errorf("Airmass(30) = %.4f, expected 1.995", x)
+	}
+	if x, _ := Airmass(0); math.Abs(x-38) > 0.1 {
+		t.Errorf("Airmass(0) = %.4f, expected 38", x)
+	}
+	if _, ok := Airmass(-1); ok {
+		t.Error("Airmass below the horizon should not be defined")
+	}
+}
+
+func TestDarkness(t *testing.T) {
+	// Equator at the March equinox: the Sun sets at about 18:07 UTC (solar
+	// noon is 12:07:30) and sinks 15° per hour, so darkness starts 72 minutes
+	// after sunset and ends 72 minutes before sunrise.
+	noon := time.Date(2025, 3, 20, 12, 0, 0, 0, time.UTC)
+	start, end, ok := Darkness(noon, 0, 0)
+	expectedStart := time.Date(2025, 3, 20, 19, 19, 30, 0, time.UTC)
+	expectedEnd := time.Date(2025, 3, 21, 4, 55, 20, 0, time.UTC)
+	if !ok || start.Sub(expectedStart).Abs() > 3*time.Minute || end.Sub(expectedEnd).Abs() > 3*time.Minute {
+		t.Errorf("Darkness = %v - %v, %v; expected about %v - %v", start, end, ok, expectedStart, expectedEnd)
+	}
+
+	// No astronomical darkness around the June solstice at 52° north
+	if _, _, ok := Darkness(time.Date(2025, 6, 21, 12, 0, 0, 0, time.UTC), 52, 5); ok {
+		t.Error("Darkness at 52° north in June should not exist")
+	}
+
+	// Polar night: dark for the whole search window
+	from := time.Date(2025, 12, 21, 12, 0, 0, 0, time.UTC)
+	if start, end, ok := Darkness(from, 85, 0); !ok || !start.Equal(from) || !end.Equal(from.Add(24*time.Hour)) {
+		t.Errorf("Darkness at 85° north in December = %v - %v, %v", start, end, ok)
+	}
+}
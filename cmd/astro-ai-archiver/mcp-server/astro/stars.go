@@ -0,0 +1,261 @@
+package astro
+
+import (
+	"math"
+	"sort"
+)
+
+// Star detection and measurement parameters
+const (
+	detectionSigma  = 5.0     // Peak threshold above the background, in noise units
+	neighbourSigma  = 2.0     // Level the direct neighbours of a peak must exceed, rejects hot pixels
+	starRadius      = 10      // Measurement window radius in pixels
+	maxMeasured     = 500     // Brightest stars measured per frame
+	saturationLevel = 0.98    // Fraction of the clip level at which a star counts as saturated
+	clippedPixels   = 4       // Pixels at the frame maximum that mark the frame as clipped
+	statsSamples    = 1 << 20 // Pixels sampled for the background statistics
+)
+
+// Star is a detected star with its shape measurements. Positions are 0-based
+// pixel coordinates of the flux-weighted centroid.
+type Star struct {
+	X            float64
+	Y            float64
+	Peak         float64 // Background-subtracted peak value
+	Flux         float64 // Background-subtracted flux within the measurement window
+	HFR          float64 // Flux-weighted mean radius in pixels
+	FWHM         float64 // Full width at half maximum in pixels
+	Eccentricity float64 // 0 for round stars, approaching 1 for elongated ones
+}
+
+// FrameQuality summarises a frame: the background level and noise, plus the
+// median shape of its stars. The shape values are 0 when no stars were found.
+type FrameQuality struct {
+	Background   float64
+	Noise        float64
+	StarCount    int
+	HFR          float64
+	FWHM         float64
+	Eccentricity float64
+}
+
+// MeasureFrame computes the quality metrics of a single-channel image stored
+// row by row.
+func MeasureFrame(pixels []float32, width, height int) FrameQuality {
+	background, noise := BackgroundNoise(pixels)
+	q := FrameQuality{Background: background, Noise: noise}
+
+	stars, total := DetectStars(pixels, width, height, background, noise)
+	q.StarCount = total
+	if len(stars) == 0 {
+		return q
+	}
+
+	hfr := make([]float64, len(stars))
+	fwhm := make([]float64, len(stars))
+	ecc := make([]float64, len(stars))
+	for i, s := range stars {
+		hfr[i], fwhm[i], ecc[i] = s.HFR, s.FWHM, s.Eccentricity
+	}
+	q.HFR = median(hfr)
+	q.FWHM = median(fwhm)
+	q.Eccentricity = median(ecc)
+	return q
+}
+
+// BackgroundNoise estimates the sky background as the median pixel value and
+// the noise as the scaled median absolute deviation, which both ignore stars.
+// Large images are sampled.
+func BackgroundNoise(pixels []float32) (background, noise float64) {
+	if len(pixels) == 0 {
+		return 0, 0
+	}
+
+	step := len(pixels)/statsSamples + 1
+	sample := make([]float64, 0, len(pixels)/step+1)
+	for i := 0; i < len(pixels); i += step {
+		sample = append(sample, float64(pixels[i]))
+	}
+
+	background = median(sample)
+	for i, v := range sample {
+		sample[i] = math.Abs(v - background)
+	}
+	noise = 1.4826 * median(sample)
+	return background, noise
+}
+
+// DetectStars finds local maxima at least detectionSigma above the background
+// and measures the brightest maxMeasured of them. Saturated stars, hot pixels
+// and stars too close to the border or to a brighter star are skipped. It
+// returns the measured stars and the total number of stars found.
+func DetectStars(pixels []float32, width, height int, background, noise float64) ([]Star, int) {
+	if width <= 2*starRadius || height <= 2*starRadius || len(pixels) < width*height {
+		return nil, 0
+	}
+	if noise <= 0 {
+		noise = 1
+	}
+
+	// A frame is clipped when several pixels share its maximum value; stars
+	// near that level are skipped because their profile is flattened.
+	var frameMax float32
+	atMax := 0
+	for _, v := range pixels[:width*height] {
+		switch {
+		case v > frameMax:
+			frameMax, atMax = v, 1
+		case v == frameMax:
+			atMax++
+		}
+	}
+	saturated := math.Inf(1)
+	if atMax >= clippedPixels {
+		saturated = float64(frameMax) * saturationLevel
+	}
+
+	threshold := float32(background + detectionSigma*noise)
+	neighbourMin := float32(background + neighbourSigma*noise)
+
+	type peak struct {
+		x, y int
+		v    float32
+	}
+	var peaks []peak
+	for y := starRadius; y < height-starRadius; y++ {
+		row := y * width
+		for x := starRadius; x < width-starRadius; x++ {
+			v := pixels[row+x]
+			if v < threshold || float64(v) >= saturated {
+				continue
+			}
+
+			// Strict maximum against the pixels before it, so a flat top
+			// yields a single peak
+			up, down := row-width+x, row+width+x
+			if pixels[up-1] >= v || pixels[up] >= v || pixels[up+1] >= v || pixels[row+x-1] >= v ||
+				pixels[row+x+1] > v || pixels[down-1] > v || pixels[down] > v || pixels[down+1] > v {
+				continue
+			}
+			if pixels[up] < neighbourMin || pixels[down] < neighbourMin ||
+				pixels[row+x-1] < neighbourMin || pixels[row+x+1] < neighbourMin {
+				continue
+			}
+			peaks = append(peaks, peak{x, y, v})
+		}
+	}
+
+	sort.Slice(peaks, func(i, j int) bool { return peaks[i].v > peaks[j].v })
+
+	// Keep peaks that are not within the window of a brighter one
+	var accepted []peak
+	const minDist2 = starRadius * starRadius
+	for _, p := range peaks {
+		isolated := true
+		for _, a := range accepted {
+			dx, dy := p.x-a.x, p.y-a.y
+			if dx*dx+dy*dy < minDist2 {
+				isolated = false
+				break
+			}
+		}
+		if isolated {
+			accepted = append(accepted, p)
+		}
+	}
+
+	stars := make([]Star, 0, min(len(accepted), maxMeasured))
+	for _, p := range accepted[:min(len(accepted), maxMeasured)] {
+		if s, ok := measureStar(pixels, width, p.x, p.y, background); ok {
+			stars = append(stars, s)
+		}
+	}
+	return stars, len(accepted)
+}
+
+// measureStar measures the star whose peak is at (px, py) within a circular
+// window of starRadius pixels.
+func measureStar(pixels []float32, width, px, py int, background float64) (Star, bool) {
+	const r2max = starRadius * starRadius
+
+	// Centroid and flux
+	var flux, sx, sy, peakValue float64
+	for dy := -starRadius; dy <= starRadius; dy++ {
+		for dx := -starRadius; dx <= starRadius; dx++ {
+			if dx*dx+dy*dy > r2max {
+				continue
+			}
+			s := float64(pixels[(py+dy)*width+px+dx]) - background
+			if s <= 0 {
+				continue
+			}
+			flux += s
+			sx += s * float64(dx)
+			sy += s * float64(dy)
+			peakValue = math.Max(peakValue, s)
+		}
+	}
+	if flux <= 0 {
+		return Star{}, false
+	}
+	cx, cy := sx/flux, sy/flux
+
+	// Radial and second moments around the centroid, and the area above half
+	// maximum
+	var sumR, mxx, myy, mxy, halfArea float64
+	for dy := -starRadius; dy <= starRadius; dy++ {
+		for dx := -starRadius; dx <= starRadius; dx++ {
+			if dx*dx+dy*dy > r2max {
+				continue
+			}
+			s := float64(pixels[(py+dy)*width+px+dx]) - background
+			if s >= peakValue/2 {
+				halfArea++
+			}
+			if s <= 0 {
+				continue
+			}
+			ddx, ddy := float64(dx)-cx, float64(dy)-cy
+			sumR += s * math.Hypot(ddx, ddy)
+			if s >= peakValue/10 {
+				mxx += s * ddx * ddx
+				myy += s * ddy * ddy
+				mxy += s * ddx * ddy
+			}
+		}
+	}
+
+	// Eigenvalues of the second-moment matrix give the axes of the star
+	var ecc float64
+	tr := mxx + myy
+	if tr > 0 {
+		d := math.Sqrt((mxx-myy)*(mxx-myy) + 4*mxy*mxy)
+		major, minor := (tr+d)/2, (tr-d)/2
+		if major > 0 {
+			ecc = math.Sqrt(math.Max(0, 1-minor/major))
+		}
+	}
+
+	return Star{
+		X:            float64(px) + cx,
+		Y:            float64(py) + cy,
+		Peak:         peakValue,
+		Flux:         flux,
+		HFR:          sumR / flux,
+		FWHM:         2 * math.Sqrt(halfArea/math.Pi),
+		Eccentricity: ecc,
+	}, true
+}
+
+// median returns the median of values; the slice is sorted in place.
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
@@ -0,0 +1,84 @@
+package astro
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// syntheticFrame renders a noisy sky with a grid of elliptical Gaussian stars.
+func syntheticFrame(width, height int, background, noise, sigmaX, sigmaY float64, seed int64) []float32 {
+	rng := rand.New(rand.NewSource(seed))
+	pixels := make([]float32, width*height)
+	for i := range pixels {
+		pixels[i] = float32(background + rng.NormFloat64()*noise)
+	}
+
+	for sy := 30; sy < height-30; sy += 40 {
+		for sx := 30; sx < width-30; sx += 40 {
+			cx := float64(sx) + rng.Float64() - 0.5
+			cy := float64(sy) + rng.Float64() - 0.5
+			amp := 500 + rng.Float64()*3000
+			for y := sy - 15; y <= sy+15; y++ {
+				for x := sx - 15; x <= sx+15; x++ {
+					dx, dy := float64(x)-cx, float64(y)-cy
+					v := amp * math.Exp(-(dx*dx/(2*sigmaX*sigmaX) + dy*dy/(2*sigmaY*sigmaY)))
+					pixels[y*width+x] += float32(v)
+				}
+			}
+		}
+	}
+	return pixels
+}
+
+func TestMeasureFrameRoundStars(t *testing.T) {
+	const sigma = 1.5
+	pixels := syntheticFrame(400, 400, 1000, 10, sigma, sigma, 1)
+	q := MeasureFrame(pixels, 400, 400)
+
+	if math.Abs(q.Background-1000) > 2 {
+		t.Errorf("Background = %.2f, expected ~1000", q.Background)
+	}
+	if math.Abs(q.Noise-10) > 1 {
+		t.Errorf("Noise = %.2f, expected ~10", q.Noise)
+	}
+	if q.StarCount != 81 {
+		t.Errorf("StarCount = %d, expected 81", q.StarCount)
+	}
+
+	wantFWHM := 2.3548 * sigma
+	if math.Abs(q.FWHM-wantFWHM) > 0.5 {
+		t.Errorf("FWHM = %.2f, expected ~%.2f", q.FWHM, wantFWHM)
+	}
+	if q.HFR < sigma || q.HFR > 2.5*sigma {
+		t.Errorf("HFR = %.2f, expected between %.2f and %.2f", q.HFR, sigma, 2.5*sigma)
+	}
+	if q.Eccentricity > 0.35 {
+		t.Errorf("Eccentricity = %.2f, expected round stars", q.Eccentricity)
+	}
+}
+
+func TestMeasureFrameElongatedStars(t *testing.T) {
+	pixels := syntheticFrame(400, 400, 1000, 10, 3, 1.5, 2)
+	q := MeasureFrame(pixels, 400, 400)
+
+	// e = sqrt(1 - (b/a)^2) = sqrt(0.75) for a 2:1 axis ratio
+	if math.Abs(q.Eccentricity-0.866) > 0.1 {
+		t.Errorf("Eccentricity = %.2f, expected ~0.87", q.Eccentricity)
+	}
+}
+
+func TestMeasureFrameIgnoresHotPixels(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	pixels := make([]float32, 200*200)
+	for i := range pixels {
+		pixels[i] = float32(1000 + rng.NormFloat64()*10)
+	}
+	for i := 0; i < 50; i++ {
+		pixels[rng.Intn(len(pixels))] = 5000
+	}
+
+	if q := MeasureFrame(pixels, 200, 200); q.StarCount != 0 {
+		t.Errorf("StarCount = %d, expected hot pixels to be ignored", q.StarCount)
+	}
+}
@@ -0,0 +1,79 @@
+// Package astro contains the astronomical math used by the archiver:
+// world coordinate system projections and angle helpers.
+package astro
+
+import (
+	"math"
+)
+
+// WCS describes a linear world coordinate system with a gnomonic (TAN)
+// projection, as written by plate solvers (ASTAP, PlateSolve2, astrometry.net).
+// SIP distortion terms are ignored; they only matter at the sub-pixel level.
+type WCS struct {
+	CRVAL1, CRVAL2 float64       // Reference point on the sky (RA, Dec in degrees)
+	CRPIX1, CRPIX2 float64       // Reference pixel (1-based, FITS convention)
+	CD             [2][2]float64 // Linear transformation matrix in degrees per pixel
+}
+
+// NewWCSFromCDELT builds a WCS from the older CDELT/CROTA2 keyword convention.
+func NewWCSFromCDELT(crval1, crval2, crpix1, crpix2, cdelt1, cdelt2, crota2 float64) WCS {
+	rho := crota2 * math.Pi / 180
+	return WCS{
+		CRVAL1: crval1,
+		CRVAL2: crval2,
+		CRPIX1: crpix1,
+		CRPIX2: crpix2,
+		CD: [2][2]float64{
+			{cdelt1 * math.Cos(rho), -cdelt2 * math.Sin(rho)},
+			{cdelt1 * math.Sin(rho), cdelt2 * math.Cos(rho)},
+		},
+	}
+}
+
+// PixelToSky converts a 1-based pixel coordinate to RA/Dec in degrees.
+func (w WCS) PixelToSky(x, y float64) (ra, dec float64) {
+	dx := x - w.CRPIX1
+	dy := y - w.CRPIX2
+
+	// Intermediate world coordinates (standard coordinates ξ, η) in radians
+	xi := (w.CD[0][0]*dx + w.CD[0][1]*dy) * math.Pi / 180
+	eta := (w.CD[1][0]*dx + w.CD[1][1]*dy) * math.Pi / 180
+
+	ra0 := w.CRVAL1 * math.Pi / 180
+	dec0 := w.CRVAL2 * math.Pi / 180
+
+	rho := math.Hypot(xi, eta)
+	if rho == 0 {
+		return NormalizeDegrees(w.CRVAL1), w.CRVAL2
+	}
+
+	c := math.Atan(rho)
+	sinC, cosC := math.Sin(c), math.Cos(c)
+	sinDec0, cosDec0 := math.Sin(dec0), math.Cos(dec0)
+
+	decRad := math.Asin(cosC*sinDec0 + eta*sinC*cosDec0/rho)
+	raRad := ra0 + math.Atan2(xi*sinC, rho*cosDec0*cosC-eta*sinDec0*sinC)
+
+	return NormalizeDegrees(raRad * 180 / math.Pi), decRad * 180 / math.Pi
+}
+
+// PixelScale returns the mean pixel scale in arcseconds per pixel.
+func (w WCS) PixelScale() float64 {
+	det := w.CD[0][0]*w.CD[1][1] - w.CD[0][1]*w.CD[1][0]
+	return math.Sqrt(math.Abs(det)) * 3600
+}
+
+// Rotation returns the position angle of the image Y axis in degrees east of
+// north, following the CROTA2 convention, normalized to [0, 360).
+func (w WCS) Rotation() float64 {
+	return NormalizeDegrees(math.Atan2(-w.CD[0][1], w.CD[1][1]) * 180 / math.Pi)
+}
+
+// NormalizeDegrees wraps an angle into the range [0, 360).
+func NormalizeDegrees(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	return deg
+}
@@ -0,0 +1,181 @@
+package mcpserver
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+// astrobinColumns is the header of AstroBin's acquisition CSV import. Columns
+// the archive knows nothing about are left empty.
+var astrobinColumns = []string{
+	"date", "filter", "number", "duration", "iso", "binning", "gain", "sensorCooling", "fNumber",
+	"darks", "flats", "flatDarks", "bias", "bortle", "meanSqm", "meanFwhm", "temperature",
+}
+
+// SetAstroBinFilters sets the mapping of filter names to AstroBin filter ids.
+func (db *Database) SetAstroBinFilters(filters []AstroBinFilterConfig) {
+	db.astrobinFilters = filters
+}
+
+// astrobinFilterID returns the AstroBin id of a filter, preferring an exact
+// name match over a case-insensitive one; 0 means not mapped.
+func (d *Database) astrobinFilterID(filter string) int {
+	id := 0
+	for _, f := range d.astrobinFilters {
+		if f.Name == filter {
+			return f.ID
+		}
+		if id == 0 && strings.EqualFold(f.Name, filter) {
+			id = f.ID
+		}
+	}
+	return id
+}
+
+// AstroBinAcquisitions groups the lights of a target, or of a project's
+// target, telescope and camera, by observation date, filter, exposure, gain
+// and binning, and renders the groups as an AstroBin acquisition CSV.
+// Rejected frames are left out and duplicate copies of a frame are counted
+// once, as in project_progress.
+func (d *Database) AstroBinAcquisitions(target, project string) (*tools.AstroBinResult, error) {
+	result := &tools.AstroBinResult{Target: target, Sessions: []tools.AstroBinSession{}}
+
+	var telescope, camera string
+	if project != "" {
+		projects, err := d.loadProjects(project)
+		if err != nil {
+			return nil, err
+		}
+		if len(projects) == 0 {
+			return nil, fmt.Errorf("project not found: %s", project)
+		}
+		p := projects[0]
+		result.Project = p.Name
+		result.Target = p.Target
+		telescope, camera = p.Telescope, p.Camera
+	}
+	if result.Target == "" {
+		return nil, fmt.Errorf("a target or a project is required")
+	}
+
+	where := " WHERE f.object = ? COLLATE NOCASE AND f.observation_date IS NOT NULL"
+	args := []interface{}{result.Target}
+	if telescope != "" {
+		where += " AND f.telescope LIKE ?"
+		args = append(args, "%"+telescope+"%")
+	}
+	if camera != "" {
+		where += " AND f.camera LIKE ?"
+		args = append(args, "%"+camera+"%")
+	}
+
+	rows, err := d.db.Query(`
+		SELECT observation_date, filter, exposure, gain, binning, COUNT(*), AVG(cooling), AVG(fwhm)
+		FROM (
+			SELECT MAX(f.observation_date) AS observation_date, MAX(f.filter) AS filter,
+			       MAX(f.exposure) AS exposure, MAX(f.gain) AS gain, MAX(f.x_binning) AS binning,
+			       MAX(COALESCE(f.set_temp, f.ccd_temp)) AS cooling, MAX(m.fwhm * w.pixel_scale) AS fwhm
+			FROM usable_files f
+			LEFT JOIN frame_metrics m ON m.file_id = f.id
+			LEFT JOIN fits_wcs w ON w.file_id = f.id`+where+`
+			GROUP BY COALESCE(f.hash, 'id:' || f.id)
+		)
+		GROUP BY observation_date, filter, exposure, gain, binning
+		ORDER BY observation_date, filter, exposure
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to group acquisitions: %w", err)
+	}
+	defer rows.Close()
+
+	unmapped := map[string]bool{}
+	var seconds float64
+	for rows.Next() {
+		var s tools.AstroBinSession
+		var gain, cooling, fwhm sql.NullFloat64
+		var binning sql.NullInt64
+		if err := rows.Scan(&s.Date, &s.Filter, &s.Duration, &gain, &binning, &s.Number, &cooling, &fwhm); err != nil {
+			return nil, err
+		}
+		s.Binning = int(binning.Int64)
+		if gain.Valid {
+			s.Gain = &gain.Float64
+		}
+		if cooling.Valid {
+			c := math.Round(cooling.Float64)
+			s.SensorCooling = &c
+		}
+		if fwhm.Valid {
+			f := math.Round(fwhm.Float64*100) / 100
+			s.MeanFWHM = &f
+		}
+		if s.FilterID = d.astrobinFilterID(s.Filter); s.FilterID == 0 {
+			unmapped[s.Filter] = true
+		}
+
+		result.Sessions = append(result.Sessions, s)
+		result.Frames += s.Number
+		seconds += float64(s.Number) * s.Duration
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result.IntegrationHours = roundHours(seconds / 3600)
+	for filter := range unmapped {
+		result.UnmappedFilters = append(result.UnmappedFilters, filter)
+	}
+	sort.Strings(result.UnmappedFilters)
+
+	result.CSV, err = astrobinCSV(result.Sessions)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// astrobinCSV renders acquisition rows in AstroBin's import format. Filters
+// without an AstroBin id get an empty filter column, to be picked on the site.
+func astrobinCSV(sessions []tools.AstroBinSession) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(astrobinColumns); err != nil {
+		return "", err
+	}
+
+	number := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	}
+	for _, s := range sessions {
+		record := make([]string, len(astrobinColumns))
+		record[0] = s.Date
+		if s.FilterID != 0 {
+			record[1] = strconv.Itoa(s.FilterID)
+		}
+		record[2] = strconv.Itoa(s.Number)
+		record[3] = strconv.FormatFloat(s.Duration, 'f', -1, 64)
+		if s.Binning != 0 {
+			record[5] = strconv.Itoa(s.Binning)
+		}
+		record[6] = number(s.Gain)
+		record[7] = number(s.SensorCooling)
+		record[15] = number(s.MeanFWHM)
+		if err := w.Write(record); err != nil {
+			return "", err
+		}
+	}
+
+	w.Flush()
+	return buf.String(), w.Error()
+}
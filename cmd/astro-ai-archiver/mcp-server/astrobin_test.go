@@ -0,0 +1,38 @@
+package mcpserver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAstroBinAcquisitions(t *testing.T) {
+	db, _ := newTestDatabase(t)
+	db.SetAstroBinFilters([]AstroBinFilterConfig{{Name: "Ha", ID: 4663}})
+	insertNGC7000Scenario(t, db)
+
+	result, err := db.AstroBinAcquisitions("ngc7000", "")
+	if err != nil {
+		t.Fatalf("AstroBinAcquisitions: %v", err)
+	}
+	if len(result.Sessions) != 4 || result.Frames != 4 || result.IntegrationHours != 2.25 {
+		t.Fatalf("result = %+v", result)
+	}
+	if len(result.UnmappedFilters) != 1 || result.UnmappedFilters[0] != "OIII" {
+		t.Errorf("unmapped filters = %v", result.UnmappedFilters)
+	}
+
+	expected := strings.Join([]string{
+		"date,filter,number,duration,iso,binning,gain,sensorCooling,fNumber,darks,flats,flatDarks,bias,bortle,meanSqm,meanFwhm,temperature",
+		"2025-08-30,4663,1,1800,,1,100,-10,,,,,,,,,",
+		"2025-09-01,4663,1,900,,1,100,-10,,,,,,,,,",
+		"2025-09-01,4663,1,1800,,1,100,-10,,,,,,,,,",
+		"2025-09-02,,1,3600,,1,100,-10,,,,,,,,,",
+	}, "\n") + "\n"
+	if result.CSV != expected {
+		t.Errorf("CSV =\n%s\nexpected\n%s", result.CSV, expected)
+	}
+
+	if _, err := db.AstroBinAcquisitions("", "missing"); err == nil {
+		t.Error("an unknown project should fail")
+	}
+}
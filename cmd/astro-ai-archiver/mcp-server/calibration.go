@@ -0,0 +1,334 @@
+package mcpserver
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+// Calibration matching defaults and limits
+const (
+	defaultTempTolerance  = 2.0 // °C
+	defaultExposureTol    = 0.5 // Seconds
+	defaultFlatWindowDays = 7
+	maxCalibrationFiles   = 20 // Candidates listed per match
+)
+
+// calibrationFrameType classifies an IMAGETYP value as DARK, FLAT, BIAS or
+// DARKFLAT and tells whether it is a master frame. Capture and stacking
+// programs spell these differently: "Dark Frame", "MASTER DARK", "Flat
+// Field", "Offset", "Zero", "FlatDark", ...
+func calibrationFrameType(imageType string) (string, bool, bool) {
+	t := strings.ToUpper(imageType)
+	master := strings.Contains(t, "MASTER")
+
+	switch {
+	case strings.Contains(t, "DARK") && strings.Contains(t, "FLAT"):
+		return "DARKFLAT", master, true
+	case strings.Contains(t, "FLAT"):
+		return "FLAT", master, true
+	case strings.Contains(t, "DARK"):
+		return "DARK", master, true
+	case strings.Contains(t, "BIAS"), strings.Contains(t, "OFFSET"), strings.Contains(t, "ZERO"):
+		return "BIAS", master, true
+	}
+	return "", false, false
+}
+
+// InsertOrUpdateCalibration stores a calibration frame in calibration_files.
+func (d *Database) InsertOrUpdateCalibration(file *FITSFile, frameType string, master bool) error {
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	_, err := d.db.Exec(`
+		INSERT INTO calibration_files (
+			relative_path, hash, file_mod_time, frame_type, master, telescope, camera, filter,
+			exposure, gain, offset, x_binning, y_binning, ccd_temp, set_temp, naxis1, naxis2,
+			readout_mode, rotator, utc_time, observation_date, indexed_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, strftime('%s', 'now'))
+		ON CONFLICT(relative_path) DO UPDATE SET
+			hash = excluded.hash,
+			file_mod_time = excluded.file_mod_time,
+			frame_type = excluded.frame_type,
+			master = excluded.master,
+			telescope = excluded.telescope,
+			camera = excluded.camera,
+			filter = excluded.filter,
+			exposure = excluded.exposure,
+			gain = excluded.gain,
+			offset = excluded.offset,
+			x_binning = excluded.x_binning,
+			y_binning = excluded.y_binning,
+			ccd_temp = excluded.ccd_temp,
+			set_temp = excluded.set_temp,
+			naxis1 = excluded.naxis1,
+			naxis2 = excluded.naxis2,
+			readout_mode = excluded.readout_mode,
+			rotator = excluded.rotator,
+			utc_time = excluded.utc_time,
+			observation_date = excluded.observation_date,
+			indexed_at = excluded.indexed_at
+	`, file.RelativePath, file.Hash, file.FileModTime, frameType, master, file.Telescope, file.Camera, file.Filter,
+		file.Exposure, file.Gain, file.Offset, file.XBinning, file.YBinning, file.CCDTemp, file.SetTemp,
+		file.Naxis1, file.Naxis2, file.ReadoutMode, file.Rotator, file.UTCTime, file.ObservationDate)
+	if err != nil {
+		return fmt.Errorf("failed to store calibration frame: %w", err)
+	}
+	return nil
+}
+
+// calibrationModTime returns the stored modification time of an indexed
+// calibration frame, or 0 when the path is not indexed.
+func (d *Database) calibrationModTime(relativePath string) (int64, error) {
+	var modTime int64
+	err := d.db.QueryRow("SELECT COALESCE(file_mod_time, 0) FROM calibration_files WHERE relative_path = ?",
+		relativePath).Scan(&modTime)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return modTime, err
+}
+
+// lightGroup is a set of lights with the same calibration requirements
+type lightGroup struct {
+	camera, telescope, filter string
+	gain                      sql.NullFloat64
+	offset                    sql.NullInt64
+	xbin, ybin                int
+	exposure                  float64
+	temp                      sql.NullFloat64 // Set point, or sensor temperature rounded to 1 °C
+	date                      string          // Observation date (flats only)
+	lights                    int
+	lastDate                  string // Newest observation date of the lights
+}
+
+// MatchCalibration finds the darks, flats and bias frames for the selected
+// lights. Rejected lights are left out unless the filters include them.
+// Darks match camera, gain, offset, binning, exposure and temperature within
+// tolerance; bias frames camera, gain, offset and binning; flats the optical
+// train (telescope and camera), filter and binning, taken within
+// FlatWindowDays of each night.
+func (d *Database) MatchCalibration(req tools.CalibrationRequest) (*tools.CalibrationReport, error) {
+	if req.TempTolerance <= 0 {
+		req.TempTolerance = defaultTempTolerance
+	}
+	if req.ExposureTolerance <= 0 {
+		req.ExposureTolerance = defaultExposureTol
+	}
+	if req.FlatWindowDays <= 0 {
+		req.FlatWindowDays = defaultFlatWindowDays
+	}
+
+	if req.SessionID == 0 && !selectsFrames(req.Filters) {
+		return nil, fmt.Errorf("no lights selected: pass a target, session or query filters")
+	}
+
+	where, args := buildFileFilters(req.Filters)
+	if req.SessionID > 0 {
+		where += ` AND EXISTS (SELECT 1 FROM sessions s WHERE s.id = ? AND s.observation_date = f.observation_date
+			AND s.telescope = COALESCE(f.telescope, '') AND s.camera = COALESCE(f.camera, ''))`
+		args = append(args, req.SessionID)
+	}
+
+	rows, err := d.db.Query(`
+		SELECT COALESCE(f.camera, ''), COALESCE(f.telescope, ''), COALESCE(f.filter, ''), f.gain, f.offset,
+		       COALESCE(f.x_binning, 1), COALESCE(f.y_binning, 1), f.exposure,
+		       ROUND(COALESCE(f.set_temp, f.ccd_temp)), COALESCE(f.observation_date, '')
+		FROM fits_files f
+		LEFT JOIN frame_metrics m ON m.file_id = f.id
+		WHERE 1=1`+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select lights: %w", err)
+	}
+
+	report := &tools.CalibrationReport{
+		Darks: []tools.CalibrationMatch{},
+		Flats: []tools.CalibrationMatch{},
+		Bias:  []tools.CalibrationMatch{},
+		Gaps:  []string{},
+	}
+	darks := map[string]*lightGroup{}
+	flats := map[string]*lightGroup{}
+	bias := map[string]*lightGroup{}
+	var darkKeys, flatKeys, biasKeys []string
+
+	add := func(groups map[string]*lightGroup, keys *[]string, key string, l lightGroup) {
+		g, ok := groups[key]
+		if !ok {
+			g = &l
+			groups[key] = g
+			*keys = append(*keys, key)
+		}
+		g.lights++
+		if l.date > g.lastDate {
+			g.lastDate = l.date
+		}
+	}
+
+	for rows.Next() {
+		var l lightGroup
+		if err := rows.Scan(&l.camera, &l.telescope, &l.filter, &l.gain, &l.offset,
+			&l.xbin, &l.ybin, &l.exposure, &l.temp, &l.date); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		report.Lights++
+
+		sensor := fmt.Sprintf("%s|%v|%v|%dx%d", l.camera, nullValue(l.gain), nullValue(l.offset), l.xbin, l.ybin)
+		add(darks, &darkKeys, fmt.Sprintf("%s|%g|%v", sensor, l.exposure, nullValue(l.temp)), l)
+		add(bias, &biasKeys, sensor, l)
+		add(flats, &flatKeys, fmt.Sprintf("%s|%s|%s|%dx%d|%s", l.telescope, l.camera, l.filter, l.xbin, l.ybin, l.date), l)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(darkKeys)
+	sort.Strings(biasKeys)
+	sort.Strings(flatKeys)
+
+	for _, key := range darkKeys {
+		g := darks[key]
+		query, qargs := sensorConditions("DARK", g)
+		query += " AND ABS(exposure - ?) <= ?"
+		qargs = append(qargs, g.exposure, req.ExposureTolerance)
+		criteria := sensorCriteria(g) + fmt.Sprintf(" %gs", g.exposure)
+		if g.temp.Valid {
+			query += " AND ABS(COALESCE(set_temp, ccd_temp) - ?) <= ?"
+			qargs = append(qargs, g.temp.Float64, req.TempTolerance)
+			criteria += fmt.Sprintf(" %g°C ±%g", g.temp.Float64, req.TempTolerance)
+		}
+		match, err := d.calibrationCandidates(query, qargs, g, criteria)
+		if err != nil {
+			return nil, err
+		}
+		report.Darks = append(report.Darks, *match)
+		if match.Candidates == 0 {
+			report.Gaps = append(report.Gaps, "dark: "+criteria)
+		}
+	}
+
+	for _, key := range biasKeys {
+		g := bias[key]
+		query, qargs := sensorConditions("BIAS", g)
+		criteria := sensorCriteria(g)
+		match, err := d.calibrationCandidates(query, qargs, g, criteria)
+		if err != nil {
+			return nil, err
+		}
+		report.Bias = append(report.Bias, *match)
+		if match.Candidates == 0 {
+			report.Gaps = append(report.Gaps, "bias: "+criteria)
+		}
+	}
+
+	for _, key := range flatKeys {
+		g := flats[key]
+		query := " WHERE frame_type = 'FLAT' AND COALESCE(telescope, '') = ? AND COALESCE(camera, '') = ? AND COALESCE(filter, '') = ?" +
+			" AND COALESCE(x_binning, 1) = ? AND COALESCE(y_binning, 1) = ?"
+		qargs := []interface{}{g.telescope, g.camera, g.filter, g.xbin, g.ybin}
+		criteria := fmt.Sprintf("%s %s %s bin %dx%d", g.telescope, g.camera, g.filter, g.xbin, g.ybin)
+		if g.date != "" {
+			query += " AND ABS(julianday(observation_date) - julianday(?)) <= ?"
+			qargs = append(qargs, g.date, req.FlatWindowDays)
+			criteria += fmt.Sprintf(" night %s ±%d days", g.date, req.FlatWindowDays)
+		}
+		match, err := d.calibrationCandidates(query, qargs, g, criteria)
+		if err != nil {
+			return nil, err
+		}
+		report.Flats = append(report.Flats, *match)
+		if match.Candidates == 0 {
+			report.Gaps = append(report.Gaps, "flat: "+criteria)
+		}
+	}
+
+	return report, nil
+}
+
+// sensorConditions returns the WHERE clause matching calibration frames of
+// the given type taken with the same sensor settings as a light group.
+// Settings the lights do not record are not matched.
+func sensorConditions(frameType string, g *lightGroup) (string, []interface{}) {
+	query := " WHERE frame_type = ? AND COALESCE(camera, '') = ? AND COALESCE(x_binning, 1) = ? AND COALESCE(y_binning, 1) = ?"
+	args := []interface{}{frameType, g.camera, g.xbin, g.ybin}
+	if g.gain.Valid {
+		query += " AND ABS(gain - ?) < 0.01"
+		args = append(args, g.gain.Float64)
+	}
+	if g.offset.Valid {
+		query += " AND offset = ?"
+		args = append(args, g.offset.Int64)
+	}
+	return query, args
+}
+
+// sensorCriteria describes the sensor settings of a light group.
+func sensorCriteria(g *lightGroup) string {
+	criteria := g.camera
+	if g.gain.Valid {
+		criteria += fmt.Sprintf(" gain %g", g.gain.Float64)
+	}
+	if g.offset.Valid {
+		criteria += fmt.Sprintf(" offset %d", g.offset.Int64)
+	}
+	return criteria + fmt.Sprintf(" bin %dx%d", g.xbin, g.ybin)
+}
+
+// calibrationCandidates runs a calibration_files query and ranks the
+// candidates: masters first, then by distance in time to the lights.
+func (d *Database) calibrationCandidates(where string, args []interface{}, g *lightGroup, criteria string) (*tools.CalibrationMatch, error) {
+	query := "SELECT relative_path, master FROM calibration_files" + where +
+		" ORDER BY master DESC, ABS(julianday(COALESCE(observation_date, '1900-01-01')) - julianday(?)), relative_path"
+	args = append(args, orDefault(g.lastDate, "2000-01-01"))
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match calibration: %w", err)
+	}
+	defer rows.Close()
+
+	match := &tools.CalibrationMatch{Criteria: strings.TrimSpace(criteria), Lights: g.lights, Files: []string{}}
+	for rows.Next() {
+		var path string
+		var master bool
+		if err := rows.Scan(&path, &master); err != nil {
+			return nil, err
+		}
+		match.Candidates++
+		if master {
+			match.Masters++
+		}
+		if len(match.Files) < maxCalibrationFiles {
+			match.Files = append(match.Files, path)
+		}
+	}
+	return match, rows.Err()
+}
+
+// nullValue formats a nullable number for grouping keys.
+func nullValue(v interface{}) string {
+	switch n := v.(type) {
+	case sql.NullFloat64:
+		if n.Valid {
+			return fmt.Sprintf("%g", math.Round(n.Float64*100)/100)
+		}
+	case sql.NullInt64:
+		if n.Valid {
+			return fmt.Sprintf("%d", n.Int64)
+		}
+	}
+	return "-"
+}
+
+// orDefault returns s, or def when s is empty.
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,131 @@
+package mcpserver
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/astrogo/fitsio"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+func TestCalibrationFrameType(t *testing.T) {
+	tests := []struct {
+		imageType string
+		frameType string
+		master    bool
+		ok        bool
+	}{
+		{"Dark Frame", "DARK", false, true},
+		{"MASTER DARK", "DARK", true, true},
+		{"Flat Field", "FLAT", false, true},
+		{"FLATDARK", "DARKFLAT", false, true},
+		{"Bias Frame", "BIAS", false, true},
+		{"OFFSET", "BIAS", false, true},
+		{"MasterBias", "BIAS", true, true},
+		{"LIGHT", "", false, false},
+		{"SNAPSHOT", "", false, false},
+	}
+	for _, tt := range tests {
+		frameType, master, ok := calibrationFrameType(tt.imageType)
+		if frameType != tt.frameType || master != tt.master || ok != tt.ok {
+			t.Errorf("calibrationFrameType(%q) = %q, %v, %v; expected %q, %v, %v",
+				tt.imageType, frameType, master, ok, tt.frameType, tt.master, tt.ok)
+		}
+	}
+}
+
+func TestScanIndexesCalibrationFrames(t *testing.T) {
+	db, dir := newTestDatabase(t)
+	src := writeTestFITS(t, 8, 8, make([]uint16, 64),
+		fitsio.Card{Name: "IMAGETYP", Value: "Dark Frame"},
+		fitsio.Card{Name: "INSTRUME", Value: "ASI2600MM"},
+		fitsio.Card{Name: "EXPTIME", Value: 300.0},
+	)
+	if err := os.Rename(src, filepath.Join(dir, "dark.fits")); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := NewScanner(db, []string{dir}, false, false, 1, nil).Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if result.Calibration != 1 || result.FilesAdded != 0 {
+		t.Errorf("scan indexed %d calibration frames and added %d lights, expected 1 and 0", result.Calibration, result.FilesAdded)
+	}
+
+	var frameType, camera string
+	var exposure float64
+	err = db.db.QueryRow("SELECT frame_type, camera, exposure FROM calibration_files WHERE relative_path = 'dark.fits'").
+		Scan(&frameType, &camera, &exposure)
+	if err != nil || frameType != "DARK" || camera != "ASI2600MM" || exposure != 300 {
+		t.Errorf("indexed dark = %s %s %g, %v", frameType, camera, exposure, err)
+	}
+
+	// Unchanged calibration frames are not read again
+	result, _ = NewScanner(db, []string{dir}, false, false, 1, nil).Scan()
+	if result.Calibration != 0 || result.FilesSkipped != 1 {
+		t.Errorf("rescan indexed %d and skipped %d, expected 0 and 1", result.Calibration, result.FilesSkipped)
+	}
+}
+
+func TestMatchCalibration(t *testing.T) {
+	db, _ := newTestDatabase(t)
+
+	gain, setTemp, warm, bin := 100.0, -10.0, -5.0, 1
+	offset := 50
+	night := func(date string) sql.NullString { return sql.NullString{String: date, Valid: true} }
+
+	for i, filter := range []string{"Ha", "Ha", "OIII"} {
+		insertFrames(t, db, &FITSFile{
+			RelativePath: filter + string(rune('a'+i)) + ".fits", Object: "NGC7000",
+			Telescope: "RC8", Camera: "ASI2600MM", Filter: filter, Exposure: 300,
+			Gain: &gain, Offset: &offset, SetTemp: &setTemp, XBinning: &bin, YBinning: &bin,
+			ObservationDate: night("2025-09-01"),
+		})
+	}
+
+	calibration := []struct {
+		file      FITSFile
+		frameType string
+		master    bool
+	}{
+		{FITSFile{RelativePath: "darks/300s.fits", Camera: "ASI2600MM", Exposure: 300, Gain: &gain, Offset: &offset, SetTemp: &setTemp, ObservationDate: night("2025-08-01")}, "DARK", false},
+		{FITSFile{RelativePath: "darks/master.fits", Camera: "ASI2600MM", Exposure: 300, Gain: &gain, Offset: &offset, SetTemp: &setTemp, ObservationDate: night("2025-06-01")}, "DARK", true},
+		{FITSFile{RelativePath: "darks/warm.fits", Camera: "ASI2600MM", Exposure: 300, Gain: &gain, Offset: &offset, SetTemp: &warm, ObservationDate: night("2025-09-01")}, "DARK", false},
+		{FITSFile{RelativePath: "flats/ha.fits", Telescope: "RC8", Camera: "ASI2600MM", Filter: "Ha", Exposure: 2, ObservationDate: night("2025-09-03")}, "FLAT", false},
+		{FITSFile{RelativePath: "flats/oiii-old.fits", Telescope: "RC8", Camera: "ASI2600MM", Filter: "OIII", Exposure: 2, ObservationDate: night("2025-07-01")}, "FLAT", false},
+		{FITSFile{RelativePath: "bias/b.fits", Camera: "ASI2600MM", Gain: &gain, Offset: &offset}, "BIAS", false},
+	}
+	for _, c := range calibration {
+		if err := db.InsertOrUpdateCalibration(&c.file, c.frameType, c.master); err != nil {
+			t.Fatalf("InsertOrUpdateCalibration: %v", err)
+		}
+	}
+
+	if _, err := db.MatchCalibration(tools.CalibrationRequest{}); err == nil {
+		t.Error("MatchCalibration without a selection should fail")
+	}
+
+	report, err := db.MatchCalibration(tools.CalibrationRequest{Filters: map[string]interface{}{"target": "NGC7000"}})
+	if err != nil {
+		t.Fatalf("MatchCalibration: %v", err)
+	}
+	if report.Lights != 3 || len(report.Darks) != 1 || len(report.Bias) != 1 || len(report.Flats) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	darks := report.Darks[0]
+	if darks.Lights != 3 || darks.Candidates != 2 || darks.Masters != 1 || darks.Files[0] != "darks/master.fits" {
+		t.Errorf("darks = %+v, expected the master first and the warm dark left out", darks)
+	}
+	if report.Bias[0].Candidates != 1 {
+		t.Errorf("bias = %+v", report.Bias[0])
+	}
+	if len(report.Gaps) != 1 || !strings.HasPrefix(report.Gaps[0], "flat: RC8 ASI2600MM OIII") {
+		t.Errorf("gaps = %v, expected only the OIII flats", report.Gaps)
+	}
+}
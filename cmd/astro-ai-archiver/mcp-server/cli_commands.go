@@ -0,0 +1,372 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+)
+
+// fileListSelect is the query behind the table output of the query command:
+// the columns that identify a frame at a glance
+const fileListSelect = `
+	SELECT f.id, f.observation_date, f.object, f.filter, f.exposure, f.telescope, f.camera, f.relative_path
+	FROM fits_files f
+	WHERE 1=1`
+
+// Filters of query_fits_archive that the query command takes as flags, by type
+var (
+	stringFilterFlags = []string{"target", "filter", "telescope", "camera", "software", "date_from", "date_to",
+		"pier_side", "readout_mode", "bayer_pattern", "grade", "sort_by", "sort_order"}
+	numberFilterFlags = []string{"min_exposure", "max_exposure", "gain_min", "gain_max", "binning",
+		"ccd_temp_min", "ccd_temp_max", "set_temp", "airmass_min", "airmass_max", "altitude_min",
+		"fwhm_min", "fwhm_max", "hfr_max", "eccentricity_min", "eccentricity_max", "star_count_min", "star_count_max",
+		"humidity_max", "sky_quality_min", "cloud_cover_max", "wind_speed_max",
+		"moon_separation_min", "moon_separation_max", "moon_illumination_max", "moon_alt_max"}
+	boolFilterFlags = []string{"include_rejected", "analyzed"}
+)
+
+// openArchive opens and migrates the configured database and applies the
+// configuration the way the MCP server does.
+func openArchive(cmd *cobra.Command) (*Config, *Database, error) {
+	cfg, db, err := openConfiguredDatabase(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := db.Migrate(context.Background()); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("migration failed: %w", err)
+	}
+	configureDatabase(db, cfg)
+	return cfg, db, nil
+}
+
+// RunScan scans the configured directories in the foreground (scan command).
+// It fails when the scan reported errors, so cron jobs can tell.
+func RunScan(cmd *cobra.Command, args []string) error {
+	cfg, db, err := openArchive(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	force, _ := cmd.Flags().GetBool("force")
+	noProgress, _ := cmd.Flags().GetBool("no-progress")
+
+	scanner := NewScanner(db, db.baseDirs, cfg.Scan.Recursive, force, cfg.Scan.Workers, cfg.CommonNames)
+
+	var done chan struct{}
+	if !noProgress && isTerminal(os.Stderr) {
+		// Log lines would tear the progress bar apart
+		if zerolog.GlobalLevel() < zerolog.WarnLevel {
+			zerolog.SetGlobalLevel(zerolog.WarnLevel)
+		}
+		done = make(chan struct{})
+		go showScanProgress(scanner, scanner.CountFiles(), done)
+	}
+
+	result, err := scanner.Scan()
+	if done != nil {
+		done <- struct{}{}
+		<-done
+	}
+	if err != nil {
+		return fmt.Errorf("scan failed: %w", err)
+	}
+
+	fmt.Printf("Scanned %d files in %s: %d added, %d updated, %d unchanged, %d calibration frames\n",
+		result.FilesScanned, result.Duration.Round(time.Second), result.FilesAdded, result.FilesUpdated,
+		result.FilesSkipped, result.Calibration)
+	if result.NINAFrames > 0 || result.GuideLogs > 0 {
+		fmt.Printf("Imported N.I.N.A. metadata for %d frames and %d PHD2 guide logs\n", result.NINAFrames, result.GuideLogs)
+	}
+	for _, e := range result.Errors {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", e)
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("scan finished with %d errors", len(result.Errors))
+	}
+	return nil
+}
+
+// showScanProgress draws a progress bar on stderr until done is signalled,
+// then draws it one last time and signals back.
+func showScanProgress(scanner *Scanner, total int, done chan struct{}) {
+	const width = 30
+	draw := func() {
+		n := scanner.Scanned()
+		if total == 0 {
+			fmt.Fprintf(os.Stderr, "\rScanned %d files", n)
+			return
+		}
+		filled := min(width, width*n/total)
+		fmt.Fprintf(os.Stderr, "\r[%s%s] %d/%d files", strings.Repeat("=", filled), strings.Repeat(" ", width-filled), n, total)
+	}
+
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			draw()
+		case <-done:
+			draw()
+			fmt.Fprintln(os.Stderr)
+			done <- struct{}{}
+			return
+		}
+	}
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// RunQuery prints the frames matching the filter flags as a table, CSV or
+// JSON (query command). CSV and JSON carry every file column plus quality
+// metrics and grade, like export query.
+func RunQuery(cmd *cobra.Command, args []string) error {
+	_, db, err := openArchive(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	format, _ := cmd.Flags().GetString("format")
+	limit, _ := cmd.Flags().GetInt("limit")
+	offset, _ := cmd.Flags().GetInt("offset")
+
+	filters := queryFilterFlags(cmd)
+	where, queryArgs := buildFileFilters(filters)
+	query := fileExportSelect
+	if format == "" || format == "table" {
+		query = fileListSelect
+	}
+	query += where + " ORDER BY " + fileOrder(filters) + " LIMIT ? OFFSET ?"
+	queryArgs = append(queryArgs, limit, offset)
+
+	count, err := db.writeQuery(os.Stdout, format, query, queryArgs...)
+	if err != nil {
+		return err
+	}
+	if format == "" || format == "table" {
+		fmt.Fprintf(os.Stderr, "%d files\n", count)
+	}
+	return nil
+}
+
+// RunSQL runs a read-only SQL query and prints its rows (sql command).
+func RunSQL(cmd *cobra.Command, args []string) error {
+	query := strings.Join(args, " ")
+	if err := validateReadOnlyQuery(query); err != nil {
+		return err
+	}
+
+	_, db, err := openArchive(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	format, _ := cmd.Flags().GetString("format")
+	_, err = db.writeQuery(os.Stdout, format, query)
+	return err
+}
+
+// RunStats prints the archive summary of get_archive_summary (stats command).
+func RunStats(cmd *cobra.Command, args []string) error {
+	_, db, err := openArchive(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	includeRejected, _ := cmd.Flags().GetBool("include-rejected")
+	summary, err := db.GetArchiveSummary(includeRejected)
+	if err != nil {
+		return fmt.Errorf("failed to get summary: %w", err)
+	}
+
+	if format, _ := cmd.Flags().GetString("format"); format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(summary)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Database:\t%s\n", db.GetFilePath())
+	fmt.Fprintf(w, "Files:\t%d", summary.TotalFiles)
+	if summary.RejectedFiles > 0 {
+		if includeRejected {
+			fmt.Fprintf(w, " (%d rejected)", summary.RejectedFiles)
+		} else {
+			fmt.Fprintf(w, " (%d rejected left out)", summary.RejectedFiles)
+		}
+	}
+	fmt.Fprintf(w, "\nIntegration:\t%.2f h\n", summary.TotalExposure/3600)
+	if !summary.OldestDate.IsZero() {
+		fmt.Fprintf(w, "Observations:\t%s - %s\n", summary.OldestDate.Format("2006-01-02"), summary.NewestDate.Format("2006-01-02"))
+	}
+	for _, list := range []struct {
+		name   string
+		values []string
+	}{
+		{"Targets", summary.UniqueTargets},
+		{"Filters", summary.UniqueFilters},
+		{"Telescopes", summary.UniqueTelescopes},
+		{"Cameras", summary.UniqueCameras},
+	} {
+		fmt.Fprintf(w, "%s (%d):\t%s\n", list.name, len(list.values), strings.Join(list.values, ", "))
+	}
+	return w.Flush()
+}
+
+// queryFilterFlags collects the query_fits_archive filters given on the
+// command line.
+func queryFilterFlags(cmd *cobra.Command) map[string]interface{} {
+	filters := map[string]interface{}{}
+	for _, name := range stringFilterFlags {
+		if v, _ := cmd.Flags().GetString(flagName(name)); v != "" {
+			filters[name] = v
+		}
+	}
+	for _, name := range numberFilterFlags {
+		if cmd.Flags().Changed(flagName(name)) {
+			filters[name], _ = cmd.Flags().GetFloat64(flagName(name))
+		}
+	}
+	for _, name := range boolFilterFlags {
+		if cmd.Flags().Changed(flagName(name)) {
+			filters[name], _ = cmd.Flags().GetBool(flagName(name))
+		}
+	}
+	return filters
+}
+
+// writeQuery runs a query and writes its rows to w as an aligned table, CSV,
+// a JSON array or JSON Lines. It returns the number of rows.
+func (d *Database) writeQuery(w io.Writer, format, query string, args ...interface{}) (int, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("query execution failed: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get columns: %w", err)
+	}
+	var writer rowWriter
+	switch strings.ToLower(format) {
+	case "", "table":
+		writer = newTableRowWriter(w, columns)
+	case "csv":
+		writer, err = newCSVRowWriter(w, columns)
+	case "json":
+		writer = &jsonArrayRowWriter{w: w, columns: columns}
+	case "jsonl":
+		writer = &jsonlRowWriter{w: w, columns: columns}
+	default:
+		return 0, fmt.Errorf("unsupported output format %q (use table, csv, json or jsonl)", format)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return count, fmt.Errorf("failed to scan row: %w", err)
+		}
+		row := make([]interface{}, len(values))
+		for i, v := range values {
+			row[i] = exportValue(v)
+		}
+		if err := writer.WriteRow(row); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+	return count, writer.Close()
+}
+
+// tableRowWriter writes rows as columns aligned with spaces
+type tableRowWriter struct {
+	w *tabwriter.Writer
+}
+
+func newTableRowWriter(w io.Writer, columns []string) *tableRowWriter {
+	t := &tableRowWriter{w: tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)}
+	fmt.Fprintln(t.w, strings.ToUpper(strings.Join(columns, "\t")))
+	return t
+}
+
+func (t *tableRowWriter) WriteRow(values []interface{}) error {
+	cells := make([]string, len(values))
+	for i, v := range values {
+		switch x := v.(type) {
+		case nil:
+		case float64:
+			cells[i] = strconv.FormatFloat(x, 'f', -1, 64)
+		default:
+			// Tabs and line breaks would break the alignment
+			cells[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(fmt.Sprint(x))
+		}
+	}
+	_, err := fmt.Fprintln(t.w, strings.Join(cells, "\t"))
+	return err
+}
+
+func (t *tableRowWriter) Close() error {
+	return t.w.Flush()
+}
+
+// jsonArrayRowWriter writes all rows as one JSON array of objects
+type jsonArrayRowWriter struct {
+	w       io.Writer
+	columns []string
+	rows    int
+}
+
+func (j *jsonArrayRowWriter) WriteRow(values []interface{}) error {
+	// Objects as the JSON Lines writer renders them, without the line break
+	var b strings.Builder
+	if j.rows == 0 {
+		b.WriteString("[\n")
+	} else {
+		b.WriteString(",\n")
+	}
+	j.rows++
+	if err := (&jsonlRowWriter{w: &b, columns: j.columns}).WriteRow(values); err != nil {
+		return err
+	}
+	_, err := io.WriteString(j.w, strings.TrimSuffix(b.String(), "\n"))
+	return err
+}
+
+func (j *jsonArrayRowWriter) Close() error {
+	end := "\n]\n"
+	if j.rows == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
@@ -0,0 +1,71 @@
+package mcpserver
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestWriteQuery(t *testing.T) {
+	db, _ := newTestDatabase(t)
+
+	insertFrames(t, db,
+		&FITSFile{RelativePath: "m31/l1.fits", Hash: "a", Object: "M31", Filter: "L", Exposure: 120},
+		&FITSFile{RelativePath: "m31/r1.fits", Hash: "b", Object: "M31", Filter: "R", Exposure: 60.5},
+	)
+	query := "SELECT filter, exposure, ra FROM fits_files ORDER BY filter"
+
+	var out strings.Builder
+	if n, err := db.writeQuery(&out, "table", query); err != nil || n != 2 {
+		t.Fatalf("writeQuery table = %d, %v", n, err)
+	}
+	expected := "FILTER  EXPOSURE  RA\nL       120       \nR       60.5      \n"
+	if out.String() != expected {
+		t.Errorf("table output:\n%q\nexpected:\n%q", out.String(), expected)
+	}
+
+	out.Reset()
+	if _, err := db.writeQuery(&out, "json", query); err != nil {
+		t.Fatalf("writeQuery json: %v", err)
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal([]byte(out.String()), &rows); err != nil || len(rows) != 2 || rows[1]["exposure"] != 60.5 || rows[1]["ra"] != nil {
+		t.Errorf("json output %q: %v", out.String(), err)
+	}
+
+	out.Reset()
+	if _, err := db.writeQuery(&out, "json", query+" LIMIT 0"); err != nil || out.String() != "[]\n" {
+		t.Errorf("empty json output = %q, %v", out.String(), err)
+	}
+
+	out.Reset()
+	if _, err := db.writeQuery(&out, "csv", query); err != nil || out.String() != "filter,exposure,ra\nL,120,\nR,60.5,\n" {
+		t.Errorf("csv output = %q, %v", out.String(), err)
+	}
+
+	if _, err := db.writeQuery(&out, "xml", query); err == nil {
+		t.Error("writeQuery should reject unknown formats")
+	}
+}
+
+func TestQueryFilterFlags(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("target", "", "")
+	cmd.Flags().String("date-from", "", "")
+	cmd.Flags().Float64("fwhm-max", 0, "")
+	cmd.Flags().Float64("ccd-temp-max", 0, "")
+	cmd.Flags().Bool("analyzed", false, "")
+	cmd.Flags().Bool("include-rejected", false, "")
+	if err := cmd.ParseFlags([]string{"--target", "M31", "--date-from", "2025-01-01", "--ccd-temp-max", "0", "--analyzed=false"}); err != nil {
+		t.Fatal(err)
+	}
+
+	filters := queryFilterFlags(cmd)
+	// Flags that were set count even when zero or false; the others are left out
+	if len(filters) != 4 || filters["target"] != "M31" || filters["date_from"] != "2025-01-01" ||
+		filters["ccd_temp_max"] != 0.0 || filters["analyzed"] != false {
+		t.Errorf("filters = %v", filters)
+	}
+}
@@ -0,0 +1,153 @@
+package mcpserver
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+// conditionsSelect summarises the frames, frame quality and observing
+// conditions of each session (alias s). Rows are decoded with
+// scanConditionsSession.
+const conditionsSelect = `
+	SELECT s.id, s.observation_date, s.telescope, s.camera,
+	       COUNT(f.id), TOTAL(f.exposure),
+	       COUNT(CASE WHEN g.grade = 'rejected' THEN 1 END),
+	       TOTAL(CASE WHEN g.grade = 'rejected' THEN f.exposure END),
+	       AVG(m.fwhm),
+	       COUNT(c.file_id),
+	       AVG(c.ambient_temp), MIN(c.ambient_temp), MAX(c.ambient_temp),
+	       AVG(c.humidity), MAX(c.humidity),
+	       AVG(c.dew_point), AVG(c.pressure),
+	       AVG(c.sky_quality), MIN(c.sky_quality),
+	       AVG(c.cloud_cover), MAX(c.cloud_cover),
+	       AVG(c.wind_speed), MAX(c.wind_speed)
+	FROM sessions s
+	JOIN fits_files f ON f.observation_date = s.observation_date
+	     AND COALESCE(f.telescope, '') = s.telescope AND COALESCE(f.camera, '') = s.camera
+	LEFT JOIN frame_marks g ON g.hash = f.hash
+	LEFT JOIN frame_metrics m ON m.file_id = f.id
+	LEFT JOIN frame_conditions c ON c.file_id = f.id`
+
+// scanConditionsSession reads a row selected with conditionsSelect
+func scanConditionsSession(scanner interface{ Scan(...interface{}) error }) (tools.ConditionsSession, error) {
+	var s tools.ConditionsSession
+	var exposure, rejected float64
+	c := &s.Conditions
+	err := scanner.Scan(&s.SessionID, &s.ObservationDate, &s.Telescope, &s.Camera,
+		&s.Frames, &exposure, &s.RejectedFrames, &rejected, &s.MeanFWHM,
+		&c.Frames, &c.AmbientTempAvg, &c.AmbientTempMin, &c.AmbientTempMax,
+		&c.HumidityAvg, &c.HumidityMax, &c.DewPointAvg, &c.PressureAvg,
+		&c.SkyQualityAvg, &c.SkyQualityMin, &c.CloudCoverAvg, &c.CloudCoverMax,
+		&c.WindSpeedAvg, &c.WindSpeedMax)
+	if err != nil {
+		return s, err
+	}
+
+	s.IntegrationHours = roundHundredths(exposure / 3600)
+	s.RejectedHours = roundHundredths(rejected / 3600)
+	for _, v := range []*float64{s.MeanFWHM, c.AmbientTempAvg, c.HumidityAvg, c.DewPointAvg,
+		c.PressureAvg, c.SkyQualityAvg, c.CloudCoverAvg, c.WindSpeedAvg} {
+		if v != nil {
+			*v = roundHundredths(*v)
+		}
+	}
+	return s, nil
+}
+
+// ConditionsReport lists sessions with their integration, the integration
+// lost to rejected frames and the observing conditions of their frames, so
+// quality can be correlated with e.g. humidity or sky brightness. Thresholds
+// keep sessions where any frame went beyond them.
+func (d *Database) ConditionsReport(req tools.ConditionsRequest) (*tools.ConditionsReport, error) {
+	where := " WHERE 1=1"
+	var args []interface{}
+	if req.DateFrom != "" {
+		where += " AND s.observation_date >= ?"
+		args = append(args, req.DateFrom)
+	}
+	if req.DateTo != "" {
+		where += " AND s.observation_date <= ?"
+		args = append(args, req.DateTo)
+	}
+	if req.Telescope != "" {
+		where += " AND s.telescope LIKE ?"
+		args = append(args, "%"+req.Telescope+"%")
+	}
+	if req.Camera != "" {
+		where += " AND s.camera LIKE ?"
+		args = append(args, "%"+req.Camera+"%")
+	}
+
+	having := ""
+	for _, t := range []struct {
+		value     *float64
+		condition string
+	}{
+		{req.HumidityAbove, "MAX(c.humidity) > ?"},
+		{req.SkyQualityBelow, "MIN(c.sky_quality) < ?"},
+		{req.CloudCoverAbove, "MAX(c.cloud_cover) > ?"},
+		{req.WindSpeedAbove, "MAX(c.wind_speed) > ?"},
+	} {
+		if t.value == nil {
+			continue
+		}
+		if having == "" {
+			having = " HAVING "
+		} else {
+			having += " AND "
+		}
+		having += t.condition
+		args = append(args, *t.value)
+	}
+
+	rows, err := d.db.Query(conditionsSelect+where+`
+		GROUP BY s.id`+having+`
+		ORDER BY s.observation_date, s.telescope, s.camera
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarise conditions: %w", err)
+	}
+	defer rows.Close()
+
+	report := &tools.ConditionsReport{Sessions: []tools.ConditionsSession{}}
+	for rows.Next() {
+		s, err := scanConditionsSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		report.Sessions = append(report.Sessions, s)
+		report.Frames += s.Frames
+		report.IntegrationHours += s.IntegrationHours
+		report.RejectedFrames += s.RejectedFrames
+		report.RejectedHours += s.RejectedHours
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	report.IntegrationHours = roundHundredths(report.IntegrationHours)
+	report.RejectedHours = roundHundredths(report.RejectedHours)
+	return report, nil
+}
+
+// sessionConditions returns the observing conditions of a session, or nil
+// when none of its frames recorded any.
+func (d *Database) sessionConditions(id int64) (*tools.SessionConditions, error) {
+	s, err := scanConditionsSession(d.db.QueryRow(conditionsSelect+" WHERE s.id = ? GROUP BY s.id", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarise conditions: %w", err)
+	}
+	if s.Conditions.Frames == 0 {
+		return nil, nil
+	}
+	return &s.Conditions, nil
+}
+
+func roundHundredths(v float64) float64 {
+	return math.Round(v*100) / 100
+}
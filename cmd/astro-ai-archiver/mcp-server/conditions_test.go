@@ -0,0 +1,102 @@
+package mcpserver
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/astrogo/fitsio"
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+func TestExtractConditions(t *testing.T) {
+	s := &Scanner{}
+	if c := s.extractConditions(newTestHeader(fitsio.Card{Name: "EXPTIME", Value: 300.0})); c != nil {
+		t.Errorf("conditions without keywords = %+v, expected nil", c)
+	}
+
+	c := s.extractConditions(newTestHeader(
+		fitsio.Card{Name: "AMBTEMP", Value: 8.5},
+		fitsio.Card{Name: "HUMIDITY", Value: 91},
+		fitsio.Card{Name: "MPSAS", Value: 20.8},
+	))
+	if c == nil || *c.AmbientTemp != 8.5 || *c.Humidity != 91 || *c.SkyQuality != 20.8 || c.WindSpeed != nil {
+		t.Errorf("conditions = %+v", c)
+	}
+}
+
+func TestConditionsReport(t *testing.T) {
+	db, _ := newTestDatabase(t)
+
+	frame := func(path, night, utc string, humidity, sky float64) *FITSFile {
+		return &FITSFile{
+			RelativePath: path, Object: "NGC7000", Filter: "Ha", Exposure: 300,
+			Telescope: "RC8", Camera: "ASI2600MM",
+			UTCTime:         sql.NullString{String: utc, Valid: true},
+			ObservationDate: sql.NullString{String: night, Valid: true},
+			Conditions:      &FrameConditions{Humidity: &humidity, SkyQuality: &sky},
+		}
+	}
+
+	// A dry night and a humid one, where the last frame was rejected
+	insertFrames(t, db,
+		frame("n1/a.fits", "2025-09-01", "2025-09-01T21:00:00", 60, 21.2),
+		frame("n1/b.fits", "2025-09-01", "2025-09-01T21:05:00", 64, 21.3),
+		frame("n2/a.fits", "2025-09-03", "2025-09-03T22:00:00", 80, 20.9),
+		frame("n2/b.fits", "2025-09-03", "2025-09-03T22:05:00", 92, 20.1),
+	)
+	if _, _, err := db.RefreshSessions(); err != nil {
+		t.Fatalf("RefreshSessions: %v", err)
+	}
+	id, _ := db.ResolveFileID("n2/b.fits")
+	if _, err := db.GradeFrames(tools.GradeSelection{FileIDs: []int64{id}}, "rejected", "dew"); err != nil {
+		t.Fatalf("GradeFrames: %v", err)
+	}
+
+	all, err := db.ConditionsReport(tools.ConditionsRequest{})
+	if err != nil {
+		t.Fatalf("ConditionsReport: %v", err)
+	}
+	if len(all.Sessions) != 2 || all.Frames != 4 || all.RejectedFrames != 1 {
+		t.Fatalf("report = %+v", all)
+	}
+	dry := all.Sessions[0].Conditions
+	if dry.Frames != 2 || *dry.HumidityAvg != 62 || *dry.HumidityMax != 64 || *dry.SkyQualityMin != 21.2 {
+		t.Errorf("dry night conditions = %+v", dry)
+	}
+
+	humidityAbove := 85.0
+	humid, err := db.ConditionsReport(tools.ConditionsRequest{HumidityAbove: &humidityAbove})
+	if err != nil {
+		t.Fatalf("ConditionsReport: %v", err)
+	}
+	if len(humid.Sessions) != 1 || humid.Sessions[0].ObservationDate != "2025-09-03" ||
+		humid.IntegrationHours != 0.17 || humid.RejectedHours != 0.08 {
+		t.Errorf("humid report = %+v", humid)
+	}
+
+	// Frames can be filtered by conditions
+	files, err := db.QueryFiles(map[string]interface{}{"humidity_max": 85.0, "sky_quality_min": 21.0}, 10, 0)
+	if err != nil {
+		t.Fatalf("QueryFiles: %v", err)
+	}
+	if n := len(files.([]*FITSFile)); n != 2 {
+		t.Errorf("QueryFiles(humidity_max, sky_quality_min) returned %d files, expected 2", n)
+	}
+
+	// Sessions carry their conditions
+	session, err := db.GetSession(humid.Sessions[0].SessionID)
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if c := session.(*Session).Conditions; c == nil || *c.HumidityMax != 92 {
+		t.Errorf("session conditions = %+v", c)
+	}
+
+	// A rescan without the keywords removes the conditions
+	insertFrames(t, db, &FITSFile{RelativePath: "n1/a.fits"})
+	var count int
+	db.db.QueryRow("SELECT COUNT(*) FROM frame_conditions").Scan(&count)
+	if count != 3 {
+		t.Errorf("frame_conditions has %d rows, expected 3", count)
+	}
+}
@@ -0,0 +1,151 @@
+package mcpserver
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/astro"
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+// sessionDarkness is the astronomical darkness of a session's night and how
+// it was used. Times are UTC in sessionTimeLayout, "" when it never gets dark.
+type sessionDarkness struct {
+	start, end  string
+	seconds     float64
+	integration float64 // Exposure time within darkness
+	lost        float64 // Gap time within darkness
+}
+
+// nightDarkness returns the astronomical darkness of the night that starts
+// on the evening of date (YYYY-MM-DD) at a site.
+func nightDarkness(date string, lat, lon float64) (time.Time, time.Time, bool) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	// Search from local noon, as far as the longitude tells
+	noon := day.Add(12*time.Hour - time.Duration(lon/15*float64(time.Hour)))
+	return astro.Darkness(noon, lat, lon)
+}
+
+// measureDarkness computes the darkness of a session's night and the parts
+// of its frames and gaps that fall within it. Gaps get their DarkSeconds.
+func measureDarkness(date string, lat, lon float64, spans []frameSpan, gaps []SessionGap) sessionDarkness {
+	var d sessionDarkness
+	start, end, ok := nightDarkness(date, lat, lon)
+	if !ok {
+		return d
+	}
+	d.start, d.end = start.Format(sessionTimeLayout), end.Format(sessionTimeLayout)
+	d.seconds = end.Sub(start).Seconds()
+
+	for _, s := range spans {
+		d.integration += overlapSeconds(s.start, s.start.Add(time.Duration(s.exposure*float64(time.Second))), start, end)
+	}
+	d.lost = darkGapSeconds(gaps, d.start, d.end)
+	return d
+}
+
+// darkGapSeconds sets the DarkSeconds of gaps for a darkness period and
+// returns their total.
+func darkGapSeconds(gaps []SessionGap, darkStart, darkEnd string) float64 {
+	start, okStart := parseDateTimeString(darkStart)
+	end, okEnd := parseDateTimeString(darkEnd)
+	if !okStart || !okEnd {
+		return 0
+	}
+
+	total := 0.0
+	for i, g := range gaps {
+		gapStart, _ := parseDateTimeString(g.Start)
+		gapEnd, _ := parseDateTimeString(g.End)
+		gaps[i].DarkSeconds = overlapSeconds(gapStart, gapEnd, start, end)
+		total += gaps[i].DarkSeconds
+	}
+	return total
+}
+
+// overlapSeconds returns the length of the overlap of two periods
+func overlapSeconds(aStart, aEnd, bStart, bEnd time.Time) float64 {
+	if aStart.Before(bStart) {
+		aStart = bStart
+	}
+	if aEnd.After(bEnd) {
+		aEnd = bEnd
+	}
+	if !aEnd.After(aStart) {
+		return 0
+	}
+	return aEnd.Sub(aStart).Seconds()
+}
+
+// sessionSite returns the site of a session: the average SITELAT/SITELONG of
+// its frames, else the configured site. It returns false without either.
+func (d *Database) sessionSite(lat, lon *float64) (float64, float64, bool) {
+	if lat == nil || lon == nil {
+		lat, lon = d.siteLat, d.siteLong
+	}
+	if lat == nil || lon == nil {
+		return 0, 0, false
+	}
+	return *lat, *lon, true
+}
+
+// EfficiencyReport lists how well sessions used the astronomical darkness of
+// their nights: hours of darkness, hours integrated in total and within
+// darkness, and the dark hours lost to gaps (weather, equipment) longer than
+// sessionGapThreshold.
+func (d *Database) EfficiencyReport(req tools.EfficiencyRequest) (*tools.EfficiencyReport, error) {
+	filters := map[string]interface{}{
+		"date_from": req.DateFrom,
+		"date_to":   req.DateTo,
+		"telescope": req.Telescope,
+		"camera":    req.Camera,
+	}
+	list, err := d.ListSessions(filters, -1, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	report := &tools.EfficiencyReport{Sessions: []tools.EfficiencySession{}}
+	var dark, integration, darkIntegration, lost float64
+	for _, s := range list.([]*Session) {
+		e := tools.EfficiencySession{
+			SessionID:            s.ID,
+			ObservationDate:      s.ObservationDate,
+			Telescope:            s.Telescope,
+			Camera:               s.Camera,
+			DarkStart:            s.DarkStart,
+			DarkEnd:              s.DarkEnd,
+			DarkHours:            roundHundredths(s.DarkSeconds / 3600),
+			IntegrationHours:     roundHundredths(s.Integration / 3600),
+			DarkIntegrationHours: roundHundredths(s.DarkIntegration / 3600),
+			Efficiency:           s.Efficiency,
+			Gaps:                 s.GapCount,
+			LostHours:            roundHundredths(s.LostSeconds / 3600),
+			LongestGapMinutes:    roundHundredths(s.LongestGap / 60),
+			HasSite:              s.SiteLat != nil,
+		}
+		report.Sessions = append(report.Sessions, e)
+
+		integration += s.Integration
+		if !e.HasSite {
+			report.WithoutSite++
+			continue
+		}
+		dark += s.DarkSeconds
+		darkIntegration += s.DarkIntegration
+		lost += s.LostSeconds
+	}
+
+	report.DarkHours = roundHundredths(dark / 3600)
+	report.IntegrationHours = roundHundredths(integration / 3600)
+	report.DarkIntegrationHours = roundHundredths(darkIntegration / 3600)
+	report.LostHours = roundHundredths(lost / 3600)
+	if dark > 0 {
+		efficiency := roundHundredths(darkIntegration / dark)
+		report.Efficiency = &efficiency
+	}
+	return report, nil
+}
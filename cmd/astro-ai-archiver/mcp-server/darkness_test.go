@@ -0,0 +1,82 @@
+package mcpserver
+
+import (
+	"database/sql"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+func TestSessionDarkness(t *testing.T) {
+	db, _ := newTestDatabase(t)
+
+	// Equator at the March equinox: dark from about 19:19 to 04:55 UTC
+	lat, lon := 0.0, 0.0
+	frame := func(path, camera string, start time.Time, site bool) *FITSFile {
+		file := &FITSFile{
+			RelativePath: path, Exposure: 300, Telescope: "RC8", Camera: camera,
+			UTCTime:         sql.NullString{String: start.Format(sessionTimeLayout), Valid: true},
+			ObservationDate: sql.NullString{String: "2025-03-20", Valid: true},
+		}
+		if site {
+			file.SiteLat, file.SiteLong = &lat, &lon
+		}
+		return file
+	}
+
+	// One frame in twilight, then 2 h, a 1 h gap and another 2 h in darkness
+	frames := []*FITSFile{frame("a/twilight.fits", "A", time.Date(2025, 3, 20, 18, 30, 0, 0, time.UTC), true)}
+	for i := 0; i < 24; i++ {
+		frames = append(frames,
+			frame(filepath.Join("a", "early", string(rune('a'+i))+".fits"), "A", time.Date(2025, 3, 20, 20, 5*i, 0, 0, time.UTC), true),
+			frame(filepath.Join("a", "late", string(rune('a'+i))+".fits"), "A", time.Date(2025, 3, 20, 23, 5*i, 0, 0, time.UTC), true))
+	}
+	// A second camera without site headers
+	frames = append(frames, frame("b/1.fits", "B", time.Date(2025, 3, 20, 21, 0, 0, 0, time.UTC), false))
+	insertFrames(t, db, frames...)
+
+	if _, _, err := db.RefreshSessions(); err != nil {
+		t.Fatalf("RefreshSessions: %v", err)
+	}
+
+	report, err := db.EfficiencyReport(tools.EfficiencyRequest{Camera: "A"})
+	if err != nil {
+		t.Fatalf("EfficiencyReport: %v", err)
+	}
+	if len(report.Sessions) != 1 {
+		t.Fatalf("report has %d sessions, expected 1", len(report.Sessions))
+	}
+	s := report.Sessions[0]
+	// Darkness is 9.6 h; the gap before 20:00 is dark from 19:19:30
+	if math.Abs(s.DarkHours-9.6) > 0.05 || s.DarkIntegrationHours != 4 || s.IntegrationHours != 4.08 ||
+		s.Gaps != 2 || math.Abs(s.LostHours-1.68) > 0.02 || s.Efficiency == nil || math.Abs(*s.Efficiency-0.42) > 0.01 {
+		t.Errorf("session = %+v", s)
+	}
+
+	// The other camera gets darkness once a site is configured
+	report, _ = db.EfficiencyReport(tools.EfficiencyRequest{Camera: "B"})
+	if report.WithoutSite != 1 || report.Sessions[0].Efficiency != nil {
+		t.Fatalf("session without site = %+v", report.Sessions[0])
+	}
+	db.SetSite(&lat, &lon)
+	if rebuilt, _, err := db.RefreshSessions(); err != nil || rebuilt != 1 {
+		t.Fatalf("RefreshSessions after SetSite = %d, %v; expected 1 rebuilt", rebuilt, err)
+	}
+	report, _ = db.EfficiencyReport(tools.EfficiencyRequest{Camera: "B"})
+	if report.WithoutSite != 0 || report.Sessions[0].DarkIntegrationHours != 0.08 {
+		t.Errorf("session with configured site = %+v", report.Sessions[0])
+	}
+
+	// Gaps in get_session carry their dark part
+	session, err := db.GetSession(s.SessionID)
+	if err != nil || session == nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	gaps := session.(*Session).Gaps
+	if len(gaps) != 2 || gaps[1].DarkSeconds != 3600 || math.Abs(gaps[0].DarkSeconds-2430) > 120 {
+		t.Errorf("gaps = %+v", gaps)
+	}
+}
@@ -0,0 +1,151 @@
+package mcpserver
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// dashboardPrefix is the path of the web dashboard on the HTTP transport
+const dashboardPrefix = "/ui/"
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// BreakdownRow is the integration of one month, target or filter
+type BreakdownRow struct {
+	Key     string         `json:"key"`
+	Frames  int            `json:"frames"`
+	Hours   float64        `json:"hours"`
+	Filters []BreakdownRow `json:"filters,omitempty"` // Per filter, for targets
+}
+
+// ArchiveBreakdown is the integration of the archive per month, target and
+// filter, most hours first (months in date order)
+type ArchiveBreakdown struct {
+	Months  []BreakdownRow `json:"months"`
+	Targets []BreakdownRow `json:"targets"`
+	Filters []BreakdownRow `json:"filters"`
+}
+
+// ArchiveBreakdown sums the integration of the archive per month, target and
+// filter for the dashboard. Like project progress, rejected frames are left
+// out and duplicate copies of a frame are counted once.
+func (d *Database) ArchiveBreakdown() (*ArchiveBreakdown, error) {
+	rows, err := d.db.Query(`
+		SELECT COALESCE(SUBSTR(observation_date, 1, 7), ''), COALESCE(object, ''), COALESCE(filter, ''),
+		       COUNT(*), TOTAL(exposure)
+		FROM (
+			SELECT MAX(f.observation_date) AS observation_date, MAX(f.object) AS object,
+			       MAX(f.filter) AS filter, MAX(f.exposure) AS exposure
+			FROM usable_files f
+			GROUP BY COALESCE(f.hash, 'id:' || f.id)
+		)
+		GROUP BY 1, 2, 3
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum integration: %w", err)
+	}
+	defer rows.Close()
+
+	months := map[string]*BreakdownRow{}
+	targets := map[string]*BreakdownRow{}
+	targetFilters := map[string]map[string]*BreakdownRow{}
+	filters := map[string]*BreakdownRow{}
+	add := func(m map[string]*BreakdownRow, key string, frames int, hours float64) {
+		row := m[key]
+		if row == nil {
+			row = &BreakdownRow{Key: key}
+			m[key] = row
+		}
+		row.Frames += frames
+		row.Hours += hours
+	}
+
+	for rows.Next() {
+		var month, target, filter string
+		var frames int
+		var seconds float64
+		if err := rows.Scan(&month, &target, &filter, &frames, &seconds); err != nil {
+			return nil, err
+		}
+		hours := seconds / 3600
+		add(months, month, frames, hours)
+		add(targets, target, frames, hours)
+		add(filters, filter, frames, hours)
+		if targetFilters[target] == nil {
+			targetFilters[target] = map[string]*BreakdownRow{}
+		}
+		add(targetFilters[target], filter, frames, hours)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	breakdown := &ArchiveBreakdown{
+		Months:  breakdownRows(months),
+		Targets: breakdownRows(targets),
+		Filters: breakdownRows(filters),
+	}
+	sort.Slice(breakdown.Months, func(i, j int) bool { return breakdown.Months[i].Key < breakdown.Months[j].Key })
+	for i, t := range breakdown.Targets {
+		breakdown.Targets[i].Filters = breakdownRows(targetFilters[t.Key])
+	}
+	return breakdown, nil
+}
+
+// breakdownRows returns rows with rounded hours, most hours first
+func breakdownRows(m map[string]*BreakdownRow) []BreakdownRow {
+	rows := make([]BreakdownRow, 0, len(m))
+	for _, row := range m {
+		row.Hours = roundHours(row.Hours)
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Hours != rows[j].Hours {
+			return rows[i].Hours > rows[j].Hours
+		}
+		return strings.ToLower(rows[i].Key) < strings.ToLower(rows[j].Key)
+	})
+	return rows
+}
+
+// isDashboardAsset reports whether path is one of the embedded dashboard
+// files, which can be served without an API key
+func isDashboardAsset(path string) bool {
+	if path == strings.TrimSuffix(dashboardPrefix, "/") {
+		return true
+	}
+	name, ok := strings.CutPrefix(path, dashboardPrefix)
+	if !ok {
+		return false
+	}
+	if name == "" {
+		return true
+	}
+	_, err := fs.Stat(dashboardFiles, "dashboard/"+name)
+	return err == nil
+}
+
+// registerDashboard serves the embedded web dashboard under dashboardPrefix.
+// The page reads the REST API; the integration breakdown, which has no tool,
+// is served next to it.
+func registerDashboard(mux *http.ServeMux, db *Database) {
+	files, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		panic(err) // The directory is embedded at build time
+	}
+	mux.Handle(dashboardPrefix, http.StripPrefix(dashboardPrefix, http.FileServerFS(files)))
+	mux.HandleFunc("GET "+dashboardPrefix+"breakdown.json", func(w http.ResponseWriter, r *http.Request) {
+		breakdown, err := db.ArchiveBreakdown()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, breakdown)
+	})
+	mux.Handle("GET /ui", http.RedirectHandler(dashboardPrefix, http.StatusMovedPermanently))
+}
@@ -0,0 +1,225 @@
+// Astro AI Archiver dashboard: reads the REST API under /api/v1 and the
+// integration breakdown served next to this page.
+"use strict";
+
+const api = "/api/v1";
+const pageSize = 50;
+let frameOffset = 0;
+
+// Servers with API keys answer 401 until the key is sent; it is asked once
+// and kept in this browser
+let keyPrompt = null;
+
+function askKey() {
+  keyPrompt ??= Promise.resolve().then(() => {
+    const key = window.prompt("API key for this archive:");
+    if (key) {
+      localStorage.setItem("apiKey", key.trim());
+    }
+    keyPrompt = null;
+    return Boolean(key);
+  });
+  return keyPrompt;
+}
+
+async function getJSON(url, options = {}) {
+  const key = localStorage.getItem("apiKey");
+  const headers = key ? { Authorization: `Bearer ${key}` } : {};
+  const response = await fetch(url, { ...options, headers });
+  if (response.status === 401) {
+    if (key && localStorage.getItem("apiKey") === key) {
+      localStorage.removeItem("apiKey");
+    }
+    if (localStorage.getItem("apiKey") || (await askKey())) {
+      return getJSON(url, options);
+    }
+    throw new Error("an API key is required");
+  }
+  const body = await response.json();
+  if (!response.ok) {
+    throw new Error(body.error || response.statusText);
+  }
+  return body;
+}
+
+// Nullable database values arrive as {String, Valid} objects
+function value(v) {
+  if (v && typeof v === "object" && "Valid" in v) {
+    return v.Valid ? v.String ?? v.Float64 ?? v.Int64 : "";
+  }
+  return v ?? "";
+}
+
+function hours(seconds) {
+  return (seconds / 3600).toFixed(1);
+}
+
+function cell(text, className) {
+  const td = document.createElement("td");
+  td.textContent = text;
+  if (className) {
+    td.className = className;
+  }
+  return td;
+}
+
+function fillTable(id, rows, render) {
+  const body = document.querySelector(`#${id} tbody`);
+  body.replaceChildren();
+  for (const row of rows) {
+    const tr = document.createElement("tr");
+    tr.append(...render(row));
+    body.append(tr);
+  }
+  if (rows.length === 0) {
+    const tr = document.createElement("tr");
+    const td = cell("Nothing yet", "muted");
+    td.colSpan = document.querySelectorAll(`#${id} th`).length;
+    tr.append(td);
+    body.append(tr);
+  }
+}
+
+async function loadTotals() {
+  const { summary } = await getJSON(`${api}/summary`);
+  const cards = [
+    [summary.total_files, "frames"],
+    [hours(summary.total_exposure_seconds), "hours integrated"],
+    [summary.unique_targets.length, "targets"],
+    [summary.unique_filters.length, "filters"],
+    [summary.unique_telescopes.length, "telescopes"],
+  ];
+  if (!summary.oldest_observation.startsWith("0001")) {
+    cards.push([summary.oldest_observation.slice(0, 4) + "–" + summary.newest_observation.slice(0, 4), "observing years"]);
+  }
+  if (summary.rejected_files > 0) {
+    cards.push([summary.rejected_files, "rejected frames (not counted)"]);
+  }
+  document.getElementById("totals").replaceChildren(...cards.map(([v, label]) => {
+    const div = document.createElement("div");
+    div.className = "card";
+    div.innerHTML = `<div class="value"></div><div class="label"></div>`;
+    div.querySelector(".value").textContent = v;
+    div.querySelector(".label").textContent = label;
+    return div;
+  }));
+}
+
+async function loadBreakdown() {
+  const breakdown = await getJSON("breakdown.json");
+
+  const chart = document.getElementById("months");
+  const max = Math.max(1, ...breakdown.months.map((m) => m.hours));
+  chart.replaceChildren(...breakdown.months.map((m) => {
+    const bar = document.createElement("div");
+    bar.className = "bar";
+    bar.style.height = `${(100 * m.hours) / max}%`;
+    bar.title = `${m.key || "unknown date"}: ${m.hours} h, ${m.frames} frames`;
+    return bar;
+  }));
+
+  fillTable("targets", breakdown.targets.slice(0, 25), (t) => [
+    cell(t.key || "(no name)"),
+    cell(t.frames, "num"),
+    cell(t.hours.toFixed(1), "num"),
+    cell((t.filters || []).map((f) => `${f.key || "?"} ${f.hours.toFixed(1)} h`).join(", "), "muted"),
+  ]);
+  fillTable("filters", breakdown.filters, (f) => [
+    cell(f.key || "(none)"),
+    cell(f.frames, "num"),
+    cell(f.hours.toFixed(1), "num"),
+  ]);
+}
+
+async function loadSessions() {
+  const { sessions } = await getJSON(`${api}/sessions?limit=10`);
+  fillTable("sessions", sessions || [], (s) => [
+    cell(s.ObservationDate),
+    cell(s.Telescope),
+    cell(s.Camera),
+    cell((s.Targets || []).join(", ")),
+    cell((s.Filters || []).join(", ")),
+    cell(s.FrameCount, "num"),
+    cell(hours(s.Integration), "num"),
+  ]);
+}
+
+async function loadFrames() {
+  const params = new URLSearchParams({ limit: pageSize, offset: frameOffset });
+  for (const [name, v] of new FormData(document.getElementById("search"))) {
+    if (v) {
+      params.set(name, v);
+    }
+  }
+  const { files } = await getJSON(`${api}/files?${params}`);
+  fillTable("frames", files || [], (f) => [
+    cell(value(f.UTCTime).replace("T", " ")),
+    cell(f.Object),
+    cell(f.Filter),
+    cell(f.Exposure, "num"),
+    cell(f.Telescope),
+    cell(f.Camera),
+    cell(f.Metrics ? f.Metrics.FWHM.toFixed(2) : "", "num"),
+    cell(f.Grade, f.Grade === "rejected" ? "rejected" : ""),
+    cell(f.RelativePath, "file"),
+  ]);
+  document.getElementById("page").textContent = `${frameOffset + 1}–${frameOffset + (files || []).length}`;
+  document.getElementById("prev").disabled = frameOffset === 0;
+  document.getElementById("next").disabled = (files || []).length < pageSize;
+}
+
+let scanning = false;
+
+async function loadScanStatus() {
+  const status = await getJSON(`${api}/scan`);
+  const wasScanning = scanning;
+  scanning = status.scanning;
+
+  let text = scanning ? "Scanning…" : "Idle";
+  if (!scanning && status.last_scan) {
+    text += ` · last scan ${new Date(status.last_scan).toLocaleString()}`;
+  }
+  document.getElementById("scan-status").textContent = text;
+  document.getElementById("scan-button").disabled = scanning;
+
+  if (scanning) {
+    setTimeout(() => loadScanStatus().catch(showError), 2000);
+  } else if (wasScanning) {
+    refresh();
+  }
+}
+
+async function startScan() {
+  document.getElementById("scan-button").disabled = true;
+  const result = await getJSON(`${api}/scan`, { method: "POST" });
+  document.getElementById("scan-status").textContent = result.message;
+  await loadScanStatus();
+}
+
+function showError(err) {
+  document.getElementById("scan-status").textContent = `Error: ${err.message}`;
+}
+
+function refresh() {
+  for (const load of [loadTotals, loadBreakdown, loadSessions, loadFrames]) {
+    load().catch(showError);
+  }
+}
+
+document.getElementById("scan-button").addEventListener("click", () => startScan().catch(showError));
+document.getElementById("search").addEventListener("submit", (event) => {
+  event.preventDefault();
+  frameOffset = 0;
+  loadFrames().catch(showError);
+});
+document.getElementById("prev").addEventListener("click", () => {
+  frameOffset = Math.max(0, frameOffset - pageSize);
+  loadFrames().catch(showError);
+});
+document.getElementById("next").addEventListener("click", () => {
+  frameOffset += pageSize;
+  loadFrames().catch(showError);
+});
+
+refresh();
+loadScanStatus().catch(showError);
@@ -0,0 +1,65 @@
+package mcpserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestArchiveBreakdown(t *testing.T) {
+	db, _ := newTestDatabase(t)
+	insertNGC7000Scenario(t, db)
+
+	breakdown, err := db.ArchiveBreakdown()
+	if err != nil {
+		t.Fatalf("ArchiveBreakdown: %v", err)
+	}
+
+	// The copy of ha2 and the rejected frame are not counted
+	months := breakdown.Months
+	if len(months) != 2 || months[0].Key != "2025-08" || months[0].Hours != 0.5 ||
+		months[1].Key != "2025-09" || months[1].Frames != 4 || months[1].Hours != 2.75 {
+		t.Errorf("months = %+v", months)
+	}
+	targets := breakdown.Targets
+	if len(targets) != 2 || targets[0].Key != "NGC7000" || targets[0].Hours != 2.25 || targets[1].Hours != 1 {
+		t.Fatalf("targets = %+v", targets)
+	}
+	if f := targets[0].Filters; len(f) != 2 || f[0].Key != "Ha" || f[0].Frames != 3 || f[1].Key != "OIII" {
+		t.Errorf("filters of NGC7000 = %+v", f)
+	}
+	if f := breakdown.Filters; len(f) != 3 || f[0].Key != "Ha" || f[0].Hours != 1.25 || f[1].Key != "L" || f[2].Key != "OIII" {
+		t.Errorf("filters = %+v", f)
+	}
+
+	mux := http.NewServeMux()
+	registerDashboard(mux, db)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if status, body := get("/ui"); status != http.StatusOK || !strings.Contains(body, "app.js") {
+		t.Errorf("GET /ui = %d", status)
+	}
+	if status, _ := get("/ui/app.js"); status != http.StatusOK {
+		t.Errorf("GET /ui/app.js = %d", status)
+	}
+	status, body := get("/ui/breakdown.json")
+	var served ArchiveBreakdown
+	if err := json.Unmarshal([]byte(body), &served); status != http.StatusOK || err != nil || len(served.Targets) != 2 {
+		t.Errorf("GET /ui/breakdown.json = %d %s", status, body)
+	}
+}
@@ -1,8 +1,10 @@
 package mcpserver
 
 import (
+	"context"
 	"crypto/sha256"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -17,46 +19,6 @@ import (
 	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
 )
 
-const schemaVersion = 1
-
-const schema = `
-CREATE TABLE IF NOT EXISTS fits_files (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    relative_path TEXT NOT NULL UNIQUE,
-    hash TEXT,
-    file_mod_time INTEGER,
-    row_mod_time INTEGER DEFAULT (strftime('%s', 'now')),
-    object TEXT NOT NULL,
-    ra REAL,
-    dec REAL,
-    telescope TEXT NOT NULL,
-    focal_length REAL,
-    exposure REAL NOT NULL,
-    utc_time TEXT,
-    local_time TEXT,
-    julian_date REAL,
-    observation_date TEXT,
-    software TEXT,
-    camera TEXT,
-    gain REAL,
-    offset INTEGER,
-    filter TEXT NOT NULL,
-    image_type TEXT
-);
-
-CREATE INDEX IF NOT EXISTS idx_object ON fits_files(object);
-CREATE INDEX IF NOT EXISTS idx_filter ON fits_files(filter);
-CREATE INDEX IF NOT EXISTS idx_telescope ON fits_files(telescope);
-CREATE INDEX IF NOT EXISTS idx_utc_time ON fits_files(utc_time);
-CREATE INDEX IF NOT EXISTS idx_hash ON fits_files(hash);
-CREATE INDEX IF NOT EXISTS idx_relative_path ON fits_files(relative_path);
-
-CREATE TABLE IF NOT EXISTS schema_version (
-    version INTEGER NOT NULL,
-    applied_at INTEGER DEFAULT (strftime('%s', 'now'))
-);
-`
-
 // Database handles all database operations
 type Database struct {
 	db          *sql.DB
@@ -64,10 +26,38 @@ type Database struct {
 	filePath    string
 	writeMu     sync.Mutex
 	commonNames map[string]string // ASIAIR common-name → catalog designation
+
+	analyzeOnScan   bool // Run the frame analysis pass after every scan
+	analysisWorkers int  // Parallel frame analyses, 0 = NumCPU
+
+	exportDir string // Where stacking sets are written, "" = exports next to the database
+
+	astrobinFilters []AstroBinFilterConfig // Filter names mapped to AstroBin filter ids
+
+	guideLogDirs  []string       // Extra directories with PHD2 guide logs
+	guideLocation *time.Location // Time zone of the guide logs
+
+	siteLat, siteLong *float64 // Observing site for frames without SITELAT/SITELONG
 }
 
-// NewDatabase creates a new database connection and initializes schema
+// NewDatabase opens the database and applies any pending schema migrations
 func NewDatabase(dbPath string, scanDirs []string) (*Database, error) {
+	database, err := OpenDatabase(dbPath, scanDirs)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := database.Migrate(context.Background()); err != nil {
+		database.Close()
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	return database, nil
+}
+
+// OpenDatabase opens the database connection without touching the schema.
+// It is used by the db CLI commands to inspect or migrate an archive.
+func OpenDatabase(dbPath string, scanDirs []string) (*Database, error) {
 	// If dbPath is empty, use default location in first scan directory
 	if dbPath == "" {
 		if len(scanDirs) == 0 {
@@ -84,40 +74,26 @@ func NewDatabase(dbPath string, scanDirs []string) (*Database, error) {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
-	// Open database
-	db, err := sql.Open("sqlite", dbPath)
+	// Open database. Connection-scoped pragmas go into the DSN so that every
+	// pooled connection gets them, not just the first one: foreign keys are
+	// needed for ON DELETE CASCADE and the busy timeout (10 seconds) for
+	// concurrent writes.
+	dsn := dbPath + "?_pragma=busy_timeout(10000)&_pragma=foreign_keys(1)&_pragma=synchronous(NORMAL)"
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Configure SQLite for better performance and concurrency
+	// WAL mode is persistent in the database file, so setting it once is enough
 	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
 		return nil, fmt.Errorf("failed to set WAL mode: %w", err)
 	}
-	if _, err := db.Exec("PRAGMA synchronous=NORMAL"); err != nil {
-		return nil, fmt.Errorf("failed to set synchronous mode: %w", err)
-	}
-	if _, err := db.Exec("PRAGMA foreign_keys=ON"); err != nil {
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
-	}
-	// Set busy timeout to 10 seconds for concurrent writes
-	if _, err := db.Exec("PRAGMA busy_timeout=10000"); err != nil {
-		return nil, fmt.Errorf("failed to set busy timeout: %w", err)
-	}
 
-	database := &Database{
+	return &Database{
 		db:       db,
 		baseDirs: scanDirs,
 		filePath: dbPath,
-	}
-
-	// Initialize schema
-	if err := database.initSchema(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
-	}
-
-	return database, nil
+	}, nil
 }
 
 // GetFilePath returns the database file path (implements tools.Database interface)
@@ -125,6 +101,29 @@ func (db *Database) GetFilePath() string {
 	return db.filePath
 }
 
+// CountAllFiles returns the number of records DeleteAllFiles would remove
+func (db *Database) CountAllFiles() (int64, error) {
+	var n int64
+	if err := db.db.QueryRow("SELECT COUNT(*) FROM fits_files").Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count records: %w", err)
+	}
+	return n, nil
+}
+
+// CountFilesByYear returns the number of records DeleteFilesByYear would
+// remove for the given year
+func (db *Database) CountFilesByYear(year int) (int64, error) {
+	var n int64
+	err := db.db.QueryRow(
+		"SELECT COUNT(*) FROM fits_files WHERE strftime('%Y', observation_date) = ?",
+		fmt.Sprintf("%04d", year),
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count records for year %d: %w", year, err)
+	}
+	return n, nil
+}
+
 // DeleteAllFiles removes all records from the fits_files table and returns the number of deleted rows.
 func (db *Database) DeleteAllFiles() (int64, error) {
 	result, err := db.db.Exec("DELETE FROM fits_files")
@@ -136,6 +135,9 @@ func (db *Database) DeleteAllFiles() (int64, error) {
 		return 0, fmt.Errorf("failed to get rows affected: %w", err)
 	}
 	log.Info().Int64("deleted", n).Msg("All records deleted from fits_files")
+	if _, _, err := db.RefreshSessions(); err != nil {
+		log.Error().Err(err).Msg("Session refresh after delete failed")
+	}
 	return n, nil
 }
 
@@ -154,6 +156,9 @@ func (db *Database) DeleteFilesByYear(year int) (int64, error) {
 		return 0, fmt.Errorf("failed to get rows affected: %w", err)
 	}
 	log.Info().Int("year", year).Int64("deleted", n).Msg("Records deleted from fits_files by year")
+	if _, _, err := db.RefreshSessions(); err != nil {
+		log.Error().Err(err).Msg("Session refresh after delete failed")
+	}
 	return n, nil
 }
 
@@ -165,41 +170,29 @@ func (db *Database) SetCommonNames(m map[string]string) {
 
 // NewScanner creates a new scanner (implements tools.Database interface)
 func (db *Database) NewScanner(directories []string, recursive, force bool) interface{} {
-	return NewScanner(db, directories, recursive, force, 0, db.commonNames)
+	return toolScanner{NewScanner(db, directories, recursive, force, 0, db.commonNames)}
 }
 
-// initSchema creates the database schema if it doesn't exist
-func (d *Database) initSchema() error {
-	log.Debug().Msg("Initializing database schema")
-
-	// Execute schema
-	if _, err := d.db.Exec(schema); err != nil {
-		return fmt.Errorf("failed to create schema: %w", err)
-	}
+// toolScanner is a Scanner whose Scan returns the result type of the tools
+// package, which rescan_fits_directory expects
+type toolScanner struct {
+	*Scanner
+}
 
-	// Check and insert schema version
-	var count int
-	err := d.db.QueryRow("SELECT COUNT(*) FROM schema_version").Scan(&count)
+func (t toolScanner) Scan() (*tools.ScanResult, error) {
+	result, err := t.Scanner.Scan()
 	if err != nil {
-		return fmt.Errorf("failed to check schema version: %w", err)
+		return nil, err
 	}
-
-	if count == 0 {
-		_, err := d.db.Exec("INSERT INTO schema_version (version) VALUES (?)", schemaVersion)
-		if err != nil {
-			return fmt.Errorf("failed to insert schema version: %w", err)
-		}
-		log.Info().Int("version", schemaVersion).Msg("Database schema created")
-	} else {
-		var version int
-		err := d.db.QueryRow("SELECT version FROM schema_version ORDER BY applied_at DESC LIMIT 1").Scan(&version)
-		if err != nil {
-			return fmt.Errorf("failed to read schema version: %w", err)
-		}
-		log.Debug().Int("version", version).Msg("Database schema version")
+	r := &tools.ScanResult{
+		FilesAdded:   result.FilesAdded,
+		FilesUpdated: result.FilesUpdated,
+		FilesDeleted: result.FilesDeleted,
 	}
-
-	return nil
+	for _, e := range result.Errors {
+		r.Errors = append(r.Errors, errors.New(e))
+	}
+	return r, nil
 }
 
 // validateReadOnlyQuery ensures the query is safe (SELECT only)
@@ -245,8 +238,12 @@ func (d *Database) InsertOrUpdateFile(file *FITSFile) error {
 		INSERT INTO fits_files (
 			relative_path, hash, file_mod_time, object, ra, dec, telescope,
 			focal_length, exposure, utc_time, local_time, julian_date, observation_date,
-			software, camera, gain, offset, filter, image_type
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			software, camera, gain, offset, filter, image_type,
+			ccd_temp, set_temp, x_binning, y_binning, naxis1, naxis2, pixel_size,
+			bayer_pattern, airmass, object_alt, site_lat, site_long,
+			focuser_position, focuser_temp, pier_side, rotator, readout_mode
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(relative_path) DO UPDATE SET
 			hash = excluded.hash,
 			file_mod_time = excluded.file_mod_time,
@@ -266,149 +263,543 @@ func (d *Database) InsertOrUpdateFile(file *FITSFile) error {
 			gain = excluded.gain,
 			offset = excluded.offset,
 			filter = excluded.filter,
-			image_type = excluded.image_type
+			image_type = excluded.image_type,
+			ccd_temp = excluded.ccd_temp,
+			set_temp = excluded.set_temp,
+			x_binning = excluded.x_binning,
+			y_binning = excluded.y_binning,
+			naxis1 = excluded.naxis1,
+			naxis2 = excluded.naxis2,
+			pixel_size = excluded.pixel_size,
+			bayer_pattern = excluded.bayer_pattern,
+			airmass = excluded.airmass,
+			object_alt = excluded.object_alt,
+			site_lat = excluded.site_lat,
+			site_long = excluded.site_long,
+			focuser_position = excluded.focuser_position,
+			focuser_temp = excluded.focuser_temp,
+			pier_side = excluded.pier_side,
+			rotator = excluded.rotator,
+			readout_mode = excluded.readout_mode
 	`
 
-	_, err := d.db.Exec(query,
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The guiding RMS covers the exposure window, so it is correlated again
+	// when a re-indexed frame's start time or exposure changed
+	_, err = tx.Exec(`
+		DELETE FROM frame_guiding WHERE file_id IN (
+			SELECT id FROM fits_files
+			WHERE relative_path = ? AND (utc_time IS NOT ? OR exposure IS NOT ?)
+		)
+	`, file.RelativePath, file.UTCTime, file.Exposure)
+	if err != nil {
+		return fmt.Errorf("failed to reset guiding: %w", err)
+	}
+
+	var fileID int64
+	err = tx.QueryRow(query+" RETURNING id",
 		file.RelativePath, file.Hash, file.FileModTime, file.Object,
 		file.RA, file.Dec, file.Telescope, file.FocalLength, file.Exposure,
 		file.UTCTime, file.LocalTime, file.JulianDate, file.ObservationDate, file.Software,
 		file.Camera, file.Gain, file.Offset, file.Filter, file.ImageType,
+		file.CCDTemp, file.SetTemp, file.XBinning, file.YBinning, file.Naxis1, file.Naxis2, file.PixelSize,
+		file.BayerPattern, file.Airmass, file.ObjectAlt, file.SiteLat, file.SiteLong,
+		file.FocuserPosition, file.FocuserTemp, file.PierSide, file.Rotator, file.ReadoutMode,
+	).Scan(&fileID)
+	if err != nil {
+		return err
+	}
+
+	if err := upsertFileWCS(tx, fileID, file.WCS); err != nil {
+		return fmt.Errorf("failed to store WCS: %w", err)
+	}
+	if err := upsertFileConditions(tx, fileID, file.Conditions); err != nil {
+		return fmt.Errorf("failed to store conditions: %w", err)
+	}
+	ephemeris, site := d.frameEphemeris(file)
+	if err := upsertFileEphemeris(tx, fileID, ephemeris, site); err != nil {
+		return fmt.Errorf("failed to store ephemeris: %w", err)
+	}
+	if err := upsertSkyIndex(tx, fileID, file); err != nil {
+		return fmt.Errorf("failed to update sky index: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// upsertFileWCS stores the plate-solve geometry for a file, or removes a stale
+// one when the file no longer carries a WCS (e.g. it was re-captured).
+func upsertFileWCS(tx *sql.Tx, fileID int64, wcs *FrameWCS) error {
+	if wcs == nil {
+		_, err := tx.Exec("DELETE FROM fits_wcs WHERE file_id = ?", fileID)
+		return err
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO fits_wcs (
+			file_id, center_ra, center_dec, rotation, pixel_scale,
+			corner1_ra, corner1_dec, corner2_ra, corner2_dec,
+			corner3_ra, corner3_dec, corner4_ra, corner4_dec
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(file_id) DO UPDATE SET
+			center_ra = excluded.center_ra,
+			center_dec = excluded.center_dec,
+			rotation = excluded.rotation,
+			pixel_scale = excluded.pixel_scale,
+			corner1_ra = excluded.corner1_ra,
+			corner1_dec = excluded.corner1_dec,
+			corner2_ra = excluded.corner2_ra,
+			corner2_dec = excluded.corner2_dec,
+			corner3_ra = excluded.corner3_ra,
+			corner3_dec = excluded.corner3_dec,
+			corner4_ra = excluded.corner4_ra,
+			corner4_dec = excluded.corner4_dec
+	`,
+		fileID, wcs.CenterRA, wcs.CenterDec, wcs.Rotation, wcs.PixelScale,
+		wcs.Corner1RA, wcs.Corner1Dec, wcs.Corner2RA, wcs.Corner2Dec,
+		wcs.Corner3RA, wcs.Corner3Dec, wcs.Corner4RA, wcs.Corner4Dec,
 	)
+	return err
+}
+
+// upsertFileConditions stores the observing conditions of a file, or removes
+// stale ones when the file no longer carries any.
+func upsertFileConditions(tx *sql.Tx, fileID int64, c *FrameConditions) error {
+	if c == nil {
+		_, err := tx.Exec("DELETE FROM frame_conditions WHERE file_id = ?", fileID)
+		return err
+	}
 
+	_, err := tx.Exec(`
+		INSERT INTO frame_conditions (
+			file_id, ambient_temp, humidity, pressure, dew_point, sky_quality, cloud_cover, wind_speed
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(file_id) DO UPDATE SET
+			ambient_temp = excluded.ambient_temp,
+			humidity = excluded.humidity,
+			pressure = excluded.pressure,
+			dew_point = excluded.dew_point,
+			sky_quality = excluded.sky_quality,
+			cloud_cover = excluded.cloud_cover,
+			wind_speed = excluded.wind_speed
+	`, fileID, c.AmbientTemp, c.Humidity, c.Pressure, c.DewPoint, c.SkyQuality, c.CloudCover, c.WindSpeed)
 	return err
 }
 
 // GetFileByPath retrieves a file by its relative path
 func (d *Database) GetFileByPath(relativePath string) (interface{}, error) {
-	query := `
-		SELECT id, relative_path, hash, file_mod_time, row_mod_time,
-			   object, ra, dec, telescope, focal_length, exposure,
-			   utc_time, local_time, julian_date, observation_date, software, camera,
-			   gain, offset, filter, image_type
-		FROM fits_files
-		WHERE relative_path = ?
-	`
+	query := fitsFileSelect + " WHERE f.relative_path = ?"
 
+	file, err := scanFITSFile(d.db.QueryRow(query, relativePath))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// GetFileByID retrieves a file by its id, or nil when it does not exist
+func (d *Database) GetFileByID(id int64) (*FITSFile, error) {
+	file, err := scanFITSFile(d.db.QueryRow(fitsFileSelect+" WHERE f.id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return file, err
+}
+
+// fitsFileSelect selects every fits_files column plus the optional plate-solve
+// geometry from fits_wcs (alias w), quality metrics from frame_metrics
+// (alias m), ephemeris from frame_ephemeris (alias e) and the grade from
+// frame_marks (alias g). Rows are decoded with scanFITSFile.
+const fitsFileSelect = `
+	SELECT f.id, f.relative_path, f.hash, f.file_mod_time, f.row_mod_time,
+		   f.object, f.ra, f.dec, f.telescope, f.focal_length, f.exposure,
+		   f.utc_time, f.local_time, f.julian_date, f.observation_date, f.software, f.camera,
+		   f.gain, f.offset, f.filter, f.image_type,
+		   f.ccd_temp, f.set_temp, f.x_binning, f.y_binning, f.naxis1, f.naxis2, f.pixel_size,
+		   COALESCE(f.bayer_pattern, ''), f.airmass, f.object_alt, f.site_lat, f.site_long,
+		   f.focuser_position, f.focuser_temp, COALESCE(f.pier_side, ''), f.rotator,
+		   COALESCE(f.readout_mode, ''),
+		   w.center_ra, w.center_dec, w.rotation, w.pixel_scale,
+		   w.corner1_ra, w.corner1_dec, w.corner2_ra, w.corner2_dec,
+		   w.corner3_ra, w.corner3_dec, w.corner4_ra, w.corner4_dec,
+		   m.background, m.noise, m.star_count, m.hfr, m.fwhm, m.eccentricity, m.analyzed_at, m.source,
+		   e.moon_illumination, e.moon_alt, e.moon_separation, e.target_alt, e.target_az, e.airmass,
+		   COALESCE(g.grade, ''), COALESCE(g.reason, '')
+	FROM fits_files f
+	LEFT JOIN fits_wcs w ON w.file_id = f.id
+	LEFT JOIN frame_metrics m ON m.file_id = f.id
+	LEFT JOIN frame_ephemeris e ON e.file_id = f.id
+	LEFT JOIN frame_marks g ON g.hash = f.hash
+`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanFITSFile decodes one row produced by fitsFileSelect
+func scanFITSFile(row rowScanner) (*FITSFile, error) {
 	file := &FITSFile{}
-	err := d.db.QueryRow(query, relativePath).Scan(
+	var centerRA, centerDec, rotation, pixelScale sql.NullFloat64
+	var corners [8]sql.NullFloat64
+	var background, noise, hfr, fwhm, ecc sql.NullFloat64
+	var starCount, analyzedAt sql.NullInt64
+	var metricsSource sql.NullString
+	var moonIllumination, moonAlt, moonSeparation, targetAlt, targetAz, airmass sql.NullFloat64
+
+	err := row.Scan(
 		&file.ID, &file.RelativePath, &file.Hash, &file.FileModTime,
 		&file.RowModTime, &file.Object, &file.RA, &file.Dec, &file.Telescope,
 		&file.FocalLength, &file.Exposure, &file.UTCTime, &file.LocalTime,
 		&file.JulianDate, &file.ObservationDate, &file.Software, &file.Camera, &file.Gain,
 		&file.Offset, &file.Filter, &file.ImageType,
+		&file.CCDTemp, &file.SetTemp, &file.XBinning, &file.YBinning, &file.Naxis1, &file.Naxis2, &file.PixelSize,
+		&file.BayerPattern, &file.Airmass, &file.ObjectAlt, &file.SiteLat, &file.SiteLong,
+		&file.FocuserPosition, &file.FocuserTemp, &file.PierSide, &file.Rotator,
+		&file.ReadoutMode,
+		&centerRA, &centerDec, &rotation, &pixelScale,
+		&corners[0], &corners[1], &corners[2], &corners[3],
+		&corners[4], &corners[5], &corners[6], &corners[7],
+		&background, &noise, &starCount, &hfr, &fwhm, &ecc, &analyzedAt, &metricsSource,
+		&moonIllumination, &moonAlt, &moonSeparation, &targetAlt, &targetAz, &airmass,
+		&file.Grade, &file.GradeReason,
 	)
-
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
 	if err != nil {
 		return nil, err
 	}
 
+	if centerRA.Valid && centerDec.Valid {
+		file.WCS = &FrameWCS{
+			CenterRA:   centerRA.Float64,
+			CenterDec:  centerDec.Float64,
+			Rotation:   rotation.Float64,
+			PixelScale: pixelScale.Float64,
+			Corner1RA:  corners[0].Float64,
+			Corner1Dec: corners[1].Float64,
+			Corner2RA:  corners[2].Float64,
+			Corner2Dec: corners[3].Float64,
+			Corner3RA:  corners[4].Float64,
+			Corner3Dec: corners[5].Float64,
+			Corner4RA:  corners[6].Float64,
+			Corner4Dec: corners[7].Float64,
+		}
+	}
+
+	if metricsSource.Valid {
+		file.Metrics = &FrameMetrics{
+			Background:   background.Float64,
+			Noise:        noise.Float64,
+			StarCount:    int(starCount.Int64),
+			HFR:          hfr.Float64,
+			FWHM:         fwhm.Float64,
+			Eccentricity: ecc.Float64,
+			Source:       metricsSource.String,
+			AnalyzedAt:   analyzedAt.Int64,
+		}
+	}
+
+	if moonIllumination.Valid {
+		file.Ephemeris = &FrameEphemeris{
+			MoonIllumination: moonIllumination.Float64,
+			MoonAlt:          moonAlt.Float64,
+			MoonSeparation:   moonSeparation.Float64,
+			TargetAlt:        targetAlt.Float64,
+			TargetAz:         targetAz.Float64,
+		}
+		if airmass.Valid {
+			file.Ephemeris.Airmass = &airmass.Float64
+		}
+	}
+
 	return file, nil
 }
 
 // QueryFiles performs a flexible query on FITS files
 func (d *Database) QueryFiles(filters map[string]interface{}, limit, offset int) (interface{}, error) {
-	query := `
-		SELECT id, relative_path, hash, file_mod_time, row_mod_time,
-			   object, ra, dec, telescope, focal_length, exposure,
-			   utc_time, local_time, julian_date, observation_date, software, camera,
-			   gain, offset, filter, image_type
-		FROM fits_files
-		WHERE 1=1
-	`
+	where, args := buildFileFilters(filters)
+	query := fitsFileSelect + " WHERE 1=1" + where
+
+	query += " ORDER BY " + fileOrder(filters) + " LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	log.Debug().
+		Str("query", query).
+		Interface("args", args).
+		Msg("Executing query")
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := []*FITSFile{}
+	for rows.Next() {
+		file, err := scanFITSFile(rows)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, rows.Err()
+}
+
+// airmassExpr and altitudeExpr are the header airmass and target altitude of
+// frame f, or the computed ones when the header has none
+const (
+	airmassExpr  = "COALESCE(f.airmass, (SELECT ep.airmass FROM frame_ephemeris ep WHERE ep.file_id = f.id))"
+	altitudeExpr = "COALESCE(f.object_alt, (SELECT ep.target_alt FROM frame_ephemeris ep WHERE ep.file_id = f.id))"
+)
+
+// notRejected is the condition on alias f that leaves out rejected frames
+const notRejected = "NOT EXISTS (SELECT 1 FROM frame_marks fm WHERE fm.hash = f.hash AND fm.grade = 'rejected')"
+
+// selectsFrames reports whether filters narrow the selection down from the
+// whole archive; include_rejected alone does not.
+func selectsFrames(filters map[string]interface{}) bool {
+	probe := make(map[string]interface{}, len(filters)+1)
+	for k, v := range filters {
+		probe[k] = v
+	}
+	probe["include_rejected"] = true
+	where, _ := buildFileFilters(probe)
+	return where != ""
+}
+
+// buildFileFilters turns the query_fits_archive filter arguments into a list of
+// "AND ..." conditions on the fits_files alias f, plus their arguments.
+func buildFileFilters(filters map[string]interface{}) (string, []interface{}) {
+	query := ""
 	args := []interface{}{}
 
-	// Build dynamic WHERE clause
 	if target, ok := filters["target"].(string); ok && target != "" {
-		query += " AND object LIKE ?"
+		query += " AND f.object LIKE ?"
 		args = append(args, "%"+target+"%")
 	}
 	if filter, ok := filters["filter"].(string); ok && filter != "" {
-		query += " AND filter = ?"
+		query += " AND f.filter = ?"
 		args = append(args, filter)
 	}
 	if telescope, ok := filters["telescope"].(string); ok && telescope != "" {
-		query += " AND telescope LIKE ?"
+		query += " AND f.telescope LIKE ?"
 		args = append(args, "%"+telescope+"%")
 	}
 	if camera, ok := filters["camera"].(string); ok && camera != "" {
-		query += " AND camera LIKE ?"
+		query += " AND f.camera LIKE ?"
 		args = append(args, "%"+camera+"%")
 	}
 	if software, ok := filters["software"].(string); ok && software != "" {
-		query += " AND software LIKE ?"
+		query += " AND f.software LIKE ?"
 		args = append(args, "%"+software+"%")
 	}
 	if dateFrom, ok := filters["date_from"].(string); ok && dateFrom != "" {
-		query += " AND utc_time >= ?"
+		query += " AND f.utc_time >= ?"
 		args = append(args, dateFrom)
 	}
 	if dateTo, ok := filters["date_to"].(string); ok && dateTo != "" {
-		query += " AND utc_time <= ?"
+		query += " AND f.utc_time <= ?"
 		args = append(args, dateTo)
 	}
 	if minExp, ok := filters["min_exposure"].(float64); ok {
-		query += " AND exposure >= ?"
+		query += " AND f.exposure >= ?"
 		args = append(args, minExp)
 	}
 	if maxExp, ok := filters["max_exposure"].(float64); ok {
-		query += " AND exposure <= ?"
+		query += " AND f.exposure <= ?"
 		args = append(args, maxExp)
 	}
 	if minGain, ok := filters["gain_min"].(float64); ok {
-		query += " AND gain >= ?"
+		query += " AND f.gain >= ?"
 		args = append(args, minGain)
 	}
 	if maxGain, ok := filters["gain_max"].(float64); ok {
-		query += " AND gain <= ?"
+		query += " AND f.gain <= ?"
 		args = append(args, maxGain)
 	}
+	if binning, ok := filters["binning"].(float64); ok {
+		query += " AND f.x_binning = ?"
+		args = append(args, int(binning))
+	}
+	if minTemp, ok := filters["ccd_temp_min"].(float64); ok {
+		query += " AND f.ccd_temp >= ?"
+		args = append(args, minTemp)
+	}
+	if maxTemp, ok := filters["ccd_temp_max"].(float64); ok {
+		query += " AND f.ccd_temp <= ?"
+		args = append(args, maxTemp)
+	}
+	if setTemp, ok := filters["set_temp"].(float64); ok {
+		query += " AND f.set_temp = ?"
+		args = append(args, setTemp)
+	}
+	if minAirmass, ok := filters["airmass_min"].(float64); ok {
+		query += " AND " + airmassExpr + " >= ?"
+		args = append(args, minAirmass)
+	}
+	if maxAirmass, ok := filters["airmass_max"].(float64); ok {
+		query += " AND " + airmassExpr + " <= ?"
+		args = append(args, maxAirmass)
+	}
+	if minAlt, ok := filters["altitude_min"].(float64); ok {
+		query += " AND " + altitudeExpr + " >= ?"
+		args = append(args, minAlt)
+	}
+	if bayer, ok := filters["bayer_pattern"].(string); ok && bayer != "" {
+		query += " AND f.bayer_pattern = ?"
+		args = append(args, strings.ToUpper(bayer))
+	}
+	if pierSide, ok := filters["pier_side"].(string); ok && pierSide != "" {
+		query += " AND f.pier_side = ?"
+		args = append(args, strings.ToUpper(pierSide))
+	}
+	if readout, ok := filters["readout_mode"].(string); ok && readout != "" {
+		query += " AND f.readout_mode LIKE ?"
+		args = append(args, "%"+readout+"%")
+	}
 
-	query += " ORDER BY utc_time DESC LIMIT ? OFFSET ?"
-	args = append(args, limit, offset)
+	if minFWHM, ok := filters["fwhm_min"].(float64); ok {
+		query += " AND m.fwhm >= ?"
+		args = append(args, minFWHM)
+	}
+	if maxFWHM, ok := filters["fwhm_max"].(float64); ok {
+		query += " AND m.fwhm <= ?"
+		args = append(args, maxFWHM)
+	}
+	if maxHFR, ok := filters["hfr_max"].(float64); ok {
+		query += " AND m.hfr <= ?"
+		args = append(args, maxHFR)
+	}
+	if maxEcc, ok := filters["eccentricity_max"].(float64); ok {
+		query += " AND m.eccentricity <= ?"
+		args = append(args, maxEcc)
+	}
+	if minEcc, ok := filters["eccentricity_min"].(float64); ok {
+		query += " AND m.eccentricity >= ?"
+		args = append(args, minEcc)
+	}
+	if minStars, ok := filters["star_count_min"].(float64); ok {
+		query += " AND m.star_count >= ?"
+		args = append(args, int(minStars))
+	}
+	if maxStars, ok := filters["star_count_max"].(float64); ok {
+		query += " AND m.star_count <= ?"
+		args = append(args, int(maxStars))
+	}
+	// Moon and target geometry; frames without ephemeris do not match
+	for _, c := range []struct{ filter, condition string }{
+		{"moon_separation_min", "ep.moon_separation >= ?"},
+		{"moon_separation_max", "ep.moon_separation <= ?"},
+		{"moon_illumination_max", "ep.moon_illumination <= ?"},
+		{"moon_alt_max", "ep.moon_alt <= ?"},
+	} {
+		if v, ok := filters[c.filter].(float64); ok {
+			query += " AND EXISTS (SELECT 1 FROM frame_ephemeris ep WHERE ep.file_id = f.id AND " + c.condition + ")"
+			args = append(args, v)
+		}
+	}
 
-	log.Debug().
-		Str("query", query).
-		Interface("args", args).
-		Msg("Executing query")
+	// Observing conditions; frames without the value do not match
+	for _, c := range []struct{ filter, condition string }{
+		{"humidity_max", "c.humidity <= ?"},
+		{"sky_quality_min", "c.sky_quality >= ?"},
+		{"cloud_cover_max", "c.cloud_cover <= ?"},
+		{"wind_speed_max", "c.wind_speed <= ?"},
+	} {
+		if v, ok := filters[c.filter].(float64); ok {
+			query += " AND EXISTS (SELECT 1 FROM frame_conditions c WHERE c.file_id = f.id AND " + c.condition + ")"
+			args = append(args, v)
+		}
+	}
+	// Rejected frames are left out unless asked for
+	switch grade, _ := filters["grade"].(string); strings.ToLower(grade) {
+	case "accepted", "rejected":
+		query += " AND EXISTS (SELECT 1 FROM frame_marks fm WHERE fm.hash = f.hash AND fm.grade = ?)"
+		args = append(args, strings.ToLower(grade))
+	case "ungraded":
+		query += " AND NOT EXISTS (SELECT 1 FROM frame_marks fm WHERE fm.hash = f.hash)"
+	default:
+		if include, _ := filters["include_rejected"].(bool); !include {
+			query += " AND " + notRejected
+		}
+	}
+	if analyzed, ok := filters["analyzed"].(bool); ok {
+		if analyzed {
+			query += " AND m.analyzed_at IS NOT NULL"
+		} else {
+			query += " AND m.analyzed_at IS NULL"
+		}
+	}
 
-	rows, err := d.db.Query(query, args...)
-	if err != nil {
-		return nil, err
+	return query, args
+}
+
+// fileSortColumns maps the sort_by values of query_fits_archive to columns
+// and their default direction: quality metrics sort best first.
+var fileSortColumns = map[string]struct{ column, direction string }{
+	"time":         {"f.utc_time", "DESC"},
+	"exposure":     {"f.exposure", "DESC"},
+	"fwhm":         {"m.fwhm", "ASC"},
+	"hfr":          {"m.hfr", "ASC"},
+	"eccentricity": {"m.eccentricity", "ASC"},
+	"star_count":   {"m.star_count", "DESC"},
+	"background":   {"m.background", "ASC"},
+	"noise":        {"m.noise", "ASC"},
+}
+
+// fileOrder returns the ORDER BY clause for the sort_by and sort_order
+// arguments. Frames without a value for the sort column come last.
+func fileOrder(filters map[string]interface{}) string {
+	sortBy, _ := filters["sort_by"].(string)
+	col, ok := fileSortColumns[strings.ToLower(sortBy)]
+	if !ok {
+		col = fileSortColumns["time"]
 	}
-	defer rows.Close()
 
-	files := []*FITSFile{}
-	for rows.Next() {
-		file := &FITSFile{}
-		err := rows.Scan(
-			&file.ID, &file.RelativePath, &file.Hash, &file.FileModTime,
-			&file.RowModTime, &file.Object, &file.RA, &file.Dec, &file.Telescope,
-			&file.FocalLength, &file.Exposure, &file.UTCTime, &file.LocalTime,
-			&file.JulianDate, &file.ObservationDate, &file.Software, &file.Camera, &file.Gain,
-			&file.Offset, &file.Filter, &file.ImageType,
-		)
-		if err != nil {
-			return nil, err
-		}
-		files = append(files, file)
+	direction := col.direction
+	switch order, _ := filters["sort_order"].(string); strings.ToLower(order) {
+	case "asc":
+		direction = "ASC"
+	case "desc":
+		direction = "DESC"
 	}
 
-	return files, rows.Err()
+	if col.column == "f.utc_time" {
+		return col.column + " " + direction
+	}
+	return col.column + " IS NULL, " + col.column + " " + direction + ", f.utc_time DESC"
 }
 
-// GetArchiveSummary returns statistics about the archive
-func (d *Database) GetArchiveSummary() (*tools.ArchiveSummary, error) {
+// GetArchiveSummary returns statistics about the archive.
+// Rejected frames are left out of the totals unless includeRejected is set.
+func (d *Database) GetArchiveSummary(includeRejected bool) (*tools.ArchiveSummary, error) {
 	summary := &tools.ArchiveSummary{}
 
 	// Total files and exposure
-	err := d.db.QueryRow(`
-		SELECT COUNT(*), COALESCE(SUM(exposure), 0)
-		FROM fits_files
-	`).Scan(&summary.TotalFiles, &summary.TotalExposure)
+	totals := "SELECT COUNT(*), COALESCE(SUM(exposure), 0) FROM fits_files f"
+	if !includeRejected {
+		totals += " WHERE " + notRejected
+	}
+	err := d.db.QueryRow(totals).Scan(&summary.TotalFiles, &summary.TotalExposure)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.db.QueryRow(`
+		SELECT COUNT(*)
+		FROM fits_files f
+		JOIN frame_marks g ON g.hash = f.hash
+		WHERE g.grade = 'rejected'
+	`).Scan(&summary.RejectedFiles)
 	if err != nil {
 		return nil, err
 	}
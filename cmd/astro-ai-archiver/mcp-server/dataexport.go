@@ -0,0 +1,437 @@
+package mcpserver
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/rs/zerolog/log"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+// sampleRows is the number of rows read ahead to infer Parquet column types
+// of computed columns, which have no declared type
+const sampleRows = 1000
+
+// exportFormats maps export formats to file extensions
+var exportFormats = map[string]string{
+	"csv":     ".csv",
+	"jsonl":   ".jsonl",
+	"parquet": ".parquet",
+}
+
+// fileExportSelect is the query behind filter-based exports: all fits_files
+// columns plus quality metrics and grade
+const fileExportSelect = `
+	SELECT f.*, m.background, m.noise, m.star_count, m.hfr, m.fwhm, m.eccentricity,
+	       g.grade, g.reason AS grade_reason
+	FROM fits_files f
+	LEFT JOIN frame_metrics m ON m.file_id = f.id
+	LEFT JOIN frame_marks g ON g.hash = f.hash
+	WHERE 1=1`
+
+// ExportData streams the rows of a read-only query, or of the frames matching
+// query_fits_archive filters, to a CSV, JSON Lines or Parquet file in the
+// export directory. Existing files are not overwritten.
+func (d *Database) ExportData(req tools.DataExportRequest) (*tools.DataExportResult, error) {
+	format := strings.ToLower(req.Format)
+	if format == "" {
+		format = "csv"
+	}
+	ext, ok := exportFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported export format %q (use csv, jsonl or parquet)", req.Format)
+	}
+
+	query := req.SQL
+	var args []interface{}
+	if query != "" {
+		if err := validateReadOnlyQuery(query); err != nil {
+			return nil, err
+		}
+	} else {
+		where, filterArgs := buildFileFilters(req.Filters)
+		query = fileExportSelect + where + " ORDER BY " + fileOrder(req.Filters)
+		args = filterArgs
+	}
+
+	name := req.Name
+	if name == "" {
+		name = "export-" + time.Now().Format("20060102-150405")
+	}
+	path := filepath.Join(d.exportRoot(), safeName(strings.TrimSuffix(name, ext), "export")+ext)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("export file already exists: %s", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query execution failed: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get column types: %w", err)
+	}
+
+	// Read ahead so Parquet columns get a type
+	var sample [][]interface{}
+	next := func() ([]interface{}, error) {
+		if !rows.Next() {
+			return nil, rows.Err()
+		}
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		for i, v := range values {
+			values[i] = exportValue(v)
+		}
+		return values, nil
+	}
+	for len(sample) < sampleRows {
+		row, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			break
+		}
+		sample = append(sample, row)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-export-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name()) // No-op after the rename
+	out := bufio.NewWriter(tmp)
+
+	var writer rowWriter
+	switch format {
+	case "csv":
+		writer, err = newCSVRowWriter(out, columns)
+	case "jsonl":
+		writer = &jsonlRowWriter{w: out, columns: columns}
+	case "parquet":
+		writer = newParquetRowWriter(out, columns, columnKinds(columnTypes, sample))
+	}
+	if err != nil {
+		tmp.Close()
+		return nil, err
+	}
+
+	count := 0
+	write := func(row []interface{}) error {
+		count++
+		return writer.WriteRow(row)
+	}
+	for _, row := range sample {
+		if err := write(row); err != nil {
+			tmp.Close()
+			return nil, fmt.Errorf("failed to write row: %w", err)
+		}
+	}
+	for len(sample) == sampleRows {
+		row, err := next()
+		if err != nil {
+			tmp.Close()
+			return nil, err
+		}
+		if row == nil {
+			break
+		}
+		if err := write(row); err != nil {
+			tmp.Close()
+			return nil, fmt.Errorf("failed to write row: %w", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to finish export: %w", err)
+	}
+	if err := out.Flush(); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Str("path", path).Str("format", format).Int("rows", count).Msg("Data exported")
+
+	return &tools.DataExportResult{
+		Path:    path,
+		Format:  format,
+		Rows:    count,
+		Columns: columns,
+		Bytes:   info.Size(),
+	}, nil
+}
+
+// exportValue converts a scanned SQLite value to int64, float64, string or nil.
+func exportValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case []byte:
+		return string(x)
+	case time.Time:
+		return x.UTC().Format(time.RFC3339)
+	case bool:
+		if x {
+			return int64(1)
+		}
+		return int64(0)
+	}
+	return v
+}
+
+// rowWriter writes exported rows in one file format
+type rowWriter interface {
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+// csvRowWriter writes a header line and one line per row
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func newCSVRowWriter(w io.Writer, columns []string) (*csvRowWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return nil, err
+	}
+	return &csvRowWriter{w: cw}, nil
+}
+
+func (c *csvRowWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		switch x := v.(type) {
+		case nil:
+		case string:
+			record[i] = x
+		case int64:
+			record[i] = strconv.FormatInt(x, 10)
+		case float64:
+			record[i] = strconv.FormatFloat(x, 'f', -1, 64)
+		default:
+			record[i] = fmt.Sprint(x)
+		}
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvRowWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonlRowWriter writes one JSON object per line, keys in column order
+type jsonlRowWriter struct {
+	w       io.Writer
+	columns []string
+}
+
+func (j *jsonlRowWriter) WriteRow(values []interface{}) error {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(j.columns[i])
+		val, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(val)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(j.w, b.String())
+	return err
+}
+
+func (j *jsonlRowWriter) Close() error { return nil }
+
+// columnKind is the Parquet type of an exported column
+type columnKind int
+
+const (
+	kindString columnKind = iota
+	kindInt
+	kindFloat
+)
+
+// columnKinds derives column types from the declared SQLite types, falling
+// back to the sampled values for computed columns. Numeric columns without a
+// declared type become doubles, so later rows cannot overflow them.
+func columnKinds(types []*sql.ColumnType, sample [][]interface{}) []columnKind {
+	kinds := make([]columnKind, len(types))
+	for i, t := range types {
+		switch strings.ToUpper(t.DatabaseTypeName()) {
+		case "INTEGER", "INT", "BIGINT":
+			kinds[i] = kindInt
+			continue
+		case "REAL", "FLOAT", "DOUBLE":
+			kinds[i] = kindFloat
+			continue
+		case "TEXT":
+			kinds[i] = kindString
+			continue
+		}
+
+		numeric, seen := true, false
+		for _, row := range sample {
+			switch row[i].(type) {
+			case nil:
+			case int64, float64:
+				seen = true
+			default:
+				numeric = false
+			}
+		}
+		if numeric && seen {
+			kinds[i] = kindFloat
+		}
+	}
+	return kinds
+}
+
+// parquetFieldName replaces characters that cannot appear in a struct tag
+var parquetFieldName = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// parquetRowWriter writes rows through a struct type built at run time, which
+// keeps the columns in query order. All columns are optional.
+type parquetRowWriter struct {
+	w     *parquet.Writer
+	typ   reflect.Type
+	kinds []columnKind
+}
+
+func newParquetRowWriter(w io.Writer, columns []string, kinds []columnKind) *parquetRowWriter {
+	fields := make([]reflect.StructField, len(columns))
+	used := map[string]bool{}
+	for i, col := range columns {
+		name := strings.Trim(parquetFieldName.ReplaceAllString(col, "_"), "_")
+		if name == "" {
+			name = fmt.Sprintf("column%d", i+1)
+		}
+		for base, n := name, 2; used[name]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		used[name] = true
+
+		var typ reflect.Type
+		switch kinds[i] {
+		case kindInt:
+			typ = reflect.TypeOf((*int64)(nil))
+		case kindFloat:
+			typ = reflect.TypeOf((*float64)(nil))
+		default:
+			typ = reflect.TypeOf((*string)(nil))
+		}
+		fields[i] = reflect.StructField{
+			Name: fmt.Sprintf("F%d", i),
+			Type: typ,
+			Tag:  reflect.StructTag(fmt.Sprintf(`parquet:"%s,optional"`, name)),
+		}
+	}
+	typ := reflect.StructOf(fields)
+	schema := parquet.SchemaOf(reflect.New(typ).Interface())
+	return &parquetRowWriter{w: parquet.NewWriter(w, schema), typ: typ, kinds: kinds}
+}
+
+func (p *parquetRowWriter) WriteRow(values []interface{}) error {
+	row := reflect.New(p.typ)
+	elem := row.Elem()
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		switch p.kinds[i] {
+		case kindInt:
+			var n int64
+			switch x := v.(type) {
+			case int64:
+				n = x
+			case float64:
+				n = int64(x)
+			case string:
+				parsed, err := strconv.ParseInt(x, 10, 64)
+				if err != nil {
+					continue // Not a number: leave NULL
+				}
+				n = parsed
+			}
+			elem.Field(i).Set(reflect.ValueOf(&n))
+		case kindFloat:
+			var f float64
+			switch x := v.(type) {
+			case int64:
+				f = float64(x)
+			case float64:
+				f = x
+			case string:
+				parsed, err := strconv.ParseFloat(x, 64)
+				if err != nil {
+					continue
+				}
+				f = parsed
+			}
+			elem.Field(i).Set(reflect.ValueOf(&f))
+		default:
+			var s string
+			switch x := v.(type) {
+			case string:
+				s = x
+			case int64:
+				s = strconv.FormatInt(x, 10)
+			case float64:
+				s = strconv.FormatFloat(x, 'f', -1, 64)
+			default:
+				s = fmt.Sprint(x)
+			}
+			elem.Field(i).Set(reflect.ValueOf(&s))
+		}
+	}
+	return p.w.Write(row.Interface())
+}
+
+func (p *parquetRowWriter) Close() error {
+	return p.w.Close()
+}
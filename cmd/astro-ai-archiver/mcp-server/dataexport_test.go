@@ -0,0 +1,135 @@
+package mcpserver
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+func TestExportData(t *testing.T) {
+	db, dir := newTestDatabase(t)
+
+	frames := []*FITSFile{
+		{RelativePath: "m31/l1.fits", Hash: "a", Object: "M31", Filter: "L", Exposure: 120},
+		{RelativePath: "m31/l2.fits", Hash: "b", Object: "M31", Filter: "L", Exposure: 120},
+		{RelativePath: "m31/r1.fits", Hash: "c", Object: "M31", Filter: "R", Exposure: 60},
+		{RelativePath: "m42/l1.fits", Hash: "d", Object: "M42", Filter: "L", Exposure: 30},
+	}
+	insertFrames(t, db, frames...)
+	id, _ := db.ResolveFileID("m31/l2.fits")
+	if _, err := db.GradeFrames(tools.GradeSelection{FileIDs: []int64{id}}, "rejected", "clouds"); err != nil {
+		t.Fatalf("GradeFrames: %v", err)
+	}
+
+	totals := "SELECT object, filter, COUNT(*) AS frames, SUM(exposure) / 60.0 AS minutes FROM fits_files GROUP BY 1, 2 ORDER BY 1, 2"
+
+	t.Run("csv filters", func(t *testing.T) {
+		result, err := db.ExportData(tools.DataExportRequest{Filters: map[string]interface{}{"target": "M31"}, Name: "m31"})
+		if err != nil {
+			t.Fatalf("ExportData: %v", err)
+		}
+		if result.Path != filepath.Join(dir, ".aaa", "exports", "m31.csv") || result.Format != "csv" || result.Rows != 2 {
+			t.Fatalf("result = %+v", result)
+		}
+
+		f, err := os.Open(result.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		records, err := csv.NewReader(f).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Header plus the two frames that are not rejected
+		if len(records) != 3 || len(records[0]) != len(result.Columns) {
+			t.Fatalf("records = %v", records)
+		}
+
+		if _, err := db.ExportData(tools.DataExportRequest{Name: "m31"}); err == nil {
+			t.Error("exporting over an existing file should fail")
+		}
+	})
+
+	t.Run("jsonl sql", func(t *testing.T) {
+		result, err := db.ExportData(tools.DataExportRequest{SQL: totals, Format: "jsonl", Name: "totals"})
+		if err != nil {
+			t.Fatalf("ExportData: %v", err)
+		}
+		if result.Rows != 3 {
+			t.Fatalf("rows = %d, expected 3", result.Rows)
+		}
+
+		f, err := os.Open(result.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		scanner.Scan()
+		var first map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &first); err != nil {
+			t.Fatal(err)
+		}
+		if first["object"] != "M31" || first["filter"] != "L" || first["frames"] != float64(2) || first["minutes"] != float64(4) {
+			t.Errorf("first row = %v", first)
+		}
+	})
+
+	t.Run("parquet sql", func(t *testing.T) {
+		result, err := db.ExportData(tools.DataExportRequest{SQL: totals, Format: "parquet", Name: "totals"})
+		if err != nil {
+			t.Fatalf("ExportData: %v", err)
+		}
+
+		f, err := os.Open(result.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		reader := parquet.NewReader(f)
+		defer reader.Close()
+
+		fields := reader.Schema().Fields()
+		if len(fields) != 4 || fields[0].Name() != "object" || fields[3].Name() != "minutes" {
+			t.Fatalf("schema = %v", reader.Schema())
+		}
+
+		type total struct {
+			Object  string  `parquet:"object,optional"`
+			Filter  string  `parquet:"filter,optional"`
+			Frames  float64 `parquet:"frames,optional"`
+			Minutes float64 `parquet:"minutes,optional"`
+		}
+		var rows []total
+		for {
+			var row total
+			if err := reader.Read(&row); err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				t.Fatal(err)
+			}
+			rows = append(rows, row)
+		}
+		if len(rows) != 3 || rows[2] != (total{"M42", "L", 1, 0.5}) {
+			t.Errorf("rows = %+v", rows)
+		}
+	})
+
+	if _, err := db.ExportData(tools.DataExportRequest{SQL: "DELETE FROM fits_files"}); err == nil {
+		t.Error("write queries should be refused")
+	}
+	if _, err := db.ExportData(tools.DataExportRequest{Format: "xlsx"}); err == nil {
+		t.Error("unknown formats should be refused")
+	}
+}
@@ -0,0 +1,85 @@
+package mcpserver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// openConfiguredDatabase loads the configuration named by the --config flag
+// and opens its database without migrating it.
+func openConfiguredDatabase(cmd *cobra.Command) (*Config, *Database, error) {
+	configFile, _ := cmd.Flags().GetString("config")
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	InitLogging(cfg.Logging.Level, cfg.Logging.Format)
+
+	expandedDirs := expandDirectories(cfg.Scan.Directory)
+	if len(expandedDirs) == 0 && cfg.Database.Path == "" {
+		return nil, nil, fmt.Errorf("no valid scan directories found after wildcard expansion")
+	}
+
+	db, err := OpenDatabase(cfg.Database.Path, expandedDirs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, db, nil
+}
+
+// RunDBMigrate applies all pending schema migrations (db migrate command).
+func RunDBMigrate(cmd *cobra.Command, args []string) error {
+	_, db, err := openConfiguredDatabase(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	results, err := db.Migrate(context.Background())
+	if err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	if len(results) == 0 {
+		fmt.Println("Database schema is up to date")
+		return nil
+	}
+	fmt.Printf("Applied %d migration(s)\n", len(results))
+	return nil
+}
+
+// RunDBStatus prints the applied and pending schema migrations (db status
+// command).
+func RunDBStatus(cmd *cobra.Command, args []string) error {
+	_, db, err := openConfiguredDatabase(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	statuses, err := db.MigrationStatus(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to read migration status: %w", err)
+	}
+
+	var current int64
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "VERSION\tMIGRATION\tSTATE\tAPPLIED AT\n")
+	for _, st := range statuses {
+		applied := ""
+		if !st.AppliedAt.IsZero() {
+			applied = st.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			current = st.Source.Version
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", st.Source.Version, migrationName(st.Source), st.State, applied)
+	}
+	w.Flush()
+
+	fmt.Printf("\nDatabase: %s\nSchema version: %d\n", db.GetFilePath(), current)
+	return nil
+}
@@ -0,0 +1,176 @@
+package mcpserver
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/astro"
+)
+
+// SetSite sets the observing site used for frames without SITELAT/SITELONG
+// headers. Either value nil leaves such frames without ephemeris.
+func (db *Database) SetSite(latitude, longitude *float64) {
+	db.siteLat, db.siteLong = latitude, longitude
+}
+
+// configuredSite identifies the site set with SetSite, or is empty when there
+// is none
+func (d *Database) configuredSite() string {
+	if d.siteLat == nil || d.siteLong == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.4f,%.4f", *d.siteLat, *d.siteLong)
+}
+
+// frameEphemeris computes the Moon and target geometry at mid-exposure of a
+// frame, or returns nil when its time, position or site is unknown. The
+// position is the plate-solved centre when available, as in the sky index.
+// The site is the configured one it used, or empty for a frame with its own.
+func (d *Database) frameEphemeris(file *FITSFile) (*FrameEphemeris, string) {
+	if !file.UTCTime.Valid {
+		return nil, ""
+	}
+	start, ok := parseDateTimeString(file.UTCTime.String)
+	if !ok {
+		return nil, ""
+	}
+
+	var ra, dec float64
+	switch {
+	case file.WCS != nil:
+		ra, dec = file.WCS.CenterRA, file.WCS.CenterDec
+	case file.RA != nil && file.Dec != nil:
+		ra, dec = *file.RA, *file.Dec
+	default:
+		return nil, ""
+	}
+
+	lat, lon, site := file.SiteLat, file.SiteLong, ""
+	if lat == nil || lon == nil {
+		lat, lon, site = d.siteLat, d.siteLong, d.configuredSite()
+	}
+	if lat == nil || lon == nil {
+		return nil, ""
+	}
+
+	mid := start.Add(time.Duration(file.Exposure / 2 * float64(time.Second)))
+	return computeEphemeris(ra, dec, *lat, *lon, astro.JulianDay(mid)), site
+}
+
+// computeEphemeris returns the geometry of a target at (ra, dec) for an
+// observer at (lat, lon) at Julian day jd
+func computeEphemeris(ra, dec, lat, lon, jd float64) *FrameEphemeris {
+	moonRA, moonDec, _ := astro.MoonPosition(jd)
+	e := &FrameEphemeris{
+		MoonIllumination: roundHundredths(astro.MoonIllumination(jd)),
+		MoonAlt:          roundHundredths(astro.MoonAltitude(lat, lon, jd)),
+		MoonSeparation:   roundHundredths(astro.AngularSeparation(ra, dec, moonRA, moonDec)),
+	}
+	alt, az := astro.Horizontal(ra, dec, lat, lon, jd)
+	e.TargetAlt, e.TargetAz = roundHundredths(alt), roundHundredths(az)
+	if airmass, ok := astro.Airmass(alt); ok {
+		airmass = roundHundredths(airmass)
+		e.Airmass = &airmass
+	}
+	return e
+}
+
+// upsertFileEphemeris stores the ephemeris of a file with the configured site
+// it was computed with, or removes a stale one when it can no longer be
+// computed.
+func upsertFileEphemeris(tx *sql.Tx, fileID int64, e *FrameEphemeris, site string) error {
+	if e == nil {
+		_, err := tx.Exec("DELETE FROM frame_ephemeris WHERE file_id = ?", fileID)
+		return err
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO frame_ephemeris (
+			file_id, moon_illumination, moon_alt, moon_separation, target_alt, target_az, airmass, site
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(file_id) DO UPDATE SET
+			moon_illumination = excluded.moon_illumination,
+			moon_alt = excluded.moon_alt,
+			moon_separation = excluded.moon_separation,
+			target_alt = excluded.target_alt,
+			target_az = excluded.target_az,
+			airmass = excluded.airmass,
+			site = excluded.site
+	`, fileID, e.MoonIllumination, e.MoonAlt, e.MoonSeparation, e.TargetAlt, e.TargetAz, e.Airmass, site)
+	return err
+}
+
+// RefreshEphemeris computes the ephemeris of frames indexed before it was
+// available or before a site was configured, and recomputes that of frames
+// without site headers when the configured site changed. New and changed
+// frames get theirs when they are stored. It returns the number of frames
+// updated.
+func (d *Database) RefreshEphemeris() (int, error) {
+	rows, err := d.db.Query(`
+		SELECT f.id, f.utc_time, f.exposure, f.ra, f.dec, f.site_lat, f.site_long, w.center_ra, w.center_dec,
+		       e.file_id IS NOT NULL
+		FROM fits_files f
+		LEFT JOIN fits_wcs w ON w.file_id = f.id
+		LEFT JOIN frame_ephemeris e ON e.file_id = f.id
+		WHERE f.utc_time IS NOT NULL
+		  AND (e.file_id IS NULL OR ((f.site_lat IS NULL OR f.site_long IS NULL) AND e.site != ?))
+	`, d.configuredSite())
+	if err != nil {
+		return 0, fmt.Errorf("failed to list frames without ephemeris: %w", err)
+	}
+
+	type pending struct {
+		id        int64
+		ephemeris *FrameEphemeris
+		site      string
+	}
+	var updates []pending
+	for rows.Next() {
+		file := &FITSFile{}
+		var centerRA, centerDec sql.NullFloat64
+		var stored bool
+		if err := rows.Scan(&file.ID, &file.UTCTime, &file.Exposure, &file.RA, &file.Dec,
+			&file.SiteLat, &file.SiteLong, &centerRA, &centerDec, &stored); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if centerRA.Valid && centerDec.Valid {
+			file.WCS = &FrameWCS{CenterRA: centerRA.Float64, CenterDec: centerDec.Float64}
+		}
+		// A stored ephemeris whose site was removed is deleted
+		if e, site := d.frameEphemeris(file); e != nil || stored {
+			updates = append(updates, pending{file.ID, e, site})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(updates) == 0 {
+		return 0, nil
+	}
+
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, u := range updates {
+		if err := upsertFileEphemeris(tx, u.id, u.ephemeris, u.site); err != nil {
+			return 0, fmt.Errorf("failed to store ephemeris: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	log.Info().Int("frames", len(updates)).Msg("Ephemeris computed")
+	return len(updates), nil
+}
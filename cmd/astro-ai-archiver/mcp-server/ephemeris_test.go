@@ -0,0 +1,95 @@
+package mcpserver
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/astro"
+)
+
+func TestFrameEphemeris(t *testing.T) {
+	db, _ := newTestDatabase(t)
+
+	// Full moon of 2025-09-07; one target next to the Moon, one opposite
+	start := time.Date(2025, 9, 7, 21, 0, 0, 0, time.UTC)
+	moonRA, moonDec, _ := astro.MoonPosition(astro.JulianDay(start.Add(150 * time.Second)))
+	farRA := astro.NormalizeDegrees(moonRA + 180)
+	lat, lon := 52.0, 5.0
+
+	frame := func(path string, ra, dec float64, site bool) *FITSFile {
+		file := &FITSFile{
+			RelativePath: path, Filter: "OIII", Exposure: 300,
+			RA: &ra, Dec: &dec,
+			UTCTime: sql.NullString{String: start.Format(sessionTimeLayout), Valid: true},
+		}
+		if site {
+			file.SiteLat, file.SiteLong = &lat, &lon
+		}
+		return file
+	}
+	insertFrames(t, db,
+		frame("near.fits", moonRA, moonDec, true),
+		frame("far.fits", farRA, -moonDec, false), // No site headers
+	)
+
+	near, err := db.GetFileByPath("near.fits")
+	if err != nil {
+		t.Fatalf("GetFileByPath: %v", err)
+	}
+	e := near.(*FITSFile).Ephemeris
+	// The target sits at the geocentric Moon position; parallax lowers the Moon
+	if e == nil || e.MoonSeparation > 0.01 || e.MoonIllumination < 0.95 ||
+		e.MoonAlt >= e.TargetAlt || e.TargetAlt-e.MoonAlt > 1 || e.Airmass == nil {
+		t.Fatalf("ephemeris = %+v", e)
+	}
+
+	// Frames without a site get their ephemeris once one is configured
+	far, _ := db.GetFileByPath("far.fits")
+	if far.(*FITSFile).Ephemeris != nil {
+		t.Fatal("frame without site should have no ephemeris")
+	}
+	db.SetSite(&lat, &lon)
+	if n, err := db.RefreshEphemeris(); err != nil || n != 1 {
+		t.Fatalf("RefreshEphemeris = %d, %v; expected 1", n, err)
+	}
+	if n, _ := db.RefreshEphemeris(); n != 0 {
+		t.Errorf("second RefreshEphemeris updated %d frames, expected 0", n)
+	}
+
+	// Moving the site recomputes only the frame that uses it; removing it
+	// drops that frame's ephemeris
+	before, _ := db.GetFileByPath("far.fits")
+	south := -33.9
+	db.SetSite(&south, &lon)
+	if n, err := db.RefreshEphemeris(); err != nil || n != 1 {
+		t.Fatalf("RefreshEphemeris after moving the site = %d, %v; expected 1", n, err)
+	}
+	after, _ := db.GetFileByPath("far.fits")
+	if a, b := after.(*FITSFile).Ephemeris, before.(*FITSFile).Ephemeris; a == nil || a.TargetAlt == b.TargetAlt {
+		t.Errorf("ephemeris after moving the site = %+v, was %+v", a, b)
+	}
+	db.SetSite(nil, nil)
+	if n, err := db.RefreshEphemeris(); err != nil || n != 1 {
+		t.Fatalf("RefreshEphemeris after removing the site = %d, %v; expected 1", n, err)
+	}
+	if far, _ := db.GetFileByPath("far.fits"); far.(*FITSFile).Ephemeris != nil {
+		t.Error("frame without site kept its ephemeris after the site was removed")
+	}
+	if near, _ := db.GetFileByPath("near.fits"); near.(*FITSFile).Ephemeris == nil {
+		t.Error("frame with site headers lost its ephemeris")
+	}
+	db.SetSite(&lat, &lon)
+	db.RefreshEphemeris()
+
+	files, err := db.QueryFiles(map[string]interface{}{"filter": "OIII", "moon_separation_max": 40.0}, 10, 0)
+	if err != nil {
+		t.Fatalf("QueryFiles: %v", err)
+	}
+	if list := files.([]*FITSFile); len(list) != 1 || list[0].RelativePath != "near.fits" {
+		t.Errorf("QueryFiles(moon_separation_max) = %d files, expected near.fits", len(list))
+	}
+	if files, _ := db.QueryFiles(map[string]interface{}{"moon_separation_min": 170.0}, 10, 0); len(files.([]*FITSFile)) != 1 {
+		t.Errorf("QueryFiles(moon_separation_min) = %d files, expected 1", len(files.([]*FITSFile)))
+	}
+}
@@ -0,0 +1,206 @@
+package mcpserver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+// unsafeNameChars are replaced in directory and file names derived from
+// targets and filters
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// SetExportDirectory sets where stacking sets are written; "" writes them to
+// an exports directory next to the database.
+func (db *Database) SetExportDirectory(dir string) {
+	db.exportDir = dir
+}
+
+// exportRoot returns the directory that holds the stacking sets.
+func (d *Database) exportRoot() string {
+	if d.exportDir != "" {
+		return d.exportDir
+	}
+	return filepath.Join(filepath.Dir(d.filePath), "exports")
+}
+
+// ExportStackingSet writes the selected lights as a stacking set: one list of
+// absolute paths per filter, optionally a lights/<filter> tree of symlinks
+// for PixInsight WBPP and a Siril script that stacks each filter from that
+// tree. Rejected frames are left out unless the filters include them. The
+// export directory must not exist yet.
+func (d *Database) ExportStackingSet(req tools.ExportRequest) (*tools.ExportResult, error) {
+	if req.SessionID == 0 && !selectsFrames(req.Filters) {
+		return nil, fmt.Errorf("no lights selected: pass a target, session or query filters")
+	}
+
+	where, args := buildFileFilters(req.Filters)
+	if req.SessionID > 0 {
+		where += ` AND EXISTS (SELECT 1 FROM sessions s WHERE s.id = ? AND s.observation_date = f.observation_date
+			AND s.telescope = COALESCE(f.telescope, '') AND s.camera = COALESCE(f.camera, ''))`
+		args = append(args, req.SessionID)
+	}
+
+	rows, err := d.db.Query(`
+		SELECT f.id, f.relative_path, COALESCE(f.object, ''), COALESCE(f.filter, ''), f.exposure
+		FROM fits_files f
+		LEFT JOIN frame_metrics m ON m.file_id = f.id
+		WHERE 1=1`+where+`
+		ORDER BY f.filter, f.utc_time, f.relative_path`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select lights: %w", err)
+	}
+
+	type light struct {
+		id       int64
+		path     string
+		exposure float64
+	}
+	var filters []string
+	byFilter := map[string][]light{}
+	targets := map[string]bool{}
+	for rows.Next() {
+		var l light
+		var object, filter string
+		if err := rows.Scan(&l.id, &l.path, &object, &filter, &l.exposure); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		filter = safeName(filter, "nofilter")
+		if _, ok := byFilter[filter]; !ok {
+			filters = append(filters, filter)
+		}
+		byFilter[filter] = append(byFilter[filter], l)
+		targets[object] = true
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("no lights match the selection")
+	}
+
+	name := req.Name
+	if name == "" {
+		name = "stack"
+		if target, ok := req.Filters["target"].(string); ok && target != "" {
+			name = target
+		} else if len(targets) == 1 {
+			for t := range targets {
+				name = t
+			}
+		}
+		name += "-" + time.Now().Format("20060102-150405")
+	}
+	dir := filepath.Join(d.exportRoot(), safeName(name, "stack"))
+	if _, err := os.Stat(dir); err == nil {
+		return nil, fmt.Errorf("export directory already exists: %s", dir)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "lists"), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	result := &tools.ExportResult{Directory: dir, Filters: []tools.ExportFilter{}, Errors: []string{}}
+	linkTree := req.Symlinks || req.Siril
+	if linkTree {
+		result.LightsDir = filepath.Join(dir, "lights")
+	}
+
+	for _, filter := range filters {
+		lights := byFilter[filter]
+		ef := tools.ExportFilter{
+			Filter: filter,
+			Frames: len(lights),
+			List:   filepath.Join(dir, "lists", filter+".txt"),
+		}
+
+		var list strings.Builder
+		seen := map[string]bool{}
+		for _, l := range lights {
+			abs := d.GetAbsolutePath(l.path)
+			list.WriteString(abs + "\n")
+			ef.Integration += l.exposure
+
+			if !linkTree {
+				continue
+			}
+			// Frames of different nights often share a file name
+			base := filepath.Base(abs)
+			if seen[base] {
+				base = fmt.Sprintf("%d_%s", l.id, base)
+			}
+			seen[base] = true
+			link := filepath.Join(result.LightsDir, filter, base)
+			if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+				return nil, fmt.Errorf("failed to create lights directory: %w", err)
+			}
+			if err := os.Symlink(abs, link); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", l.path, err))
+			}
+		}
+		ef.Integration = roundHours(ef.Integration / 3600)
+
+		if err := os.WriteFile(ef.List, []byte(list.String()), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write file list: %w", err)
+		}
+		result.Filters = append(result.Filters, ef)
+		result.Frames += len(lights)
+	}
+
+	if req.Siril {
+		result.SirilScript = filepath.Join(dir, "stack.ssf")
+		if err := os.WriteFile(result.SirilScript, []byte(sirilScript(result)), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write Siril script: %w", err)
+		}
+	}
+
+	log.Info().
+		Str("directory", dir).
+		Int("frames", result.Frames).
+		Int("filters", len(result.Filters)).
+		Int("errors", len(result.Errors)).
+		Msg("Stacking set exported")
+
+	return result, nil
+}
+
+// sirilScript returns a Siril script that registers and stacks the lights of
+// each filter from the symlink tree. Paths are relative to the export
+// directory, which Siril uses as its working directory.
+func sirilScript(result *tools.ExportResult) string {
+	var b strings.Builder
+	b.WriteString("############################################\n")
+	b.WriteString("# Stacking script written by astro-ai-archiver\n")
+	b.WriteString("# Run from this directory: siril-cli -s stack.ssf\n")
+	b.WriteString("# Lights are not calibrated; see match_calibration\n")
+	b.WriteString("############################################\n")
+	b.WriteString("requires 1.2.0\n")
+
+	for _, f := range result.Filters {
+		fmt.Fprintf(&b, "\n# %s: %d frames, %.2f h\n", f.Filter, f.Frames, f.Integration)
+		fmt.Fprintf(&b, "cd lights/%s\n", f.Filter)
+		fmt.Fprintf(&b, "convert %s -out=../../process\n", f.Filter)
+		b.WriteString("cd ../../process\n")
+		fmt.Fprintf(&b, "register %s\n", f.Filter)
+		fmt.Fprintf(&b, "stack r_%s rej 3 3 -norm=addscale -output_norm -out=../%s_stacked\n", f.Filter, f.Filter)
+		b.WriteString("cd ..\n")
+	}
+	return b.String()
+}
+
+// safeName makes s usable as a file or directory name, or returns def when
+// nothing usable is left.
+func safeName(s, def string) string {
+	s = strings.Trim(unsafeNameChars.ReplaceAllString(strings.TrimSpace(s), "_"), "_.")
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,98 @@
+package mcpserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+// RunExport writes a stacking set for the lights selected by the command
+// flags (export stack command).
+func RunExport(cmd *cobra.Command, args []string) error {
+	cfg, db, err := openConfiguredDatabase(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Migrate(context.Background()); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+	db.SetExportDirectory(cfg.Export.Directory)
+
+	request := tools.ExportRequest{Filters: filterFlags(cmd)}
+	request.SessionID, _ = cmd.Flags().GetInt64("session")
+	request.Name, _ = cmd.Flags().GetString("name")
+	noSymlinks, _ := cmd.Flags().GetBool("no-symlinks")
+	noSiril, _ := cmd.Flags().GetBool("no-siril")
+	request.Symlinks = !noSymlinks
+	request.Siril = !noSiril
+
+	result, err := db.ExportStackingSet(request)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d frames to %s\n", result.Frames, result.Directory)
+	for _, f := range result.Filters {
+		fmt.Printf("  %-10s %5d frames %8.2f h  %s\n", f.Filter, f.Frames, f.Integration, f.List)
+	}
+	if result.SirilScript != "" {
+		fmt.Printf("Siril script: %s\n", result.SirilScript)
+	}
+	for _, e := range result.Errors {
+		fmt.Printf("Error: %s\n", e)
+	}
+	return nil
+}
+
+// RunExportQuery writes the result of a query, or the frames selected by the
+// command flags, to a CSV, JSON Lines or Parquet file (export query command).
+func RunExportQuery(cmd *cobra.Command, args []string) error {
+	cfg, db, err := openConfiguredDatabase(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Migrate(context.Background()); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+	db.SetExportDirectory(cfg.Export.Directory)
+
+	request := tools.DataExportRequest{Filters: filterFlags(cmd)}
+	request.SQL, _ = cmd.Flags().GetString("sql")
+	request.Format, _ = cmd.Flags().GetString("format")
+	request.Name, _ = cmd.Flags().GetString("name")
+
+	result, err := db.ExportData(request)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d rows (%d columns) to %s\n", result.Rows, len(result.Columns), result.Path)
+	return nil
+}
+
+// filterFlags collects the query filters given on the command line.
+func filterFlags(cmd *cobra.Command) map[string]interface{} {
+	filters := map[string]interface{}{}
+	for _, flag := range []string{"target", "filter", "telescope", "camera", "date_from", "date_to"} {
+		if v, _ := cmd.Flags().GetString(flagName(flag)); v != "" {
+			filters[flag] = v
+		}
+	}
+	if fwhm, _ := cmd.Flags().GetFloat64("fwhm-max"); fwhm > 0 {
+		filters["fwhm_max"] = fwhm
+	}
+	return filters
+}
+
+// flagName returns the command-line spelling of a filter argument.
+func flagName(filter string) string {
+	return strings.ReplaceAll(filter, "_", "-")
+}
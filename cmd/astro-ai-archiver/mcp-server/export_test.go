@@ -0,0 +1,124 @@
+package mcpserver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/astrogo/fitsio"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+func TestExportStackingSet(t *testing.T) {
+	db, dir := newTestDatabase(t)
+
+	frames := []*FITSFile{
+		{RelativePath: "n1/light_001.fits", Hash: "a", Object: "NGC7000", Filter: "Ha", Exposure: 1800},
+		{RelativePath: "n2/light_001.fits", Hash: "b", Object: "NGC7000", Filter: "Ha", Exposure: 1800},
+		{RelativePath: "n2/light_002.fits", Hash: "c", Object: "NGC7000", Filter: "O III", Exposure: 600},
+		{RelativePath: "n2/light_003.fits", Hash: "d", Object: "NGC7000", Filter: "O III", Exposure: 600},
+	}
+	insertFrames(t, db, frames...)
+	id, _ := db.ResolveFileID("n2/light_003.fits")
+	if _, err := db.GradeFrames(tools.GradeSelection{FileIDs: []int64{id}}, "rejected", ""); err != nil {
+		t.Fatalf("GradeFrames: %v", err)
+	}
+
+	request := tools.ExportRequest{
+		Filters: map[string]interface{}{"target": "NGC7000"}, Name: "ngc7000", Symlinks: true, Siril: true,
+	}
+	result, err := db.ExportStackingSet(request)
+	if err != nil {
+		t.Fatalf("ExportStackingSet: %v", err)
+	}
+
+	exportDir := filepath.Join(dir, ".aaa", "exports", "ngc7000")
+	if result.Directory != exportDir || result.Frames != 3 || len(result.Filters) != 2 || len(result.Errors) != 0 {
+		t.Fatalf("result = %+v", result)
+	}
+
+	list, err := os.ReadFile(filepath.Join(exportDir, "lists", "Ha.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := filepath.Join(dir, "n1", "light_001.fits") + "\n" + filepath.Join(dir, "n2", "light_001.fits") + "\n"
+	if string(list) != expected {
+		t.Errorf("Ha list = %q, expected %q", list, expected)
+	}
+
+	// Same file names from different nights get distinct links
+	links, _ := os.ReadDir(filepath.Join(exportDir, "lights", "Ha"))
+	if len(links) != 2 {
+		t.Errorf("Ha links = %d, expected 2", len(links))
+	}
+	// The rejected OIII frame is left out, the filter name is made safe
+	if links, _ := os.ReadDir(filepath.Join(exportDir, "lights", "O_III")); len(links) != 1 {
+		t.Errorf("O_III links = %d, expected 1", len(links))
+	}
+
+	script, err := os.ReadFile(result.SirilScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"cd lights/O_III", "register Ha", "stack r_Ha rej 3 3"} {
+		if !strings.Contains(string(script), line) {
+			t.Errorf("Siril script lacks %q", line)
+		}
+	}
+
+	if _, err := db.ExportStackingSet(request); err == nil {
+		t.Error("exporting into an existing directory should fail")
+	}
+}
+
+func TestRescanSkipsExports(t *testing.T) {
+	db, dir := newTestDatabase(t)
+	src := writeTestFITS(t, 8, 8, make([]uint16, 64),
+		fitsio.Card{Name: "IMAGETYP", Value: "LIGHT"},
+		fitsio.Card{Name: "OBJECT", Value: "NGC7000"},
+		fitsio.Card{Name: "TELESCOP", Value: "RedCat 51"},
+		fitsio.Card{Name: "FILTER", Value: "Ha"},
+		fitsio.Card{Name: "EXPTIME", Value: 300.0},
+	)
+	if err := os.Rename(src, filepath.Join(dir, "light.fits")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewScanner(db, []string{dir}, true, false, 1, nil).Scan(); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	result, err := db.ExportStackingSet(tools.ExportRequest{
+		Filters: map[string]interface{}{"target": "NGC7000"}, Name: "ngc7000", Symlinks: true, Siril: true,
+	})
+	if err != nil {
+		t.Fatalf("ExportStackingSet: %v", err)
+	}
+	// What Siril leaves behind after running the script
+	if err := os.MkdirAll(filepath.Join(result.Directory, "process"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	stacked, _ := os.ReadFile(filepath.Join(dir, "light.fits"))
+	for _, name := range []string{"process/pp_light_00001.fit", "Ha_stacked.fit"} {
+		if err := os.WriteFile(filepath.Join(result.Directory, name), stacked, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scanner := NewScanner(db, []string{dir}, true, false, 1, nil)
+	if scanner.CountFiles() != 1 {
+		t.Errorf("CountFiles = %d, expected 1", scanner.CountFiles())
+	}
+	rescan, err := scanner.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	summary, err := db.GetArchiveSummary(false)
+	if err != nil {
+		t.Fatalf("GetArchiveSummary: %v", err)
+	}
+	if rescan.FilesAdded != 0 || summary.TotalFiles != 1 || summary.TotalExposure != 300 {
+		t.Errorf("rescan added %d; archive has %d frames, %g s", rescan.FilesAdded, summary.TotalFiles, summary.TotalExposure)
+	}
+}
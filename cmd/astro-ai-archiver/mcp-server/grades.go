@@ -0,0 +1,132 @@
+package mcpserver
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+// Frame grades as stored in frame_marks; "clear" removes the mark
+const (
+	gradeAccepted = "accepted"
+	gradeRejected = "rejected"
+	gradeClear    = "clear"
+)
+
+// GradeFrames marks the selected frames as accepted or rejected, or clears
+// their marks. Marks are keyed by file hash so they survive rescans; frames
+// without a hash cannot be graded and are counted as skipped. Selecting by
+// filters matches rejected frames too, so earlier grades can be revised.
+func (d *Database) GradeFrames(selection tools.GradeSelection, grade, reason string) (*tools.GradeResult, error) {
+	grade = strings.ToLower(strings.TrimSpace(grade))
+	switch grade {
+	case gradeAccepted, gradeRejected, gradeClear:
+	default:
+		return nil, fmt.Errorf("invalid grade %q (use accepted, rejected or clear)", grade)
+	}
+
+	query := "SELECT f.hash FROM fits_files f LEFT JOIN frame_metrics m ON m.file_id = f.id WHERE 1=1"
+	var args []interface{}
+	if len(selection.FileIDs) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(selection.FileIDs)), ",")
+		query += " AND f.id IN (" + placeholders + ")"
+		for _, id := range selection.FileIDs {
+			args = append(args, id)
+		}
+	}
+
+	filters := make(map[string]interface{}, len(selection.Filters)+1)
+	for k, v := range selection.Filters {
+		filters[k] = v
+	}
+	if _, ok := filters["include_rejected"]; !ok {
+		filters["include_rejected"] = true
+	}
+	where, filterArgs := buildFileFilters(filters)
+	if where == "" && len(selection.FileIDs) == 0 {
+		return nil, fmt.Errorf("no frames selected: pass file ids or at least one filter")
+	}
+	query += where
+	args = append(args, filterArgs...)
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select frames: %w", err)
+	}
+	result := &tools.GradeResult{Grade: grade}
+	var hashes []string
+	for rows.Next() {
+		var hash sql.NullString
+		if err := rows.Scan(&hash); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		result.Matched++
+		if hash.String == "" {
+			result.NoHash++
+			continue
+		}
+		hashes = append(hashes, hash.String)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `
+		INSERT INTO frame_marks (hash, grade, reason, marked_at)
+		VALUES (?, ?, NULLIF(?, ''), strftime('%s', 'now'))
+		ON CONFLICT(hash) DO UPDATE SET
+			grade = excluded.grade,
+			reason = excluded.reason,
+			marked_at = excluded.marked_at
+	`
+	if grade == gradeClear {
+		stmt = "DELETE FROM frame_marks WHERE hash = ?"
+	}
+	prepared, err := tx.Prepare(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer prepared.Close()
+
+	for _, hash := range hashes {
+		var res sql.Result
+		if grade == gradeClear {
+			res, err = prepared.Exec(hash)
+		} else {
+			res, err = prepared.Exec(hash, grade, reason)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to grade frame: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			result.Graded++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("grade", grade).
+		Int("matched", result.Matched).
+		Int("graded", result.Graded).
+		Int("no_hash", result.NoHash).
+		Msg("Frames graded")
+
+	return result, nil
+}
@@ -0,0 +1,77 @@
+package mcpserver
+
+import (
+	"testing"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+func TestGradeFramesSurviveRescan(t *testing.T) {
+	db, _ := newTestDatabase(t)
+
+	frames := []*FITSFile{
+		{RelativePath: "a.fits", Hash: "aaa", Object: "M31", Filter: "L", Exposure: 300},
+		{RelativePath: "b.fits", Hash: "bbb", Object: "M31", Filter: "L", Exposure: 300},
+		{RelativePath: "c.fits", Hash: "ccc", Object: "M31", Filter: "Ha", Exposure: 600},
+	}
+	insertFrames(t, db, frames...)
+
+	if _, err := db.GradeFrames(tools.GradeSelection{}, "rejected", ""); err == nil {
+		t.Error("grading without a selection should fail")
+	}
+
+	id, _ := db.ResolveFileID("b.fits")
+	result, err := db.GradeFrames(tools.GradeSelection{FileIDs: []int64{id}}, "rejected", "clouds")
+	if err != nil {
+		t.Fatalf("GradeFrames: %v", err)
+	}
+	if result.Matched != 1 || result.Graded != 1 {
+		t.Errorf("result = %+v, expected 1 matched and graded", result)
+	}
+
+	summary, err := db.GetArchiveSummary(false)
+	if err != nil {
+		t.Fatalf("GetArchiveSummary: %v", err)
+	}
+	if summary.TotalFiles != 2 || summary.TotalExposure != 900 || summary.RejectedFiles != 1 {
+		t.Errorf("summary = %d files, %.0f s, %d rejected; expected 2, 900, 1",
+			summary.TotalFiles, summary.TotalExposure, summary.RejectedFiles)
+	}
+	if summary, _ := db.GetArchiveSummary(true); summary.TotalFiles != 3 {
+		t.Errorf("summary with rejected = %d files, expected 3", summary.TotalFiles)
+	}
+
+	// A rescan replaces the row; the grade follows the hash
+	if err := db.DeleteFile("b.fits"); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	frames[1].ID = 0
+	frames[1].RelativePath = "moved/b.fits"
+	insertFrames(t, db, frames[1])
+
+	rejected, err := db.QueryFiles(map[string]interface{}{"grade": "rejected"}, 10, 0)
+	if err != nil {
+		t.Fatalf("QueryFiles: %v", err)
+	}
+	if files := rejected.([]*FITSFile); len(files) != 1 || files[0].RelativePath != "moved/b.fits" ||
+		files[0].Grade != "rejected" || files[0].GradeReason != "clouds" {
+		t.Errorf("rejected frames after rescan = %+v", files)
+	}
+
+	usable, _ := db.QueryFiles(map[string]interface{}{"filter": "L"}, 10, 0)
+	if files := usable.([]*FITSFile); len(files) != 1 || files[0].RelativePath != "a.fits" {
+		t.Errorf("default query returned %d L frames, expected only a.fits", len(files))
+	}
+
+	// Filter selection includes rejected frames, so grades can be revised
+	result, err = db.GradeFrames(tools.GradeSelection{Filters: map[string]interface{}{"filter": "L"}}, "clear", "")
+	if err != nil {
+		t.Fatalf("GradeFrames clear: %v", err)
+	}
+	if result.Matched != 2 || result.Graded != 1 {
+		t.Errorf("clear result = %+v, expected 2 matched, 1 cleared", result)
+	}
+	if summary, _ := db.GetArchiveSummary(false); summary.TotalFiles != 3 || summary.RejectedFiles != 0 {
+		t.Errorf("summary after clear = %d files, %d rejected", summary.TotalFiles, summary.RejectedFiles)
+	}
+}
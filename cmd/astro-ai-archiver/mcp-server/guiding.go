@@ -0,0 +1,403 @@
+package mcpserver
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+	_ "time/tzdata" // Time zone names on Windows, which has no zoneinfo database
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+// unixTimeSQL converts fits_files.utc_time to Unix seconds in SQL
+const unixTimeSQL = "(julianday(f.utc_time) - 2440587.5) * 86400.0"
+
+// SetGuidingOptions configures PHD2 guide log import: extra directories
+// searched for PHD2_GuideLog_*.txt files after every scan, and the time zone
+// the logs were written in (IANA name, "" = local time of this computer).
+func (db *Database) SetGuidingOptions(logDirs []string, timezone string) error {
+	db.guideLogDirs = logDirs
+	db.guideLocation = time.Local
+	if timezone == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return fmt.Errorf("invalid guiding time zone %q: %w", timezone, err)
+	}
+	db.guideLocation = loc
+	return nil
+}
+
+// guideLogFiles lists the PHD2 guide logs in the configured log directories.
+func (d *Database) guideLogFiles() []string {
+	var files []string
+	for _, dir := range d.guideLogDirs {
+		matches, err := filepath.Glob(filepath.Join(dir, "PHD2_GuideLog_*.txt"))
+		if err != nil {
+			log.Warn().Err(err).Str("directory", dir).Msg("Invalid guide log directory")
+			continue
+		}
+		files = append(files, matches...)
+	}
+	return files
+}
+
+// ImportGuideLogs imports new and changed PHD2 guide logs, given by path, and
+// those in the configured log directories, then computes the guiding RMS of
+// light frames exposed while guiding. Unchanged logs are skipped.
+func (d *Database) ImportGuideLogs(paths []string) (*tools.GuideImportResult, error) {
+	result := &tools.GuideImportResult{}
+
+	seen := map[string]bool{}
+	for _, p := range append(paths, d.guideLogFiles()...) {
+		abs, err := filepath.Abs(p)
+		if err != nil || seen[abs] {
+			continue
+		}
+		seen[abs] = true
+
+		sessions, samples, err := d.importGuideLog(abs)
+		switch {
+		case err != nil:
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", abs, err))
+			log.Warn().Err(err).Str("file", abs).Msg("Guide log import failed")
+		case sessions < 0:
+			result.Skipped++
+		default:
+			result.Logs++
+			result.Sessions += sessions
+			result.Samples += samples
+		}
+	}
+
+	frames, err := d.correlateGuiding()
+	if err != nil {
+		return nil, err
+	}
+	result.Frames = frames
+
+	if result.Logs > 0 || frames > 0 {
+		log.Info().
+			Int("logs", result.Logs).
+			Int("sessions", result.Sessions).
+			Int("samples", result.Samples).
+			Int("frames", result.Frames).
+			Msg("Guide logs imported")
+	}
+	return result, nil
+}
+
+// importGuideLog replaces the stored contents of one guide log. It returns
+// -1 sessions when the log is unchanged since the last import.
+func (d *Database) importGuideLog(path string) (int, int, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var modTime int64
+	err = d.db.QueryRow("SELECT file_mod_time FROM guide_logs WHERE path = ?", path).Scan(&modTime)
+	if err == nil && modTime == info.ModTime().Unix() {
+		return -1, 0, nil
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return 0, 0, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	loc := d.guideLocation
+	if loc == nil {
+		loc = time.Local
+	}
+	sessions, err := parsePHD2Log(f, loc)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read guide log: %w", err)
+	}
+
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	// Sessions, samples, events and frame RMS of an earlier import cascade
+	if _, err := tx.Exec("DELETE FROM guide_logs WHERE path = ?", path); err != nil {
+		return 0, 0, err
+	}
+	res, err := tx.Exec("INSERT INTO guide_logs (path, file_mod_time) VALUES (?, ?)", path, info.ModTime().Unix())
+	if err != nil {
+		return 0, 0, err
+	}
+	logID, err := res.LastInsertId()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	sampleStmt, err := tx.Prepare("INSERT INTO guide_samples (session_id, time, ra, dec) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer sampleStmt.Close()
+	eventStmt, err := tx.Prepare("INSERT INTO guide_events (session_id, time, kind, detail) VALUES (?, ?, ?, NULLIF(?, ''))")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer eventStmt.Close()
+
+	samples := 0
+	for _, s := range sessions {
+		var rmsRA, rmsDec, rmsTotal interface{}
+		if ra, dec, total, ok := s.rms(); ok {
+			rmsRA, rmsDec, rmsTotal = ra, dec, total
+		}
+		dithers := 0
+		for _, e := range s.events {
+			if e.kind == "dither" {
+				dithers++
+			}
+		}
+
+		res, err := tx.Exec(`
+			INSERT INTO guide_sessions (
+				log_id, night, mount, pixel_scale, start_time, end_time, samples, rms_ra, rms_dec, rms_total, dithers
+			) VALUES (?, ?, NULLIF(?, ''), NULLIF(?, 0), ?, ?, ?, ?, ?, ?, ?)
+		`, logID, s.night(), s.mount, s.pixelScale,
+			s.start.UTC().Format(sessionTimeLayout), s.end.UTC().Format(sessionTimeLayout),
+			len(s.samples), rmsRA, rmsDec, rmsTotal, dithers)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to store guide session: %w", err)
+		}
+		sessionID, err := res.LastInsertId()
+		if err != nil {
+			return 0, 0, err
+		}
+
+		for _, p := range s.samples {
+			if _, err := sampleStmt.Exec(sessionID, p.time, p.ra, p.dec); err != nil {
+				return 0, 0, fmt.Errorf("failed to store guide sample: %w", err)
+			}
+		}
+		for _, e := range s.events {
+			if _, err := eventStmt.Exec(sessionID, e.time, e.kind, e.detail); err != nil {
+				return 0, 0, fmt.Errorf("failed to store guide event: %w", err)
+			}
+		}
+		samples += len(s.samples)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return len(sessions), samples, nil
+}
+
+// correlateGuiding computes the guiding RMS during the exposure of every
+// light frame that started within a guide session and has no guiding record
+// yet. Frames without samples in their exposure window get a record with
+// zero samples, so they are not looked at again. InsertOrUpdateFile drops
+// the record of a frame whose start time or exposure changed.
+func (d *Database) correlateGuiding() (int, error) {
+	rows, err := d.db.Query(`
+		SELECT id, start, exposure, session_id FROM (
+			SELECT f.id, ` + unixTimeSQL + ` AS start, f.exposure,
+			       (SELECT s.id FROM guide_sessions s
+			        WHERE s.start_time <= f.utc_time AND s.end_time >= f.utc_time
+			        ORDER BY s.start_time DESC LIMIT 1) AS session_id
+			FROM fits_files f
+			WHERE f.utc_time IS NOT NULL
+			  AND NOT EXISTS (SELECT 1 FROM frame_guiding fg WHERE fg.file_id = f.id)
+		)
+		WHERE session_id IS NOT NULL
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select frames for guiding: %w", err)
+	}
+
+	type frame struct {
+		id        int64
+		start     float64
+		exposure  float64
+		sessionID int64
+	}
+	var frames []frame
+	for rows.Next() {
+		var f frame
+		if err := rows.Scan(&f.id, &f.start, &f.exposure, &f.sessionID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		frames = append(frames, f)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+	if len(frames) == 0 {
+		return 0, nil
+	}
+
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, f := range frames {
+		end := f.start + f.exposure
+		sessionID, n := f.sessionID, 0
+		var sumRA, sumRA2, sumDec, sumDec2 float64
+		var scale sql.NullFloat64
+		err := tx.QueryRow(`
+			SELECT g.session_id, COUNT(*), SUM(g.ra), SUM(g.ra * g.ra), SUM(g.dec), SUM(g.dec * g.dec), s.pixel_scale
+			FROM guide_samples g
+			JOIN guide_sessions s ON s.id = g.session_id
+			WHERE g.time BETWEEN ? AND ?
+			GROUP BY g.session_id
+			ORDER BY COUNT(*) DESC
+			LIMIT 1
+		`, f.start, end).Scan(&sessionID, &n, &sumRA, &sumRA2, &sumDec, &sumDec2, &scale)
+		if err != nil && err != sql.ErrNoRows {
+			return 0, err
+		}
+
+		var rmsRA, rmsDec, rmsTotal interface{}
+		if n > 0 && scale.Valid {
+			ra := stdDev(sumRA, sumRA2, float64(n)) * scale.Float64
+			dec := stdDev(sumDec, sumDec2, float64(n)) * scale.Float64
+			rmsRA, rmsDec, rmsTotal = ra, dec, math.Hypot(ra, dec)
+		}
+
+		var dithers int
+		err = tx.QueryRow("SELECT COUNT(*) FROM guide_events WHERE kind = 'dither' AND time BETWEEN ? AND ?", f.start, end).Scan(&dithers)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.Exec(`
+			INSERT OR REPLACE INTO frame_guiding (file_id, guide_session_id, samples, rms_ra, rms_dec, rms_total, dithers)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, f.id, sessionID, n, rmsRA, rmsDec, rmsTotal, dithers)
+		if err != nil {
+			return 0, fmt.Errorf("failed to store frame guiding: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(frames), nil
+}
+
+// GuidingReport summarises guiding performance per mount and night, or per
+// mount only. The RMS of a group combines its sessions weighted by their
+// sample counts; frame RMS values only cover frames with guide samples.
+func (d *Database) GuidingReport(req tools.GuidingReportRequest) ([]tools.GuidingSummary, error) {
+	night := "s.night AS night"
+	if req.GroupBy == "mount" {
+		night = "'' AS night"
+	} else if req.GroupBy != "" && req.GroupBy != "night" {
+		return nil, fmt.Errorf("invalid group_by %q (use night or mount)", req.GroupBy)
+	}
+
+	where := " WHERE 1=1"
+	var args []interface{}
+	if req.Mount != "" {
+		where += " AND s.mount LIKE ?"
+		args = append(args, "%"+req.Mount+"%")
+	}
+	if req.DateFrom != "" {
+		where += " AND s.night >= ?"
+		args = append(args, req.DateFrom)
+	}
+	if req.DateTo != "" {
+		where += " AND s.night <= ?"
+		args = append(args, req.DateTo)
+	}
+
+	rows, err := d.db.Query(`
+		SELECT COALESCE(s.mount, ''), `+night+`, COUNT(*),
+		       SUM((julianday(s.end_time) - julianday(s.start_time)) * 24),
+		       SUM(s.samples), SUM(s.dithers),
+		       SUM(s.samples * s.rms_ra * s.rms_ra) / SUM(CASE WHEN s.rms_ra IS NOT NULL THEN s.samples END),
+		       SUM(s.samples * s.rms_dec * s.rms_dec) / SUM(CASE WHEN s.rms_dec IS NOT NULL THEN s.samples END)
+		FROM guide_sessions s`+where+`
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarise guiding: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := []tools.GuidingSummary{}
+	index := map[[2]string]int{}
+	for rows.Next() {
+		var g tools.GuidingSummary
+		var varRA, varDec sql.NullFloat64
+		if err := rows.Scan(&g.Mount, &g.Night, &g.Sessions, &g.Hours, &g.Samples, &g.Dithers, &varRA, &varDec); err != nil {
+			return nil, err
+		}
+		g.Hours = roundHours(g.Hours)
+		if varRA.Valid && varDec.Valid {
+			ra, dec := math.Sqrt(varRA.Float64), math.Sqrt(varDec.Float64)
+			g.RMSRA, g.RMSDec, g.RMSTotal = roundArcsec(ra), roundArcsec(dec), roundArcsec(math.Hypot(ra, dec))
+		}
+		index[[2]string{g.Mount, g.Night}] = len(summaries)
+		summaries = append(summaries, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Per-frame RMS of the same groups
+	frameRows, err := d.db.Query(`
+		SELECT COALESCE(s.mount, ''), `+night+`, COUNT(*), AVG(fg.rms_total), MAX(fg.rms_total)
+		FROM frame_guiding fg
+		JOIN guide_sessions s ON s.id = fg.guide_session_id`+where+` AND fg.samples > 0
+		GROUP BY 1, 2
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarise frame guiding: %w", err)
+	}
+	defer frameRows.Close()
+
+	for frameRows.Next() {
+		var mount, night string
+		var frames int
+		var mean, worst sql.NullFloat64
+		if err := frameRows.Scan(&mount, &night, &frames, &mean, &worst); err != nil {
+			return nil, err
+		}
+		if i, ok := index[[2]string{mount, night}]; ok {
+			summaries[i].Frames = frames
+			summaries[i].FrameRMSMean = roundArcsec(mean.Float64)
+			summaries[i].FrameRMSWorst = roundArcsec(worst.Float64)
+		}
+	}
+	if err := frameRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return summaries, nil
+}
+
+// roundArcsec rounds to hundredths of an arcsecond for reporting.
+func roundArcsec(v float64) float64 {
+	return math.Round(v*100) / 100
+}
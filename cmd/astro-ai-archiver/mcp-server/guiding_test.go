@@ -0,0 +1,148 @@
+package mcpserver
+
+import (
+	"database/sql"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+const testGuideLog = `PHD2 version 2.6.13, Log version 2.5. Log enabled at 2025-09-01 21:55:02
+
+Calibration Begins at 2025-09-01 21:56:00
+Direction,Step,dx,dy,x,y,Dist
+West,1,1.2,0.1,100.0,100.0,1.2
+Calibration complete, mount = ZWO AM5.
+
+Guiding Begins at 2025-09-01 22:00:00
+Dither = both axes, Dither scale = 1.000, Image noise reduction = none
+Pixel scale = 2.00 arc-sec/px, Binning = 1, Focal length = 240 mm
+Mount = ZWO AM5, connected, guiding enabled, xAngle = 12.3, xRate = 7.1
+Frame,Time,mount,dx,dy,RARawDistance,DECRawDistance,RAGuideDistance,DECGuideDistance,RADuration,RADirection,DECDuration,DECDirection,XStep,YStep,StarMass,SNR,ErrorCode
+1,2.0,"Mount",0.1,0.1,0.3,0.1,0.3,0.1,50,E,0,,,,12000,30.1,0
+2,4.0,"Mount",0.1,0.1,-0.3,-0.1,-0.3,-0.1,50,W,0,,,,12000,30.1,0
+3,6.0,"DROP",,,,,,,,,,,,,0,0.0,1,"Star lost - low SNR"
+4,8.0,"Mount",0.1,0.1,0.3,0.1,0.3,0.1,50,E,0,,,,12000,30.1,0
+INFO: DITHER by 1.50, -0.80, new lock pos = 101.50, 99.20
+INFO: SETTLING STATE CHANGE, Settling started
+5,10.0,"Mount",0.1,0.1,-0.3,-0.1,-0.3,-0.1,50,W,0,,,,12000,30.1,0
+INFO: SETTLING STATE CHANGE, Settling complete
+Guiding Ends at 2025-09-01 22:00:12
+`
+
+func TestParsePHD2Log(t *testing.T) {
+	sessions, err := parsePHD2Log(strings.NewReader(testGuideLog), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("sessions = %d, expected 1", len(sessions))
+	}
+	s := sessions[0]
+	if s.mount != "ZWO AM5" || s.pixelScale != 2 || len(s.samples) != 4 || len(s.events) != 3 || s.night() != "2025-09-01" {
+		t.Fatalf("session = %q %g, %d samples, %d events, night %s", s.mount, s.pixelScale, len(s.samples), len(s.events), s.night())
+	}
+	if s.events[0].kind != "dither" || s.events[0].time != s.samples[2].time {
+		t.Errorf("dither event = %+v", s.events[0])
+	}
+	ra, dec, total, ok := s.rms()
+	if !ok || math.Abs(ra-0.6) > 1e-9 || math.Abs(dec-0.2) > 1e-9 || math.Abs(total-math.Hypot(0.6, 0.2)) > 1e-9 {
+		t.Errorf("rms = %g %g %g", ra, dec, total)
+	}
+}
+
+func TestImportGuideLogs(t *testing.T) {
+	db, dir := newTestDatabase(t)
+	logPath := filepath.Join(dir, "PHD2_GuideLog_2025-09-01_215502.txt")
+	if err := os.WriteFile(logPath, []byte(testGuideLog), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.SetGuidingOptions(nil, "UTC"); err != nil {
+		t.Fatal(err)
+	}
+
+	// One frame exposed over the first two samples, one outside any session
+	frames := []*FITSFile{
+		{RelativePath: "l1.fits", UTCTime: sql.NullString{String: "2025-09-01T22:00:01", Valid: true}, Exposure: 4},
+		{RelativePath: "l2.fits", UTCTime: sql.NullString{String: "2025-09-01T23:00:00", Valid: true}, Exposure: 4},
+	}
+	for _, f := range frames {
+		f.Object, f.Filter = "NGC7000", "Ha"
+	}
+	insertFrames(t, db, frames...)
+
+	result, err := db.ImportGuideLogs([]string{logPath})
+	if err != nil {
+		t.Fatalf("ImportGuideLogs: %v", err)
+	}
+	if result.Logs != 1 || result.Sessions != 1 || result.Samples != 4 || result.Frames != 1 {
+		t.Fatalf("result = %+v", result)
+	}
+
+	id, _ := db.ResolveFileID("l1.fits")
+	var samples int
+	var rmsRA float64
+	if err := db.db.QueryRow("SELECT samples, rms_ra FROM frame_guiding WHERE file_id = ?", id).Scan(&samples, &rmsRA); err != nil {
+		t.Fatal(err)
+	}
+	if samples != 2 || math.Abs(rmsRA-0.6) > 1e-9 {
+		t.Errorf("frame guiding = %d samples, RA RMS %g", samples, rmsRA)
+	}
+
+	// Unchanged logs are skipped
+	if result, err := db.ImportGuideLogs([]string{logPath}); err != nil || result.Skipped != 1 || result.Logs != 0 {
+		t.Errorf("reimport = %+v, %v", result, err)
+	}
+
+	report, err := db.GuidingReport(tools.GuidingReportRequest{})
+	if err != nil {
+		t.Fatalf("GuidingReport: %v", err)
+	}
+	if len(report) != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	g := report[0]
+	if g.Mount != "ZWO AM5" || g.Night != "2025-09-01" || g.Samples != 4 || g.Dithers != 1 || g.RMSRA != 0.6 || g.Frames != 1 || g.FrameRMSWorst != 0.63 {
+		t.Errorf("summary = %+v", g)
+	}
+
+	if byMount, _ := db.GuidingReport(tools.GuidingReportRequest{GroupBy: "mount"}); len(byMount) != 1 || byMount[0].Night != "" {
+		t.Errorf("per-mount report = %+v", byMount)
+	}
+
+	// Re-indexing a frame keeps its guiding unless its exposure window moved
+	guidedSamples := func() (int, bool) {
+		t.Helper()
+		var n int
+		err := db.db.QueryRow("SELECT samples FROM frame_guiding WHERE file_id = ?", id).Scan(&n)
+		if err == sql.ErrNoRows {
+			return 0, false
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n, true
+	}
+	insertFrames(t, db, frames[0])
+	if n, ok := guidedSamples(); !ok || n != 2 {
+		t.Errorf("guiding of an unchanged frame = %d samples, %v", n, ok)
+	}
+	frames[0].Exposure = 2
+	insertFrames(t, db, frames[0])
+	if _, ok := guidedSamples(); ok {
+		t.Error("guiding of a frame with a new exposure was kept")
+	}
+	result, err = db.ImportGuideLogs([]string{logPath})
+	if err != nil {
+		t.Fatalf("ImportGuideLogs: %v", err)
+	}
+	if n, ok := guidedSamples(); result.Frames != 1 || !ok || n != 1 {
+		t.Errorf("frame with a new exposure correlated to %d samples (%v), result %+v", n, ok, result)
+	}
+}
@@ -0,0 +1,94 @@
+package mcpserver
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+// newTestDatabase opens an empty archive in a temporary directory, laid out
+// as in a real archive with the database in .aaa. It returns the database
+// and the archive directory; the database is closed when the test ends.
+func newTestDatabase(t *testing.T) (*Database, string) {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := NewDatabase(filepath.Join(dir, ".aaa", "archive.db"), []string{dir})
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, dir
+}
+
+// insertFrames indexes frames as if they had been scanned. Frames are lights
+// unless they say otherwise, and a frame without a hash gets its path as hash.
+func insertFrames(t *testing.T, db *Database, frames ...*FITSFile) {
+	t.Helper()
+	for _, f := range frames {
+		if f.ImageType == "" {
+			f.ImageType = "LIGHT"
+		}
+		if f.Hash == "" {
+			f.Hash = f.RelativePath
+		}
+		if err := db.InsertOrUpdateFile(f); err != nil {
+			t.Fatalf("InsertOrUpdateFile %s: %v", f.RelativePath, err)
+		}
+	}
+}
+
+// insertNGC7000Scenario indexes the lights the progress and statistics tests
+// share: NGC7000 in Ha and OIII over three nights and a month boundary, a
+// copy of one Ha frame, a rejected OIII frame and one M31 frame.
+//
+//	2025-08-30  NGC7000  Ha    1800 s
+//	2025-09-01  NGC7000  Ha    1800 s  (and a copy)
+//	2025-09-01  NGC7000  Ha     900 s
+//	2025-09-02  NGC7000  OIII  3600 s
+//	2025-09-02  NGC7000  OIII  3600 s  rejected
+//	2025-09-02  M31      L     3600 s
+//
+// Counted once and without the rejected frame, NGC7000 has 1.25 h of Ha and
+// 1 h of OIII. Every frame has gain 100, set temperature -10 and binning 1.
+func insertNGC7000Scenario(t *testing.T, db *Database) {
+	t.Helper()
+	gain, setTemp, bin := 100.0, -10.0, 1
+	frame := func(path, hash, night, object, filter string, exposure float64) *FITSFile {
+		return &FITSFile{
+			RelativePath: path, Hash: hash, Object: object, Filter: filter, Exposure: exposure,
+			ObservationDate: sql.NullString{String: night, Valid: true},
+			Gain:            &gain, SetTemp: &setTemp, XBinning: &bin,
+		}
+	}
+	insertFrames(t, db,
+		frame("ngc7000/ha1.fits", "ha1", "2025-08-30", "NGC7000", "Ha", 1800),
+		frame("ngc7000/ha2.fits", "ha2", "2025-09-01", "NGC7000", "Ha", 1800),
+		frame("copy/ha2.fits", "ha2", "2025-09-01", "NGC7000", "Ha", 1800),
+		frame("ngc7000/ha3.fits", "ha3", "2025-09-01", "NGC7000", "Ha", 900),
+		frame("ngc7000/oiii1.fits", "oiii1", "2025-09-02", "NGC7000", "OIII", 3600),
+		frame("ngc7000/oiii2.fits", "oiii2", "2025-09-02", "NGC7000", "OIII", 3600),
+		frame("m31/l1.fits", "l1", "2025-09-02", "M31", "L", 3600),
+	)
+
+	id, _ := db.ResolveFileID("ngc7000/oiii2.fits")
+	if _, err := db.GradeFrames(tools.GradeSelection{FileIDs: []int64{id}}, "rejected", ""); err != nil {
+		t.Fatalf("GradeFrames: %v", err)
+	}
+}
+
+// newTestSession connects a client to server in memory. The session is
+// closed when the test ends.
+func newTestSession(t *testing.T, server *mcp.Server, opts *mcp.ClientOptions) *mcp.ClientSession {
+	t.Helper()
+	session, err := connectInMemory(context.Background(), server, "test-client", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session
+}
@@ -0,0 +1,107 @@
+package mcpserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/auth"
+	"github.com/rs/zerolog/log"
+)
+
+// apiKeyHeader is an alternative to "Authorization: Bearer <key>" for
+// clients that cannot set a bearer token
+const apiKeyHeader = "X-API-Key"
+
+// validateAPIKeys checks the configured keys before the server starts, so a
+// typo does not leave the server open or lock everybody out
+func validateAPIKeys(keys []APIKeyConfig) error {
+	names := map[string]bool{}
+	values := map[string]bool{}
+	for i, k := range keys {
+		if k.Name == "" {
+			return fmt.Errorf("api key %d has no name", i+1)
+		}
+		if len(k.Key) < 16 {
+			return fmt.Errorf("api key %q must be at least 16 characters", k.Name)
+		}
+		if names[k.Name] {
+			return fmt.Errorf("api key name %q is used twice", k.Name)
+		}
+		if values[k.Key] {
+			return fmt.Errorf("api key %q has the same key as another one", k.Name)
+		}
+		if err := k.Tools.Policy().Validate(); err != nil {
+			return fmt.Errorf("api key %q: %w", k.Name, err)
+		}
+		names[k.Name], values[k.Key] = true, true
+	}
+	return nil
+}
+
+// apiKeyVerifier accepts the configured keys. The key's name becomes the
+// UserID of the token, which the MCP handler uses to keep sessions apart.
+func apiKeyVerifier(keys []APIKeyConfig) auth.TokenVerifier {
+	// Keys are compared by hash so every comparison takes the same time,
+	// whatever the length of the presented key
+	hashes := make([][32]byte, len(keys))
+	for i, k := range keys {
+		hashes[i] = sha256.Sum256([]byte(k.Key))
+	}
+	return func(ctx context.Context, token string, req *http.Request) (*auth.TokenInfo, error) {
+		presented := sha256.Sum256([]byte(token))
+		name := ""
+		for i, hash := range hashes {
+			if subtle.ConstantTimeCompare(presented[:], hash[:]) == 1 {
+				name = keys[i].Name
+			}
+		}
+		if name == "" {
+			log.Warn().Str("remote", req.RemoteAddr).Str("path", req.URL.Path).Msg("Request with invalid API key rejected")
+			return nil, fmt.Errorf("%w: unknown api key", auth.ErrInvalidToken)
+		}
+		// API keys do not expire, but the SDK requires an expiration
+		return &auth.TokenInfo{UserID: name, Expiration: time.Now().Add(time.Hour)}, nil
+	}
+}
+
+// requireAPIKey rejects requests without a valid API key before they reach
+// the MCP handler, the REST API or the dashboard data. The dashboard page
+// itself is public: it holds no archive data and asks for a key when the
+// API answers 401. Without configured keys every request is let through.
+func requireAPIKey(keys []APIKeyConfig, next http.Handler) http.Handler {
+	if len(keys) == 0 {
+		return next
+	}
+	authenticated := auth.RequireBearerToken(apiKeyVerifier(keys), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Debug().Str("api_key", apiKeyName(r.Context())).Str("method", r.Method).Str("path", r.URL.Path).Msg("Authenticated request")
+		next.ServeHTTP(w, r)
+	}))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isDashboardAsset(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if key := r.Header.Get(apiKeyHeader); key != "" && r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+key)
+		}
+		if !strings.HasPrefix(strings.ToLower(r.Header.Get("Authorization")), "bearer ") {
+			log.Warn().Str("remote", r.RemoteAddr).Str("path", r.URL.Path).Msg("Request without API key rejected")
+		}
+		authenticated.ServeHTTP(w, r)
+	})
+}
+
+// apiKeyName returns the name of the API key a request was made with, or ""
+// when the server runs without keys
+func apiKeyName(ctx context.Context) string {
+	if info := auth.TokenInfoFromContext(ctx); info != nil {
+		return info.UserID
+	}
+	return ""
+}
@@ -0,0 +1,177 @@
+package mcpserver
+
+import (
+	"database/sql"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/astro"
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+// SetAnalysisOptions configures the frame analysis pass: whether it runs after
+// every scan and how many frames are analyzed in parallel (0 = NumCPU).
+func (db *Database) SetAnalysisOptions(onScan bool, workers int) {
+	db.analyzeOnScan = onScan
+	db.analysisWorkers = workers
+}
+
+// analyzeFrame loads a frame and measures its quality. Shape metrics of
+// colour frames are measured on 2x2 superpixels and scaled back to sensor
+// pixels.
+func analyzeFrame(path string) (*FrameMetrics, error) {
+	img, err := loadFrameImage(path)
+	if err != nil {
+		return nil, err
+	}
+
+	lum := img.luminance()
+	q := astro.MeasureFrame(lum.Pixels, lum.Width, lum.Height)
+
+	scale := float64(img.Width) / float64(lum.Width)
+	return &FrameMetrics{
+		Background:   q.Background,
+		Noise:        q.Noise,
+		StarCount:    q.StarCount,
+		HFR:          q.HFR * scale,
+		FWHM:         q.FWHM * scale,
+		Eccentricity: q.Eccentricity,
+	}, nil
+}
+
+// AnalyzeFrames computes quality metrics for the frames matching the filters
+// (the query_fits_archive filters) that have no metrics yet, or whose content
+// changed since they were analyzed. With force all matching frames are
+// analyzed again. At most limit frames are processed (0 = no limit).
+func (d *Database) AnalyzeFrames(filters map[string]interface{}, force bool, limit int) (*tools.AnalysisResult, error) {
+	startTime := time.Now()
+
+	where, args := buildFileFilters(filters)
+	pending := " WHERE 1=1" + where
+	if !force {
+		pending += " AND (m.file_id IS NULL OR m.file_hash IS NOT f.hash)"
+	}
+
+	query := "SELECT f.id, f.relative_path, f.hash FROM fits_files f LEFT JOIN frame_metrics m ON m.file_id = f.id" +
+		pending + " ORDER BY f.utc_time"
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select frames: %w", err)
+	}
+
+	type job struct {
+		id   int64
+		path string
+		hash sql.NullString
+	}
+	var jobs []job
+	for rows.Next() {
+		var j job
+		if err := rows.Scan(&j.id, &j.path, &j.hash); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	workers := d.analysisWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	result := &tools.AnalysisResult{Errors: []string{}}
+	var mu sync.Mutex
+	jobChan := make(chan job)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobChan {
+				metrics, err := analyzeFrame(d.GetAbsolutePath(j.path))
+				if err == nil {
+					err = d.upsertFrameMetrics(j.id, j.hash, metrics)
+				}
+
+				mu.Lock()
+				if err != nil {
+					result.Failed++
+					result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", j.path, err))
+					log.Warn().Err(err).Str("file", j.path).Msg("Frame analysis failed")
+				} else {
+					result.Analyzed++
+					log.Debug().
+						Str("file", j.path).
+						Int("stars", metrics.StarCount).
+						Float64("fwhm", metrics.FWHM).
+						Msg("Frame analyzed")
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, j := range jobs {
+		jobChan <- j
+	}
+	close(jobChan)
+	wg.Wait()
+
+	// Frames still waiting for analysis, e.g. beyond the limit
+	countQuery := "SELECT COUNT(*) FROM fits_files f LEFT JOIN frame_metrics m ON m.file_id = f.id" +
+		" WHERE 1=1" + where + " AND (m.file_id IS NULL OR m.file_hash IS NOT f.hash)"
+	if err := d.db.QueryRow(countQuery, args...).Scan(&result.Remaining); err != nil {
+		return nil, err
+	}
+	result.Duration = time.Since(startTime).Round(time.Millisecond).String()
+
+	log.Info().
+		Int("analyzed", result.Analyzed).
+		Int("failed", result.Failed).
+		Int("remaining", result.Remaining).
+		Str("duration", result.Duration).
+		Msg("Frame analysis completed")
+
+	return result, nil
+}
+
+// upsertFrameMetrics stores the metrics of a frame, replacing earlier ones
+// (including star metrics taken from N.I.N.A.).
+// Shape metrics of frames without measurable stars are stored as NULL, so
+// such frames do not pass "FWHM below x" filters.
+func (d *Database) upsertFrameMetrics(fileID int64, hash sql.NullString, m *FrameMetrics) error {
+	var hfr, fwhm, ecc interface{}
+	if m.HFR > 0 {
+		hfr, fwhm, ecc = m.HFR, m.FWHM, m.Eccentricity
+	}
+
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	_, err := d.db.Exec(`
+		INSERT INTO frame_metrics (
+			file_id, file_hash, source, background, noise, star_count, hfr, fwhm, eccentricity, analyzed_at
+		) VALUES (?, ?, 'pixels', ?, ?, ?, ?, ?, ?, strftime('%s', 'now'))
+		ON CONFLICT(file_id) DO UPDATE SET
+			file_hash = excluded.file_hash,
+			source = excluded.source,
+			background = excluded.background,
+			noise = excluded.noise,
+			star_count = excluded.star_count,
+			hfr = excluded.hfr,
+			fwhm = excluded.fwhm,
+			eccentricity = excluded.eccentricity,
+			analyzed_at = excluded.analyzed_at
+	`, fileID, hash, m.Background, m.Noise, m.StarCount, hfr, fwhm, ecc)
+	return err
+}
@@ -0,0 +1,214 @@
+package mcpserver
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pressly/goose/v3"
+	"github.com/rs/zerolog/log"
+)
+
+// Schema changes are goose migrations: numbered SQL files in migrations/ plus
+// the Go migrations registered below. Never edit a migration that has been
+// released; add a new one instead.
+//
+//go:embed migrations/*.sql
+var embedMigrations embed.FS
+
+// acquisitionColumns are the acquisition-condition columns added to
+// fits_files by migration 4. Databases created by the pre-migration schema
+// version 2 already have them, so the migration only adds missing ones.
+var acquisitionColumns = []struct{ name, sqlType string }{
+	{"ccd_temp", "REAL"},
+	{"set_temp", "REAL"},
+	{"x_binning", "INTEGER"},
+	{"y_binning", "INTEGER"},
+	{"naxis1", "INTEGER"},
+	{"naxis2", "INTEGER"},
+	{"pixel_size", "REAL"},
+	{"bayer_pattern", "TEXT"},
+	{"airmass", "REAL"},
+	{"object_alt", "REAL"},
+	{"site_lat", "REAL"},
+	{"site_long", "REAL"},
+	{"focuser_position", "INTEGER"},
+	{"focuser_temp", "REAL"},
+	{"pier_side", "TEXT"},
+	{"rotator", "REAL"},
+	{"readout_mode", "TEXT"},
+}
+
+// goMigrations returns the migrations that cannot be expressed in plain SQL.
+func goMigrations() []*goose.Migration {
+	return []*goose.Migration{
+		goose.NewGoMigration(4, &goose.GoFunc{RunTx: addAcquisitionColumns}, nil),
+	}
+}
+
+// addAcquisitionColumns adds the acquisition-condition columns that are not
+// present yet. Existing rows keep NULL values until they are rescanned.
+func addAcquisitionColumns(ctx context.Context, tx *sql.Tx) error {
+	existing, err := tableColumns(ctx, tx, "fits_files")
+	if err != nil {
+		return err
+	}
+
+	for _, col := range acquisitionColumns {
+		if existing[col.name] {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE fits_files ADD COLUMN %s %s", col.name, col.sqlType)); err != nil {
+			return fmt.Errorf("failed to add column %s: %w", col.name, err)
+		}
+	}
+	return nil
+}
+
+// tableColumns returns the set of column names of a table.
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+// migrationProvider creates a goose provider for the embedded migrations.
+func (d *Database) migrationProvider() (*goose.Provider, error) {
+	migrationsFS, err := fs.Sub(embedMigrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return goose.NewProvider(goose.DialectSQLite3, d.db, migrationsFS,
+		goose.WithGoMigrations(goMigrations()...),
+		goose.WithDisableGlobalRegistry(true),
+	)
+}
+
+// Migrate applies all pending migrations, each in its own transaction. When
+// an existing archive is about to be changed, a backup copy is written to the
+// backups directory next to the database first.
+func (d *Database) Migrate(ctx context.Context) ([]*goose.MigrationResult, error) {
+	provider, err := d.migrationProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := provider.HasPending(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check pending migrations: %w", err)
+	}
+	if !pending {
+		version, err := provider.GetDBVersion(ctx)
+		if err == nil {
+			log.Debug().Int64("version", version).Msg("Database schema is up to date")
+		}
+		return nil, nil
+	}
+
+	hasData, err := d.hasTable("fits_files")
+	if err != nil {
+		return nil, err
+	}
+	if hasData {
+		path, err := d.Backup("pre-migration")
+		if err != nil {
+			return nil, fmt.Errorf("failed to back up database before migrating: %w", err)
+		}
+		log.Info().Str("backup", path).Msg("Database backed up before migration")
+	}
+
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	results, err := provider.Up(ctx)
+	for _, r := range results {
+		log.Info().
+			Int64("version", r.Source.Version).
+			Str("migration", migrationName(r.Source)).
+			Dur("duration", r.Duration).
+			Msg("Migration applied")
+	}
+	if err != nil {
+		return results, err
+	}
+
+	version, _ := provider.GetDBVersion(ctx)
+	log.Info().Int64("version", version).Int("applied", len(results)).Msg("Database schema migrated")
+	return results, nil
+}
+
+// migrationName returns a display name for a migration source. Go migrations
+// have no file, so they are shown by version.
+func migrationName(src *goose.Source) string {
+	if src.Path == "" {
+		return fmt.Sprintf("%05d (go)", src.Version)
+	}
+	return filepath.Base(src.Path)
+}
+
+// MigrationStatus reports every known migration and whether it has been
+// applied to this database.
+func (d *Database) MigrationStatus(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	provider, err := d.migrationProvider()
+	if err != nil {
+		return nil, err
+	}
+	return provider.Status(ctx)
+}
+
+// hasTable reports whether a table exists in the database.
+func (d *Database) hasTable(name string) (bool, error) {
+	var count int
+	err := d.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect database: %w", err)
+	}
+	return count > 0, nil
+}
+
+// Backup writes a consistent copy of the database to the backups directory
+// next to the database file and returns its path. The reason becomes part of
+// the file name.
+func (d *Database) Backup(reason string) (string, error) {
+	dir := filepath.Join(filepath.Dir(d.filePath), "backups")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	base := filepath.Base(d.filePath)
+	name := fmt.Sprintf("%s-%s-%s",
+		base[:len(base)-len(filepath.Ext(base))],
+		reason,
+		time.Now().Format("20060102-150405"))
+	path := filepath.Join(dir, name+filepath.Ext(base))
+	// VACUUM INTO does not overwrite; a second backup within the same second
+	// gets a number
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); err != nil {
+			break
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", name, i, filepath.Ext(base)))
+	}
+
+	if _, err := d.db.Exec("VACUUM INTO ?", path); err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	return path, nil
+}
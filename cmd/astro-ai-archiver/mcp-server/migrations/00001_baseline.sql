@@ -0,0 +1,38 @@
+-- Baseline: the original single-table schema (schema_version 1).
+-- Uses IF NOT EXISTS so databases created before migrations were introduced
+-- are adopted without changes.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS fits_files (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    relative_path TEXT NOT NULL UNIQUE,
+    hash TEXT,
+    file_mod_time INTEGER,
+    row_mod_time INTEGER DEFAULT (strftime('%s', 'now')),
+    object TEXT NOT NULL,
+    ra REAL,
+    dec REAL,
+    telescope TEXT NOT NULL,
+    focal_length REAL,
+    exposure REAL NOT NULL,
+    utc_time TEXT,
+    local_time TEXT,
+    julian_date REAL,
+    observation_date TEXT,
+    software TEXT,
+    camera TEXT,
+    gain REAL,
+    offset INTEGER,
+    filter TEXT NOT NULL,
+    image_type TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_object ON fits_files(object);
+CREATE INDEX IF NOT EXISTS idx_filter ON fits_files(filter);
+CREATE INDEX IF NOT EXISTS idx_telescope ON fits_files(telescope);
+CREATE INDEX IF NOT EXISTS idx_utc_time ON fits_files(utc_time);
+CREATE INDEX IF NOT EXISTS idx_hash ON fits_files(hash);
+CREATE INDEX IF NOT EXISTS idx_relative_path ON fits_files(relative_path);
+
+-- +goose Down
+DROP TABLE fits_files;
@@ -0,0 +1,21 @@
+-- Plate-solved field geometry, one row per solved frame.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS fits_wcs (
+    file_id INTEGER PRIMARY KEY REFERENCES fits_files(id) ON DELETE CASCADE,
+    center_ra REAL NOT NULL,
+    center_dec REAL NOT NULL,
+    rotation REAL,
+    pixel_scale REAL,
+    corner1_ra REAL,
+    corner1_dec REAL,
+    corner2_ra REAL,
+    corner2_dec REAL,
+    corner3_ra REAL,
+    corner3_dec REAL,
+    corner4_ra REAL,
+    corner4_dec REAL
+);
+
+-- +goose Down
+DROP TABLE fits_wcs;
@@ -0,0 +1,28 @@
+-- Declination-band spatial index for cone searches. Existing rows are indexed
+-- from their plate-solved centre, or the header RA/Dec when unsolved.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS sky_index (
+    file_id INTEGER PRIMARY KEY REFERENCES fits_files(id) ON DELETE CASCADE,
+    ra REAL NOT NULL,
+    dec REAL NOT NULL,
+    dec_band INTEGER NOT NULL,
+    source TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_sky_index_band ON sky_index(dec_band, ra);
+
+INSERT INTO sky_index (file_id, ra, dec, dec_band, source)
+SELECT f.id,
+       COALESCE(w.center_ra, f.ra),
+       COALESCE(w.center_dec, f.dec),
+       MIN(179, MAX(0, CAST(COALESCE(w.center_dec, f.dec) + 90 AS INTEGER))),
+       CASE WHEN w.file_id IS NOT NULL THEN 'wcs' ELSE 'header' END
+FROM fits_files f
+LEFT JOIN fits_wcs w ON w.file_id = f.id
+WHERE NOT EXISTS (SELECT 1 FROM sky_index s WHERE s.file_id = f.id)
+  AND COALESCE(w.center_ra, f.ra) BETWEEN 0 AND 360
+  AND COALESCE(w.center_dec, f.dec) BETWEEN -90 AND 90;
+
+-- +goose Down
+DROP TABLE sky_index;
@@ -0,0 +1,11 @@
+-- The hand-rolled schema_version table is superseded by goose_db_version.
+
+-- +goose Up
+DROP TABLE IF EXISTS schema_version;
+
+-- +goose Down
+CREATE TABLE IF NOT EXISTS schema_version (
+    version INTEGER NOT NULL,
+    applied_at INTEGER DEFAULT (strftime('%s', 'now'))
+);
+INSERT INTO schema_version (version) VALUES (2);
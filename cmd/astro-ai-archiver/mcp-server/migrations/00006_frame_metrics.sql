@@ -0,0 +1,21 @@
+-- Per-frame image quality metrics computed from the pixel data. file_hash
+-- records the content the metrics were computed from, so re-captured files
+-- are analyzed again.
+
+-- +goose Up
+CREATE TABLE frame_metrics (
+    file_id INTEGER PRIMARY KEY REFERENCES fits_files(id) ON DELETE CASCADE,
+    file_hash TEXT,
+    background REAL,
+    noise REAL,
+    star_count INTEGER,
+    hfr REAL,
+    fwhm REAL,
+    eccentricity REAL,
+    analyzed_at INTEGER DEFAULT (strftime('%s', 'now'))
+);
+
+CREATE INDEX idx_frame_metrics_fwhm ON frame_metrics(fwhm);
+
+-- +goose Down
+DROP TABLE frame_metrics;
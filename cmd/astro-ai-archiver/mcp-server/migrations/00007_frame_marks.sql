@@ -0,0 +1,24 @@
+-- Accept/reject grades of frames. Marks are keyed by file content hash, so
+-- they survive rescans, renames and moves of the file.
+
+-- +goose Up
+CREATE TABLE frame_marks (
+    hash TEXT PRIMARY KEY,
+    grade TEXT NOT NULL CHECK (grade IN ('accepted', 'rejected')),
+    reason TEXT,
+    marked_at INTEGER DEFAULT (strftime('%s', 'now'))
+);
+
+CREATE INDEX idx_frame_marks_grade ON frame_marks(grade);
+
+-- All frames that have not been rejected, for aggregation queries
+CREATE VIEW usable_files AS
+SELECT f.*
+FROM fits_files f
+WHERE NOT EXISTS (
+    SELECT 1 FROM frame_marks g WHERE g.hash = f.hash AND g.grade = 'rejected'
+);
+
+-- +goose Down
+DROP VIEW usable_files;
+DROP TABLE frame_marks;
@@ -0,0 +1,28 @@
+-- Imaging sessions: the frames of one night taken with one telescope and
+-- camera. Rows are derived from fits_files by RefreshSessions after every
+-- scan; signature tells which sessions changed since the last refresh.
+
+-- +goose Up
+CREATE TABLE sessions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    observation_date TEXT NOT NULL,
+    telescope TEXT NOT NULL,
+    camera TEXT NOT NULL,
+    start_time TEXT,
+    end_time TEXT,
+    targets TEXT,
+    filters TEXT,
+    frame_count INTEGER NOT NULL,
+    integration REAL NOT NULL,
+    gap_count INTEGER NOT NULL DEFAULT 0,
+    gap_seconds REAL NOT NULL DEFAULT 0,
+    longest_gap REAL NOT NULL DEFAULT 0,
+    signature TEXT NOT NULL,
+    refreshed_at INTEGER DEFAULT (strftime('%s', 'now')),
+    UNIQUE (observation_date, telescope, camera)
+);
+
+CREATE INDEX idx_sessions_date ON sessions(observation_date);
+
+-- +goose Down
+DROP TABLE sessions;
@@ -0,0 +1,26 @@
+-- Imaging projects: a target with integration goals per filter. Projects
+-- come from the project tools (source 'tool') or from the config file
+-- (source 'config', synchronised at startup).
+
+-- +goose Up
+CREATE TABLE projects (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    target TEXT NOT NULL,
+    telescope TEXT,
+    camera TEXT,
+    notes TEXT,
+    source TEXT NOT NULL DEFAULT 'tool' CHECK (source IN ('tool', 'config')),
+    added_at INTEGER DEFAULT (strftime('%s', 'now'))
+);
+
+CREATE TABLE project_goals (
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    filter TEXT NOT NULL,
+    hours REAL NOT NULL CHECK (hours > 0),
+    PRIMARY KEY (project_id, filter)
+);
+
+-- +goose Down
+DROP TABLE project_goals;
+DROP TABLE projects;
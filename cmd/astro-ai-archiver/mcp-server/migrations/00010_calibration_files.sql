@@ -0,0 +1,36 @@
+-- Index of calibration frames (darks, flats, bias and dark flats, single
+-- frames and masters). They are kept apart from fits_files so that all
+-- light-frame queries stay unchanged.
+
+-- +goose Up
+CREATE TABLE calibration_files (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    relative_path TEXT NOT NULL UNIQUE,
+    hash TEXT,
+    file_mod_time INTEGER,
+    frame_type TEXT NOT NULL CHECK (frame_type IN ('DARK', 'FLAT', 'BIAS', 'DARKFLAT')),
+    master INTEGER NOT NULL DEFAULT 0,
+    telescope TEXT,
+    camera TEXT,
+    filter TEXT,
+    exposure REAL,
+    gain REAL,
+    offset INTEGER,
+    x_binning INTEGER,
+    y_binning INTEGER,
+    ccd_temp REAL,
+    set_temp REAL,
+    naxis1 INTEGER,
+    naxis2 INTEGER,
+    readout_mode TEXT,
+    rotator REAL,
+    utc_time TEXT,
+    observation_date TEXT,
+    indexed_at INTEGER DEFAULT (strftime('%s', 'now'))
+);
+
+CREATE INDEX idx_calibration_type_camera ON calibration_files(frame_type, camera);
+CREATE INDEX idx_calibration_date ON calibration_files(observation_date);
+
+-- +goose Down
+DROP TABLE calibration_files;
@@ -0,0 +1,49 @@
+-- Per-frame metadata written by the N.I.N.A. Session Metadata plugin
+-- (ImageMetaData.csv), stored with the frame metrics. source tells whether
+-- the shape metrics were measured by analyze_frames ('pixels') or taken from
+-- N.I.N.A. ('nina'); a pixel analysis replaces N.I.N.A.'s values.
+-- AcquisitionDetails.csv rows are kept per file.
+
+-- +goose Up
+ALTER TABLE frame_metrics ADD COLUMN source TEXT NOT NULL DEFAULT 'pixels';
+ALTER TABLE frame_metrics ADD COLUMN guiding_rms REAL;
+ALTER TABLE frame_metrics ADD COLUMN guiding_rms_ra REAL;
+ALTER TABLE frame_metrics ADD COLUMN guiding_rms_dec REAL;
+ALTER TABLE frame_metrics ADD COLUMN focuser_position INTEGER;
+ALTER TABLE frame_metrics ADD COLUMN focuser_temp REAL;
+ALTER TABLE frame_metrics ADD COLUMN adu_mean REAL;
+ALTER TABLE frame_metrics ADD COLUMN adu_median REAL;
+ALTER TABLE frame_metrics ADD COLUMN adu_stdev REAL;
+ALTER TABLE frame_metrics ADD COLUMN adu_min INTEGER;
+ALTER TABLE frame_metrics ADD COLUMN adu_max INTEGER;
+
+CREATE TABLE nina_acquisitions (
+    relative_path TEXT PRIMARY KEY,
+    target TEXT,
+    ra TEXT,
+    dec TEXT,
+    telescope TEXT,
+    focal_length REAL,
+    focal_ratio REAL,
+    camera TEXT,
+    pixel_size REAL,
+    bit_depth INTEGER,
+    latitude REAL,
+    longitude REAL,
+    elevation REAL,
+    indexed_at INTEGER DEFAULT (strftime('%s', 'now'))
+);
+
+-- +goose Down
+DROP TABLE nina_acquisitions;
+ALTER TABLE frame_metrics DROP COLUMN adu_max;
+ALTER TABLE frame_metrics DROP COLUMN adu_min;
+ALTER TABLE frame_metrics DROP COLUMN adu_stdev;
+ALTER TABLE frame_metrics DROP COLUMN adu_median;
+ALTER TABLE frame_metrics DROP COLUMN adu_mean;
+ALTER TABLE frame_metrics DROP COLUMN focuser_temp;
+ALTER TABLE frame_metrics DROP COLUMN focuser_position;
+ALTER TABLE frame_metrics DROP COLUMN guiding_rms_dec;
+ALTER TABLE frame_metrics DROP COLUMN guiding_rms_ra;
+ALTER TABLE frame_metrics DROP COLUMN guiding_rms;
+ALTER TABLE frame_metrics DROP COLUMN source;
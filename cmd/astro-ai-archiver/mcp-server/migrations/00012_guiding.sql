@@ -0,0 +1,68 @@
+-- PHD2 guide logs. Each "Guiding Begins ... Guiding Ends" section of a log is
+-- a guide session with its samples (raw RA/Dec distances in guide camera
+-- pixels, times in Unix seconds UTC) and dither/settle events.
+-- frame_guiding holds the guiding RMS during each light frame's exposure.
+
+-- +goose Up
+CREATE TABLE guide_logs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    path TEXT NOT NULL UNIQUE,
+    file_mod_time INTEGER,
+    indexed_at INTEGER DEFAULT (strftime('%s', 'now'))
+);
+
+CREATE TABLE guide_sessions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    log_id INTEGER NOT NULL REFERENCES guide_logs(id) ON DELETE CASCADE,
+    night TEXT NOT NULL,
+    mount TEXT,
+    pixel_scale REAL,
+    start_time TEXT NOT NULL,
+    end_time TEXT NOT NULL,
+    samples INTEGER NOT NULL,
+    rms_ra REAL,
+    rms_dec REAL,
+    rms_total REAL,
+    dithers INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_guide_sessions_night ON guide_sessions(night);
+CREATE INDEX idx_guide_sessions_time ON guide_sessions(start_time, end_time);
+
+CREATE TABLE guide_samples (
+    session_id INTEGER NOT NULL REFERENCES guide_sessions(id) ON DELETE CASCADE,
+    time REAL NOT NULL,
+    ra REAL NOT NULL,
+    dec REAL NOT NULL
+);
+
+CREATE INDEX idx_guide_samples_time ON guide_samples(time);
+CREATE INDEX idx_guide_samples_session ON guide_samples(session_id);
+
+CREATE TABLE guide_events (
+    session_id INTEGER NOT NULL REFERENCES guide_sessions(id) ON DELETE CASCADE,
+    time REAL NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('dither', 'settle_start', 'settle_done', 'settle_failed')),
+    detail TEXT
+);
+
+CREATE INDEX idx_guide_events_time ON guide_events(time);
+
+CREATE TABLE frame_guiding (
+    file_id INTEGER PRIMARY KEY REFERENCES fits_files(id) ON DELETE CASCADE,
+    guide_session_id INTEGER NOT NULL REFERENCES guide_sessions(id) ON DELETE CASCADE,
+    samples INTEGER NOT NULL,
+    rms_ra REAL,
+    rms_dec REAL,
+    rms_total REAL,
+    dithers INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_frame_guiding_session ON frame_guiding(guide_session_id);
+
+-- +goose Down
+DROP TABLE frame_guiding;
+DROP TABLE guide_events;
+DROP TABLE guide_samples;
+DROP TABLE guide_sessions;
+DROP TABLE guide_logs;
@@ -0,0 +1,18 @@
+-- Observing conditions at the time of each frame, from the FITS headers
+-- written by capture software with an observing-conditions device or sky
+-- quality meter. Only frames with at least one value get a row.
+
+-- +goose Up
+CREATE TABLE frame_conditions (
+    file_id INTEGER PRIMARY KEY REFERENCES fits_files(id) ON DELETE CASCADE,
+    ambient_temp REAL,
+    humidity REAL,
+    pressure REAL,
+    dew_point REAL,
+    sky_quality REAL,
+    cloud_cover REAL,
+    wind_speed REAL
+);
+
+-- +goose Down
+DROP TABLE frame_conditions;
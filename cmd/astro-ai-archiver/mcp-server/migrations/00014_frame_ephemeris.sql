@@ -0,0 +1,17 @@
+-- Moon and target geometry at mid-exposure of each light frame, computed
+-- offline from utc_time, the frame position and the observing site. Only
+-- frames with a time, a position and a site get a row.
+
+-- +goose Up
+CREATE TABLE frame_ephemeris (
+    file_id INTEGER PRIMARY KEY REFERENCES fits_files(id) ON DELETE CASCADE,
+    moon_illumination REAL NOT NULL, -- Illuminated fraction, 0 = new, 1 = full
+    moon_alt REAL NOT NULL,          -- Degrees, negative below the horizon
+    moon_separation REAL NOT NULL,   -- Degrees between the Moon and the target
+    target_alt REAL NOT NULL,
+    target_az REAL NOT NULL,         -- Degrees from north through east
+    airmass REAL                     -- NULL below the horizon
+);
+
+-- +goose Down
+DROP TABLE frame_ephemeris;
@@ -0,0 +1,23 @@
+-- Astronomical darkness per session: the period the Sun is more than 18°
+-- below the horizon on the session's night at its site, the exposure time
+-- and gap time within it. Sessions without a site have no darkness.
+-- Clearing the signatures makes the next RefreshSessions fill them in.
+
+-- +goose Up
+ALTER TABLE sessions ADD COLUMN site_lat REAL;
+ALTER TABLE sessions ADD COLUMN site_long REAL;
+ALTER TABLE sessions ADD COLUMN dark_start TEXT;
+ALTER TABLE sessions ADD COLUMN dark_end TEXT;
+ALTER TABLE sessions ADD COLUMN dark_seconds REAL NOT NULL DEFAULT 0;
+ALTER TABLE sessions ADD COLUMN dark_integration REAL NOT NULL DEFAULT 0;
+ALTER TABLE sessions ADD COLUMN lost_seconds REAL NOT NULL DEFAULT 0;
+UPDATE sessions SET signature = '';
+
+-- +goose Down
+ALTER TABLE sessions DROP COLUMN lost_seconds;
+ALTER TABLE sessions DROP COLUMN dark_integration;
+ALTER TABLE sessions DROP COLUMN dark_seconds;
+ALTER TABLE sessions DROP COLUMN dark_end;
+ALTER TABLE sessions DROP COLUMN dark_start;
+ALTER TABLE sessions DROP COLUMN site_long;
+ALTER TABLE sessions DROP COLUMN site_lat;
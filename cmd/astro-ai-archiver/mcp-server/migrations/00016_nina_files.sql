@@ -0,0 +1,16 @@
+-- N.I.N.A. Session Metadata CSVs already imported, so a rescan skips the
+-- ones that have not changed. analyzed_at is only set by analyze_frames:
+-- frames with N.I.N.A. metrics alone have not been analysed.
+
+-- +goose Up
+CREATE TABLE nina_files (
+    path TEXT PRIMARY KEY,
+    file_mod_time INTEGER,
+    indexed_at INTEGER DEFAULT (strftime('%s', 'now'))
+);
+
+UPDATE frame_metrics SET analyzed_at = NULL WHERE source = 'nina';
+
+-- +goose Down
+UPDATE frame_metrics SET analyzed_at = strftime('%s', 'now') WHERE source = 'nina' AND analyzed_at IS NULL;
+DROP TABLE nina_files;
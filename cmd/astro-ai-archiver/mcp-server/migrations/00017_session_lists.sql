@@ -0,0 +1,15 @@
+-- sessions.targets and sessions.filters are separated by newlines instead of
+-- commas, which occur in target names. Clearing the signatures makes the
+-- next RefreshSessions rebuild lists that were split at such a comma.
+
+-- +goose Up
+UPDATE sessions SET
+    targets = REPLACE(targets, ',', char(10)),
+    filters = REPLACE(filters, ',', char(10)),
+    signature = '';
+
+-- +goose Down
+UPDATE sessions SET
+    targets = REPLACE(targets, char(10), ','),
+    filters = REPLACE(filters, char(10), ','),
+    signature = '';
@@ -0,0 +1,10 @@
+-- frame_ephemeris.site records the configured site a row was computed with,
+-- so RefreshEphemeris can recompute it when the site changes. Frames with
+-- their own SITELAT/SITELONG keep ''. Existing rows also start with '', which
+-- makes the next refresh recompute those that used the configured site.
+
+-- +goose Up
+ALTER TABLE frame_ephemeris ADD COLUMN site TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE frame_ephemeris DROP COLUMN site;
@@ -0,0 +1,124 @@
+package mcpserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// legacyV1Schema is the schema created by releases before migrations existed.
+const legacyV1Schema = `
+CREATE TABLE fits_files (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    relative_path TEXT NOT NULL UNIQUE,
+    hash TEXT,
+    file_mod_time INTEGER,
+    row_mod_time INTEGER DEFAULT (strftime('%s', 'now')),
+    object TEXT NOT NULL,
+    ra REAL,
+    dec REAL,
+    telescope TEXT NOT NULL,
+    focal_length REAL,
+    exposure REAL NOT NULL,
+    utc_time TEXT,
+    local_time TEXT,
+    julian_date REAL,
+    observation_date TEXT,
+    software TEXT,
+    camera TEXT,
+    gain REAL,
+    offset INTEGER,
+    filter TEXT NOT NULL,
+    image_type TEXT
+);
+CREATE TABLE schema_version (
+    version INTEGER NOT NULL,
+    applied_at INTEGER DEFAULT (strftime('%s', 'now'))
+);
+INSERT INTO schema_version (version) VALUES (1);
+INSERT INTO fits_files (relative_path, hash, file_mod_time, object, ra, dec, telescope, focal_length, exposure,
+    utc_time, local_time, julian_date, observation_date, software, camera, gain, offset, filter, image_type)
+VALUES ('M31/light_001.fits', 'abc', 1700000000, 'M31', 10.68, 41.27, 'RC8', 1624, 300,
+    '2024-10-01T22:00:00', '2024-10-02T00:00:00', 2460585.4, '2024-10-01', 'N.I.N.A.', 'ASI2600MM', 100, 50, 'L', 'LIGHT');
+`
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), ".aaa", "archive.db")
+
+	legacy, err := OpenDatabase(dbPath, nil)
+	if err != nil {
+		t.Fatalf("OpenDatabase: %v", err)
+	}
+	if _, err := legacy.db.Exec(legacyV1Schema); err != nil {
+		t.Fatalf("creating legacy schema: %v", err)
+	}
+	legacy.Close()
+
+	db, err := NewDatabase(dbPath, nil)
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	defer db.Close()
+
+	statuses, err := db.MigrationStatus(context.Background())
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	for _, st := range statuses {
+		if st.State != "applied" {
+			t.Errorf("migration %d is %s, expected applied", st.Source.Version, st.State)
+		}
+	}
+
+	result, err := db.QueryFiles(map[string]interface{}{"target": "M31"}, 10, 0)
+	if err != nil {
+		t.Fatalf("QueryFiles after migration: %v", err)
+	}
+	files := result.([]*FITSFile)
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file after migration, got %d", len(files))
+	}
+	if f := files[0]; f.RelativePath != "M31/light_001.fits" || f.Object != "M31" || f.Filter != "L" || f.Exposure != 300 {
+		t.Errorf("migrated file = %s %s %s %g", f.RelativePath, f.Object, f.Filter, f.Exposure)
+	}
+	if result, _ := db.QueryFiles(map[string]interface{}{"target": "M42"}, 10, 0); len(result.([]*FITSFile)) != 0 {
+		t.Error("the target filter did not apply to migrated rows")
+	}
+
+	matches, err := db.ConeSearch(10.7, 41.3, 0.5, nil, 10)
+	if err != nil {
+		t.Fatalf("ConeSearch: %v", err)
+	}
+	if len(matches) != 1 {
+		t.Errorf("expected the migrated file in the sky index, got %d matches", len(matches))
+	}
+
+	if ok, _ := db.hasTable("schema_version"); ok {
+		t.Error("legacy schema_version table was not dropped")
+	}
+
+	backups, _ := os.ReadDir(filepath.Join(filepath.Dir(dbPath), "backups"))
+	if len(backups) != 1 {
+		t.Errorf("expected 1 pre-migration backup, got %d", len(backups))
+	}
+
+	// A second run has nothing to do and must not take another backup.
+	results, err := db.Migrate(context.Background())
+	if err != nil || len(results) != 0 {
+		t.Errorf("second Migrate = %d results, %v; expected none", len(results), err)
+	}
+}
+
+func TestMigrateNewDatabaseSkipsBackup(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDatabase(filepath.Join(dir, "archive.db"), nil)
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := os.Stat(filepath.Join(dir, "backups")); !os.IsNotExist(err) {
+		t.Error("a backup was taken for a new database")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"reflect"
 	"time"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
 )
 
 // DirectoryConfig can be either a single string or an array of strings
@@ -79,6 +81,139 @@ type FITSFile struct {
 	Offset          *int           `db:"offset"`
 	Filter          string         `db:"filter"`
 	ImageType       string         `db:"image_type"`
+
+	// Acquisition conditions (schema v2)
+	CCDTemp         *float64 `db:"ccd_temp"`         // CCD-TEMP, sensor temperature in °C
+	SetTemp         *float64 `db:"set_temp"`         // SET-TEMP, cooler set point in °C
+	XBinning        *int     `db:"x_binning"`        // XBINNING
+	YBinning        *int     `db:"y_binning"`        // YBINNING
+	Naxis1          *int     `db:"naxis1"`           // Image width in pixels
+	Naxis2          *int     `db:"naxis2"`           // Image height in pixels
+	PixelSize       *float64 `db:"pixel_size"`       // XPIXSZ, pixel size in µm (including binning)
+	BayerPattern    string   `db:"bayer_pattern"`    // BAYERPAT, e.g. "RGGB"; empty for mono sensors
+	Airmass         *float64 `db:"airmass"`          // AIRMASS
+	ObjectAlt       *float64 `db:"object_alt"`       // OBJCTALT, target altitude in degrees
+	SiteLat         *float64 `db:"site_lat"`         // SITELAT, observatory latitude in degrees
+	SiteLong        *float64 `db:"site_long"`        // SITELONG, observatory longitude in degrees (east positive)
+	FocuserPosition *int     `db:"focuser_position"` // FOCPOS, focuser step position
+	FocuserTemp     *float64 `db:"focuser_temp"`     // FOCTEMP, focuser temperature in °C
+	PierSide        string   `db:"pier_side"`        // PIERSIDE, "EAST" or "WEST"
+	Rotator         *float64 `db:"rotator"`          // ROTATOR, mechanical rotator angle in degrees
+	ReadoutMode     string   `db:"readout_mode"`     // READOUTM
+
+	Grade       string `db:"grade"`        // "accepted", "rejected" or "" when ungraded (frame_marks)
+	GradeReason string `db:"grade_reason"` // Reason given with the grade
+
+	WCS        *FrameWCS        `db:"-"` // Plate-solved field geometry, nil when the frame carries no WCS
+	Metrics    *FrameMetrics    `db:"-"` // Image quality metrics, nil until the frame has been analyzed
+	Conditions *FrameConditions `db:"-"` // Observing conditions, nil when the headers carry none
+	Ephemeris  *FrameEphemeris  `db:"-"` // Moon and target geometry, nil without time, position or site
+}
+
+// FrameEphemeris is the Moon and target geometry at mid-exposure of a frame
+// (table frame_ephemeris), computed offline at scan time. Angles are in
+// degrees.
+type FrameEphemeris struct {
+	MoonIllumination float64  `db:"moon_illumination"` // Illuminated fraction, 0 = new, 1 = full
+	MoonAlt          float64  `db:"moon_alt"`          // Negative below the horizon
+	MoonSeparation   float64  `db:"moon_separation"`   // Between the Moon and the target
+	TargetAlt        float64  `db:"target_alt"`
+	TargetAz         float64  `db:"target_az"` // From north through east
+	Airmass          *float64 `db:"airmass"`   // nil below the horizon
+}
+
+// FrameConditions holds the observing conditions written into a frame's
+// header (table frame_conditions). Values are nil when the keyword is absent.
+type FrameConditions struct {
+	AmbientTemp *float64 `db:"ambient_temp"` // AMBTEMP, °C
+	Humidity    *float64 `db:"humidity"`     // HUMIDITY, %
+	Pressure    *float64 `db:"pressure"`     // PRESSURE, hPa
+	DewPoint    *float64 `db:"dew_point"`    // DEWPOINT, °C
+	SkyQuality  *float64 `db:"sky_quality"`  // SKYQUAL or MPSAS, mag/arcsec²
+	CloudCover  *float64 `db:"cloud_cover"`  // CLOUDCVR, %
+	WindSpeed   *float64 `db:"wind_speed"`   // WINDSPD
+}
+
+// FrameMetrics holds the image quality metrics of a frame (table
+// frame_metrics). Background and noise are in ADU; HFR and FWHM in sensor
+// pixels. The star shape values are 0 when no stars could be measured.
+type FrameMetrics struct {
+	Background   float64 `db:"background"`   // Median sky level
+	Noise        float64 `db:"noise"`        // Background noise (scaled MAD)
+	StarCount    int     `db:"star_count"`   // Number of detected stars
+	HFR          float64 `db:"hfr"`          // Median half-flux radius
+	FWHM         float64 `db:"fwhm"`         // Median full width at half maximum
+	Eccentricity float64 `db:"eccentricity"` // Median eccentricity, 0 = round
+	Source       string  `db:"source"`       // "pixels" (analyze_frames) or "nina"
+	AnalyzedAt   int64   `db:"analyzed_at"`  // Unix timestamp of the analysis, 0 for N.I.N.A. metrics
+}
+
+// Session is one night of imaging with one telescope and camera (table
+// sessions), derived from the frames' observation_date. Times are UTC;
+// integration, gaps and darkness are in seconds. Darkness is only known for
+// sessions with a site. Breakdown and Gaps are only filled by GetSession.
+type Session struct {
+	ID              int64    `db:"id"`
+	ObservationDate string   `db:"observation_date"`
+	Telescope       string   `db:"telescope"`
+	Camera          string   `db:"camera"`
+	StartTime       string   `db:"start_time"` // Start of the first frame
+	EndTime         string   `db:"end_time"`   // End of the last frame
+	Targets         []string `db:"targets"`
+	Filters         []string `db:"filters"`
+	FrameCount      int      `db:"frame_count"`
+	Integration     float64  `db:"integration"`
+	GapCount        int      `db:"gap_count"`   // Pauses longer than sessionGapThreshold
+	GapSeconds      float64  `db:"gap_seconds"` // Total length of those pauses
+	LongestGap      float64  `db:"longest_gap"`
+
+	SiteLat         *float64 `db:"site_lat"`         // Average SITELAT of the frames, else the configured site
+	SiteLong        *float64 `db:"site_long"`        // Likewise for SITELONG
+	DarkStart       string   `db:"dark_start"`       // Start of astronomical darkness, "" when it never gets dark
+	DarkEnd         string   `db:"dark_end"`         // End of astronomical darkness
+	DarkSeconds     float64  `db:"dark_seconds"`     // Length of astronomical darkness
+	DarkIntegration float64  `db:"dark_integration"` // Exposure time within darkness
+	LostSeconds     float64  `db:"lost_seconds"`     // Gap time within darkness
+	Efficiency      *float64 `db:"-"`                // DarkIntegration / DarkSeconds, nil without darkness
+
+	Breakdown  []SessionGroup           `db:"-"`
+	Gaps       []SessionGap             `db:"-"`
+	Conditions *tools.SessionConditions `db:"-"` // Observing conditions, nil when no frame has any
+}
+
+// SessionGroup is the integration of one target and filter within a session
+type SessionGroup struct {
+	Target      string
+	Filter      string
+	Frames      int
+	Rejected    int // Frames graded as rejected, included in Frames and Integration
+	Integration float64
+}
+
+// SessionGap is a pause in acquisition between two frames of a session
+type SessionGap struct {
+	Start       string
+	End         string
+	Seconds     float64
+	DarkSeconds float64 // Part of the gap within astronomical darkness
+}
+
+// FrameWCS holds the plate-solved field geometry of a frame (table fits_wcs).
+// All coordinates are in degrees. Corners 1-4 are the outer edges of pixels
+// (1,1), (NAXIS1,1), (NAXIS1,NAXIS2) and (1,NAXIS2).
+type FrameWCS struct {
+	CenterRA   float64 `db:"center_ra"`
+	CenterDec  float64 `db:"center_dec"`
+	Rotation   float64 `db:"rotation"`    // Position angle of the image Y axis, degrees east of north
+	PixelScale float64 `db:"pixel_scale"` // Arcseconds per pixel
+	Corner1RA  float64 `db:"corner1_ra"`
+	Corner1Dec float64 `db:"corner1_dec"`
+	Corner2RA  float64 `db:"corner2_ra"`
+	Corner2Dec float64 `db:"corner2_dec"`
+	Corner3RA  float64 `db:"corner3_ra"`
+	Corner3Dec float64 `db:"corner3_dec"`
+	Corner4RA  float64 `db:"corner4_ra"`
+	Corner4Dec float64 `db:"corner4_dec"`
 }
 
 // Config represents the application configuration
@@ -98,16 +233,95 @@ type Config struct {
 	Transport struct {
 		Type string `yaml:"type" mapstructure:"type"` // "stdio" or "http"
 		HTTP struct {
-			Host string `yaml:"host" mapstructure:"host"`
-			Port int    `yaml:"port" mapstructure:"port"`
+			Host                 string         `yaml:"host" mapstructure:"host"`
+			Port                 int            `yaml:"port" mapstructure:"port"`
+			APIKeys              []APIKeyConfig `yaml:"api_keys" mapstructure:"api_keys"`                           // When set, every request needs one of these keys
+			AllowUnauthenticated bool           `yaml:"allow_unauthenticated" mapstructure:"allow_unauthenticated"` // Listen beyond localhost without API keys
+			TLS                  TLSConfig      `yaml:"tls" mapstructure:"tls"`
 		} `yaml:"http" mapstructure:"http"`
 	} `yaml:"transport" mapstructure:"transport"`
-	Logging struct {
+	Analysis struct {
+		OnScan  bool `yaml:"on_scan" mapstructure:"on_scan"` // Analyze new frames after every scan
+		Workers int  `yaml:"workers" mapstructure:"workers"` // Parallel analyses (default: runtime.NumCPU())
+	} `yaml:"analysis" mapstructure:"analysis"`
+	Export struct {
+		Directory string `yaml:"directory" mapstructure:"directory"` // Where stacking sets and data exports are written (default: exports next to the database)
+	} `yaml:"export" mapstructure:"export"`
+	Guiding struct {
+		LogDirectories DirectoryConfig `yaml:"log_directories" mapstructure:"log_directories"` // Extra directories with PHD2_GuideLog_*.txt files
+		Timezone       string          `yaml:"timezone" mapstructure:"timezone"`               // Time zone the logs were written in (default: local)
+	} `yaml:"guiding" mapstructure:"guiding"`
+	Site struct {
+		Latitude  *float64 `yaml:"latitude" mapstructure:"latitude"`   // Degrees, north positive
+		Longitude *float64 `yaml:"longitude" mapstructure:"longitude"` // Degrees, east positive
+	} `yaml:"site" mapstructure:"site"` // Observing site for frames without SITELAT/SITELONG
+	AstroBin struct {
+		Filters []AstroBinFilterConfig `yaml:"filters" mapstructure:"filters"` // Filter names mapped to AstroBin filter ids
+	} `yaml:"astrobin" mapstructure:"astrobin"`
+	Projects []ProjectConfig `yaml:"projects" mapstructure:"projects"` // Imaging projects, synchronised into the database at startup
+	Tools    ToolsConfig     `yaml:"tools" mapstructure:"tools"`       // Which MCP tools are offered
+	Logging  struct {
 		Level  string `mapstructure:"level"`
 		Format string `mapstructure:"format"`
 	} `mapstructure:"logging"`
 }
 
+// ProjectConfig is an imaging project defined in the config file. Goals are
+// a list rather than a map because config keys are case-insensitive and
+// filter names are not.
+type ProjectConfig struct {
+	Name      string       `yaml:"name" mapstructure:"name"`
+	Target    string       `yaml:"target" mapstructure:"target"`
+	Telescope string       `yaml:"telescope" mapstructure:"telescope"`
+	Camera    string       `yaml:"camera" mapstructure:"camera"`
+	Notes     string       `yaml:"notes" mapstructure:"notes"`
+	Goals     []GoalConfig `yaml:"goals" mapstructure:"goals"`
+}
+
+// GoalConfig is the integration goal of one filter in hours
+type GoalConfig struct {
+	Filter string  `yaml:"filter" mapstructure:"filter"`
+	Hours  float64 `yaml:"hours" mapstructure:"hours"`
+}
+
+// AstroBinFilterConfig maps a filter name as written by the capture software
+// to the id of the filter in AstroBin's equipment database (the number in
+// the filter's AstroBin URL)
+type AstroBinFilterConfig struct {
+	Name string `yaml:"name" mapstructure:"name"`
+	ID   int    `yaml:"id" mapstructure:"id"`
+}
+
+// APIKeyConfig is a named key for the HTTP transport. The name shows up in
+// the logs instead of the key.
+type APIKeyConfig struct {
+	Name  string      `yaml:"name" mapstructure:"name"`
+	Key   string      `yaml:"key" mapstructure:"key"`
+	Tools ToolsConfig `yaml:"tools" mapstructure:"tools"` // Further limits the tools of this key
+}
+
+// ToolsConfig is a tool policy: read_only leaves out the tools that change
+// the archive or write files, allow (when set) lists the only tools offered
+// and deny the tools never offered
+type ToolsConfig struct {
+	ReadOnly bool     `yaml:"read_only" mapstructure:"read_only"`
+	Allow    []string `yaml:"allow" mapstructure:"allow"`
+	Deny     []string `yaml:"deny" mapstructure:"deny"`
+}
+
+// Policy returns the tool policy of the configuration
+func (t ToolsConfig) Policy() tools.ToolPolicy {
+	return tools.ToolPolicy{ReadOnly: t.ReadOnly, Allow: t.Allow, Deny: t.Deny}
+}
+
+// TLSConfig enables HTTPS on the HTTP transport. Without certificate files a
+// self-signed certificate is generated next to the database.
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled" mapstructure:"enabled"`
+	CertFile string `yaml:"cert_file" mapstructure:"cert_file"`
+	KeyFile  string `yaml:"key_file" mapstructure:"key_file"`
+}
+
 // Config interface implementation (for tools package)
 func (c *Config) GetScanDirectories() []string {
 	return c.Scan.Directory
@@ -133,6 +347,10 @@ func (c *Config) GetLoggingFormat() string {
 	return c.Logging.Format
 }
 
+func (c *Config) GetToolPolicy() tools.ToolPolicy {
+	return c.Tools.Policy()
+}
+
 // ScanResult represents the result of a directory scan
 type ScanResult struct {
 	FilesScanned int
@@ -140,6 +358,9 @@ type ScanResult struct {
 	FilesUpdated int
 	FilesSkipped int
 	FilesDeleted int
+	Calibration  int // Calibration frames indexed
+	NINAFrames   int // Frames with metadata from N.I.N.A. ImageMetaData.csv files
+	GuideLogs    int // PHD2 guide logs imported
 	Errors       []string
 	Duration     time.Duration
 }
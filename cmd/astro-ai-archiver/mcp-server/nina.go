@@ -0,0 +1,290 @@
+package mcpserver
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Files written next to the images by the N.I.N.A. Session Metadata plugin
+const (
+	ninaImageMetadataFile = "imagemetadata.csv"
+	ninaAcquisitionFile   = "acquisitiondetails.csv"
+)
+
+// isNINAFile reports whether a file name is one of the Session Metadata
+// plugin's CSV files.
+func isNINAFile(name string) bool {
+	name = strings.ToLower(name)
+	return name == ninaImageMetadataFile || name == ninaAcquisitionFile
+}
+
+// ImportNINAFile reads a Session Metadata CSV file. ImageMetaData.csv rows are
+// matched by file name to frames in the same directory tree and stored with
+// their frame metrics; AcquisitionDetails.csv is stored in nina_acquisitions.
+// A file unchanged since its last import is skipped unless force is set.
+// It returns the number of frames that received metadata.
+func (d *Database) ImportNINAFile(absPath string, force bool) (int, error) {
+	relPath, err := d.GetRelativePath(absPath)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return 0, err
+	}
+
+	var modTime int64
+	err = d.db.QueryRow("SELECT file_mod_time FROM nina_files WHERE path = ?", relPath).Scan(&modTime)
+	if err == nil && modTime == info.ModTime().Unix() && !force {
+		return 0, nil
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	records, err := readNINACSV(absPath)
+	if err != nil {
+		return 0, err
+	}
+
+	matched := 0
+	if len(records) >= 2 { // More than the header
+		if strings.EqualFold(path.Base(relPath), ninaAcquisitionFile) {
+			err = d.importNINAAcquisition(relPath, records)
+		} else {
+			matched, err = d.importNINAImageMetadata(relPath, records)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+	_, err = d.db.Exec(`
+		INSERT INTO nina_files (path, file_mod_time) VALUES (?, ?)
+		ON CONFLICT(path) DO UPDATE SET file_mod_time = excluded.file_mod_time, indexed_at = strftime('%s', 'now')
+	`, relPath, info.ModTime().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return matched, nil
+}
+
+// readNINACSV returns all records of a CSV file with the header first.
+func readNINACSV(name string) ([][]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	if len(records) > 0 && len(records[0]) > 0 {
+		records[0][0] = strings.TrimPrefix(records[0][0], "\ufeff") // Byte order mark
+	}
+	return records, nil
+}
+
+// ninaRecord gives access to the fields of a CSV row by column name.
+type ninaRecord struct {
+	columns map[string]int
+	fields  []string
+}
+
+func ninaColumns(header []string) map[string]int {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	return columns
+}
+
+func (r ninaRecord) str(column string) string {
+	i, ok := r.columns[strings.ToLower(column)]
+	if !ok || i >= len(r.fields) {
+		return ""
+	}
+	return strings.TrimSpace(r.fields[i])
+}
+
+// float returns a numeric field, or nil when it is empty or NaN (N.I.N.A.
+// writes NaN for metrics it could not measure).
+func (r ninaRecord) float(column string) interface{} {
+	v, err := strconv.ParseFloat(r.str(column), 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return v
+}
+
+func (r ninaRecord) integer(column string) interface{} {
+	v, ok := r.float(column).(float64)
+	if !ok {
+		return nil
+	}
+	return int64(math.Round(v))
+}
+
+// importNINAImageMetadata stores the per-frame rows of an ImageMetaData.csv.
+// N.I.N.A.'s star metrics only fill frames without a pixel analysis.
+func (d *Database) importNINAImageMetadata(relPath string, records [][]string) (int, error) {
+	frames, err := d.framesByName(path.Dir(relPath))
+	if err != nil {
+		return 0, err
+	}
+
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO frame_metrics (
+			file_id, source, background, star_count, hfr, fwhm, eccentricity,
+			guiding_rms, guiding_rms_ra, guiding_rms_dec, focuser_position, focuser_temp,
+			adu_mean, adu_median, adu_stdev, adu_min, adu_max, analyzed_at
+		) VALUES (?, 'nina', ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL)
+		ON CONFLICT(file_id) DO UPDATE SET
+			background = CASE WHEN source = 'nina' THEN excluded.background ELSE background END,
+			star_count = CASE WHEN source = 'nina' THEN excluded.star_count ELSE star_count END,
+			hfr = CASE WHEN source = 'nina' THEN excluded.hfr ELSE hfr END,
+			fwhm = CASE WHEN source = 'nina' THEN excluded.fwhm ELSE fwhm END,
+			eccentricity = CASE WHEN source = 'nina' THEN excluded.eccentricity ELSE eccentricity END,
+			guiding_rms = excluded.guiding_rms,
+			guiding_rms_ra = excluded.guiding_rms_ra,
+			guiding_rms_dec = excluded.guiding_rms_dec,
+			focuser_position = excluded.focuser_position,
+			focuser_temp = excluded.focuser_temp,
+			adu_mean = excluded.adu_mean,
+			adu_median = excluded.adu_median,
+			adu_stdev = excluded.adu_stdev,
+			adu_min = excluded.adu_min,
+			adu_max = excluded.adu_max
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	columns := ninaColumns(records[0])
+	matched, unmatched := 0, 0
+	for _, fields := range records[1:] {
+		r := ninaRecord{columns: columns, fields: fields}
+		filePath := r.str("FilePath")
+		name := strings.ToLower(filePath[strings.LastIndexAny(filePath, `/\`)+1:])
+		ids := frames[name]
+		if len(ids) != 1 {
+			unmatched++ // Not indexed (yet), or ambiguous
+			continue
+		}
+
+		// Shape metrics of frames without stars stay NULL, as with analyze_frames
+		hfr, fwhm, ecc := r.float("HFR"), r.float("FWHM"), r.float("Eccentricity")
+		if v, ok := hfr.(float64); !ok || v <= 0 {
+			hfr, fwhm, ecc = nil, nil, nil
+		}
+
+		_, err := stmt.Exec(ids[0],
+			r.float("ADUMedian"), r.integer("DetectedStars"), hfr, fwhm, ecc,
+			r.float("GuidingRMSArcSec"), r.float("GuidingRMSRAArcSec"), r.float("GuidingRMSDECArcSec"),
+			r.integer("FocuserPosition"), r.float("FocuserTemp"),
+			r.float("ADUMean"), r.float("ADUMedian"), r.float("ADUStDev"), r.integer("ADUMin"), r.integer("ADUMax"),
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to store metadata of %s: %w", filePath, err)
+		}
+		matched++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	log.Debug().Str("file", relPath).Int("matched", matched).Int("unmatched", unmatched).Msg("Imported N.I.N.A. image metadata")
+	return matched, nil
+}
+
+// framesByName maps lower-cased file names to the ids of the frames below a
+// directory ("." for the whole archive).
+func (d *Database) framesByName(dir string) (map[string][]int64, error) {
+	query := "SELECT id, relative_path FROM fits_files"
+	var args []interface{}
+	if dir != "." {
+		prefix := dir + "/"
+		query += " WHERE substr(relative_path, 1, ?) = ?"
+		args = append(args, utf8.RuneCountInString(prefix), prefix)
+	}
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list frames: %w", err)
+	}
+	defer rows.Close()
+
+	frames := map[string][]int64{}
+	for rows.Next() {
+		var id int64
+		var relPath string
+		if err := rows.Scan(&id, &relPath); err != nil {
+			return nil, err
+		}
+		name := strings.ToLower(path.Base(relPath))
+		frames[name] = append(frames[name], id)
+	}
+	return frames, rows.Err()
+}
+
+// importNINAAcquisition stores the first row of an AcquisitionDetails.csv.
+func (d *Database) importNINAAcquisition(relPath string, records [][]string) error {
+	r := ninaRecord{columns: ninaColumns(records[0]), fields: records[1]}
+
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	_, err := d.db.Exec(`
+		INSERT INTO nina_acquisitions (
+			relative_path, target, ra, dec, telescope, focal_length, focal_ratio, camera,
+			pixel_size, bit_depth, latitude, longitude, elevation
+		) VALUES (?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?)
+		ON CONFLICT(relative_path) DO UPDATE SET
+			target = excluded.target,
+			ra = excluded.ra,
+			dec = excluded.dec,
+			telescope = excluded.telescope,
+			focal_length = excluded.focal_length,
+			focal_ratio = excluded.focal_ratio,
+			camera = excluded.camera,
+			pixel_size = excluded.pixel_size,
+			bit_depth = excluded.bit_depth,
+			latitude = excluded.latitude,
+			longitude = excluded.longitude,
+			elevation = excluded.elevation,
+			indexed_at = excluded.indexed_at
+	`, relPath, r.str("TargetName"), r.str("RACoordinates"), r.str("DECCoordinates"), r.str("TelescopeName"),
+		r.float("FocalLength"), r.float("FocalRatio"), r.str("CameraName"), r.float("PixelSize"), r.integer("BitDepth"),
+		r.float("ObserverLatitude"), r.float("ObserverLongitude"), r.float("ObserverElevation"))
+	if err != nil {
+		return fmt.Errorf("failed to store acquisition details: %w", err)
+	}
+	return nil
+}
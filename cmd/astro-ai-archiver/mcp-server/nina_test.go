@@ -0,0 +1,124 @@
+package mcpserver
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/astrogo/fitsio"
+)
+
+func TestScanImportsNINAMetadata(t *testing.T) {
+	db, dir := newTestDatabase(t)
+	session := filepath.Join(dir, "2025-09-01", "NGC7000")
+	if err := os.MkdirAll(filepath.Join(session, "LIGHT"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	src := writeTestFITS(t, 8, 8, make([]uint16, 64),
+		fitsio.Card{Name: "IMAGETYP", Value: "LIGHT"},
+		fitsio.Card{Name: "OBJECT", Value: "NGC7000"},
+		fitsio.Card{Name: "TELESCOP", Value: "RedCat 51"},
+		fitsio.Card{Name: "FILTER", Value: "Ha"},
+		fitsio.Card{Name: "EXPTIME", Value: 300.0},
+	)
+	if err := os.Rename(src, filepath.Join(session, "LIGHT", "NGC7000_0001.fits")); err != nil {
+		t.Fatal(err)
+	}
+
+	metadata := "\ufeffExposureNumber,FilePath,FilterName,ADUStDev,ADUMean,ADUMedian,ADUMin,ADUMax,DetectedStars,HFR,FWHM,Eccentricity,GuidingRMSArcSec,GuidingRMSRAArcSec,GuidingRMSDECArcSec,FocuserPosition,FocuserTemp\n" +
+		`1,"D:\Astro\2025-09-01\NGC7000\LIGHT\NGC7000_0001.fits",Ha,12.5,410.2,402,300,65535,812,2.31,NaN,NaN,0.62,0.41,0.47,10512,8.5` + "\n" +
+		`2,"D:\Astro\2025-09-01\NGC7000\LIGHT\NGC7000_0002.fits",Ha,12.1,409.8,401,298,65535,790,2.35,NaN,NaN,0.7,0.5,0.49,10512,8.4` + "\n"
+	acquisition := "TargetName,RACoordinates,DECCoordinates,TelescopeName,FocalLength,FocalRatio,CameraName,PixelSize,BitDepth,ObserverLatitude,ObserverLongitude,ObserverElevation\n" +
+		"NGC7000,20h 59m 17s,44° 31' 44\",RedCat 51,250,4.9,ZWO ASI2600MM Pro,3.76,16,51.5,4.3,12\n"
+	for name, content := range map[string]string{"ImageMetaData.csv": metadata, "AcquisitionDetails.csv": acquisition} {
+		if err := os.WriteFile(filepath.Join(session, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scan := func() *ScanResult {
+		result, err := NewScanner(db, []string{dir}, true, false, 1, nil).Scan()
+		if err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+		return result
+	}
+
+	// Only the indexed frame is matched
+	if result := scan(); result.FilesAdded != 1 || result.NINAFrames != 1 {
+		t.Fatalf("scan added %d frames and %d with N.I.N.A. metadata, expected 1 and 1", result.FilesAdded, result.NINAFrames)
+	}
+
+	id, _ := db.ResolveFileID("2025-09-01/NGC7000/LIGHT/NGC7000_0001.fits")
+	var source string
+	var stars, focuser int
+	var hfr, guiding float64
+	var fwhm sql.NullFloat64
+	query := "SELECT source, star_count, hfr, fwhm, guiding_rms, focuser_position FROM frame_metrics WHERE file_id = ?"
+	if err := db.db.QueryRow(query, id).Scan(&source, &stars, &hfr, &fwhm, &guiding, &focuser); err != nil {
+		t.Fatal(err)
+	}
+	if source != "nina" || stars != 812 || hfr != 2.31 || fwhm.Valid || guiding != 0.62 || focuser != 10512 {
+		t.Errorf("metrics = %s %d %g %v %g %d", source, stars, hfr, fwhm, guiding, focuser)
+	}
+
+	// N.I.N.A. metrics are shown with the frame, but it is not analyzed yet
+	analyzed := func(want bool) int {
+		t.Helper()
+		files, err := db.QueryFiles(map[string]interface{}{"analyzed": want}, 10, 0)
+		if err != nil {
+			t.Fatalf("QueryFiles: %v", err)
+		}
+		return len(files.([]*FITSFile))
+	}
+	if n, m := analyzed(true), analyzed(false); n != 0 || m != 1 {
+		t.Errorf("analyzed filter returned %d analyzed and %d not analyzed frames, expected 0 and 1", n, m)
+	}
+	if file, err := db.GetFileByID(id); err != nil || file.Metrics == nil || file.Metrics.Source != "nina" || file.Metrics.AnalyzedAt != 0 {
+		t.Errorf("file metrics = %+v, %v", file, err)
+	}
+
+	// Unchanged CSVs are not imported again
+	if _, err := db.db.Exec("UPDATE frame_metrics SET focuser_position = 1 WHERE file_id = ?", id); err != nil {
+		t.Fatal(err)
+	}
+	if result := scan(); result.NINAFrames != 0 {
+		t.Errorf("rescan imported %d frames from unchanged CSVs", result.NINAFrames)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(session, "ImageMetaData.csv"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if result := scan(); result.NINAFrames != 1 {
+		t.Errorf("rescan imported %d frames from a changed CSV, expected 1", result.NINAFrames)
+	}
+	if err := db.db.QueryRow("SELECT focuser_position FROM frame_metrics WHERE file_id = ?", id).Scan(&focuser); err != nil || focuser != 10512 {
+		t.Errorf("focuser position after reimport = %d, %v", focuser, err)
+	}
+
+	var telescope string
+	var pixelSize float64
+	err := db.db.QueryRow("SELECT telescope, pixel_size FROM nina_acquisitions WHERE relative_path = '2025-09-01/NGC7000/AcquisitionDetails.csv'").
+		Scan(&telescope, &pixelSize)
+	if err != nil || telescope != "RedCat 51" || pixelSize != 3.76 {
+		t.Errorf("acquisition = %s %g, %v", telescope, pixelSize, err)
+	}
+
+	// A pixel analysis takes precedence over N.I.N.A.'s star metrics, but
+	// the guiding data is kept
+	if err := db.upsertFrameMetrics(id, sql.NullString{}, &FrameMetrics{StarCount: 500, HFR: 1.9, FWHM: 3.5}); err != nil {
+		t.Fatal(err)
+	}
+	scan()
+	if err := db.db.QueryRow(query, id).Scan(&source, &stars, &hfr, &fwhm, &guiding, &focuser); err != nil {
+		t.Fatal(err)
+	}
+	if source != "pixels" || stars != 500 || hfr != 1.9 || guiding != 0.62 {
+		t.Errorf("metrics after analysis = %s %d %g %g", source, stars, hfr, guiding)
+	}
+	if n := analyzed(true); n != 1 {
+		t.Errorf("analyzed filter returned %d frames after the analysis, expected 1", n)
+	}
+}
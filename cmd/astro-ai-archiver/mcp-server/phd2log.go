@@ -0,0 +1,185 @@
+package mcpserver
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// phd2TimeLayout is the format of the time stamps in PHD2 guide logs, which
+// are written in the local time of the guiding computer
+const phd2TimeLayout = "2006-01-02 15:04:05"
+
+// isPHD2GuideLog reports whether a file name is a PHD2 guide log.
+func isPHD2GuideLog(name string) bool {
+	ok, _ := filepath.Match("PHD2_GuideLog_*.txt", name)
+	return ok
+}
+
+// guideSample is one guide step: the raw star offset from the lock position
+// in guide camera pixels. Time is in Unix seconds.
+type guideSample struct {
+	time    float64
+	ra, dec float64
+}
+
+// guideEvent is a dither or a settling state change
+type guideEvent struct {
+	time   float64
+	kind   string // dither, settle_start, settle_done or settle_failed
+	detail string
+}
+
+// guideSession is one "Guiding Begins ... Guiding Ends" section of a log
+type guideSession struct {
+	start, end time.Time
+	mount      string
+	pixelScale float64 // Arcseconds per guide camera pixel, 0 = unknown
+	samples    []guideSample
+	events     []guideEvent
+}
+
+// night is the local date of the evening the session started in.
+func (s *guideSession) night() string {
+	return s.start.Add(-12 * time.Hour).Format("2006-01-02")
+}
+
+// rms returns the RA, Dec and total RMS of the samples in arcseconds, or
+// false when the pixel scale is unknown. As in PHD2, RMS is the standard
+// deviation of the offsets.
+func (s *guideSession) rms() (ra, dec, total float64, ok bool) {
+	if s.pixelScale <= 0 || len(s.samples) == 0 {
+		return 0, 0, 0, false
+	}
+	var sumRA, sumRA2, sumDec, sumDec2 float64
+	for _, p := range s.samples {
+		sumRA += p.ra
+		sumRA2 += p.ra * p.ra
+		sumDec += p.dec
+		sumDec2 += p.dec * p.dec
+	}
+	n := float64(len(s.samples))
+	ra = stdDev(sumRA, sumRA2, n) * s.pixelScale
+	dec = stdDev(sumDec, sumDec2, n) * s.pixelScale
+	return ra, dec, math.Hypot(ra, dec), true
+}
+
+// stdDev returns the population standard deviation from running sums.
+func stdDev(sum, sumSquares, n float64) float64 {
+	mean := sum / n
+	return math.Sqrt(math.Max(0, sumSquares/n-mean*mean))
+}
+
+// parsePHD2Log reads the guide sessions of a PHD2 guide log. Calibration
+// sections, AO steps and dropped frames are skipped; sessions without
+// samples are left out. A session cut off by the end of the log ends at its
+// last sample.
+func parsePHD2Log(r io.Reader, loc *time.Location) ([]*guideSession, error) {
+	var sessions []*guideSession
+	var cur *guideSession
+	var columns map[string]int
+	var lastTime float64
+
+	finish := func() {
+		if cur != nil && len(cur.samples) > 0 {
+			if cur.end.IsZero() {
+				last := cur.samples[len(cur.samples)-1].time
+				cur.end = time.Unix(0, int64(last*1e9)).In(loc)
+			}
+			sessions = append(sessions, cur)
+		}
+		cur = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if rest, ok := strings.CutPrefix(line, "Guiding Begins at "); ok {
+			finish()
+			start, err := time.ParseInLocation(phd2TimeLayout, rest, loc)
+			if err != nil {
+				continue
+			}
+			cur = &guideSession{start: start}
+			columns = nil
+			lastTime = float64(start.Unix())
+			continue
+		}
+		if strings.HasPrefix(line, "Calibration Begins at ") {
+			finish()
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "Guiding Ends at "):
+			if end, err := time.ParseInLocation(phd2TimeLayout, strings.TrimPrefix(line, "Guiding Ends at "), loc); err == nil {
+				cur.end = end
+			}
+			finish()
+
+		case strings.HasPrefix(line, "Pixel scale = "):
+			value, _, _ := strings.Cut(strings.TrimPrefix(line, "Pixel scale = "), " ")
+			cur.pixelScale, _ = strconv.ParseFloat(value, 64)
+
+		case strings.HasPrefix(line, "Mount = "):
+			name := strings.TrimPrefix(line, "Mount = ")
+			if before, _, ok := strings.Cut(name, ", connected"); ok {
+				name = before
+			} else if before, _, ok := strings.Cut(name, ","); ok {
+				name = before
+			}
+			cur.mount = strings.TrimSpace(name)
+
+		case strings.HasPrefix(line, "Frame,Time,"):
+			columns = ninaColumns(strings.Split(line, ","))
+
+		case strings.HasPrefix(line, "INFO: DITHER"):
+			cur.events = append(cur.events, guideEvent{time: lastTime, kind: "dither", detail: strings.TrimPrefix(line, "INFO: ")})
+
+		case strings.HasPrefix(line, "INFO: SETTLING STATE CHANGE"):
+			kind := ""
+			switch {
+			case strings.Contains(line, "Settling started"):
+				kind = "settle_start"
+			case strings.Contains(line, "Settling complete"):
+				kind = "settle_done"
+			case strings.Contains(line, "Settling failed"):
+				kind = "settle_failed"
+			}
+			if kind != "" {
+				cur.events = append(cur.events, guideEvent{time: lastTime, kind: kind})
+			}
+
+		case columns != nil && line != "" && line[0] >= '0' && line[0] <= '9':
+			fields, err := csv.NewReader(strings.NewReader(line)).Read()
+			if err != nil {
+				continue
+			}
+			row := ninaRecord{columns: columns, fields: fields}
+			if !strings.EqualFold(row.str("mount"), "Mount") {
+				continue // AO step or dropped frame
+			}
+			offset, okTime := row.float("Time").(float64)
+			ra, okRA := row.float("RARawDistance").(float64)
+			dec, okDec := row.float("DECRawDistance").(float64)
+			if !okTime || !okRA || !okDec {
+				continue
+			}
+			lastTime = float64(cur.start.Unix()) + offset
+			cur.samples = append(cur.samples, guideSample{time: lastTime, ra: ra, dec: dec})
+		}
+	}
+	finish()
+
+	return sessions, scanner.Err()
+}
@@ -0,0 +1,119 @@
+package mcpserver
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/astrogo/fitsio"
+)
+
+// frameImage is the first plane of a frame's primary image as physical pixel
+// values (BZERO and BSCALE applied), stored row by row.
+type frameImage struct {
+	Width        int
+	Height       int
+	Pixels       []float32
+	BayerPattern string // BAYERPAT of colour sensors, empty for mono data
+	BottomUp     bool   // Rows are stored bottom-up (ROWORDER=BOTTOM-UP)
+}
+
+// loadFrameImage reads the primary image of a FITS file.
+func loadFrameImage(path string) (*frameImage, error) {
+	osFile, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer osFile.Close()
+
+	f, err := fitsio.Open(osFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open FITS file: %w", err)
+	}
+	defer f.Close()
+
+	img, ok := f.HDU(0).(fitsio.Image)
+	if !ok {
+		return nil, fmt.Errorf("primary HDU is not an image")
+	}
+	header := img.Header()
+
+	axes := header.Axes()
+	if len(axes) < 2 || axes[0] <= 0 || axes[1] <= 0 {
+		return nil, fmt.Errorf("primary HDU has no 2D image (NAXIS=%d)", len(axes))
+	}
+	width, height := axes[0], axes[1]
+
+	bitpix := header.Bitpix()
+	size := bitpix / 8
+	if size < 0 {
+		size = -size
+	}
+	raw := img.Raw()
+	if size == 0 || len(raw) < width*height*size {
+		return nil, fmt.Errorf("image data is truncated")
+	}
+
+	bzero, bscale := 0.0, 1.0
+	if v := getFloatHeader(header, "BZERO"); v != nil {
+		bzero = *v
+	}
+	if v := getFloatHeader(header, "BSCALE"); v != nil {
+		bscale = *v
+	}
+
+	// FITS data is big-endian
+	pixels := make([]float32, width*height)
+	for i := range pixels {
+		b := raw[i*size:]
+		var v float64
+		switch bitpix {
+		case 8:
+			v = float64(b[0])
+		case 16:
+			v = float64(int16(binary.BigEndian.Uint16(b)))
+		case 32:
+			v = float64(int32(binary.BigEndian.Uint32(b)))
+		case 64:
+			v = float64(int64(binary.BigEndian.Uint64(b)))
+		case -32:
+			v = float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+		case -64:
+			v = math.Float64frombits(binary.BigEndian.Uint64(b))
+		default:
+			return nil, fmt.Errorf("unsupported BITPIX %d", bitpix)
+		}
+		pixels[i] = float32(bzero + bscale*v)
+	}
+
+	return &frameImage{
+		Width:        width,
+		Height:       height,
+		Pixels:       pixels,
+		BayerPattern: strings.ToUpper(getStringHeader(header, "BAYERPAT")),
+		BottomUp:     strings.EqualFold(getStringHeader(header, "ROWORDER"), "BOTTOM-UP"),
+	}, nil
+}
+
+// luminance returns a single-channel view of the frame. Colour (Bayer) frames
+// are binned 2x2 so every output pixel averages one complete colour cell;
+// mono frames are returned unchanged.
+func (img *frameImage) luminance() *frameImage {
+	if img.BayerPattern == "" {
+		return img
+	}
+
+	w, h := img.Width/2, img.Height/2
+	out := make([]float32, w*h)
+	for y := 0; y < h; y++ {
+		top := 2 * y * img.Width
+		bottom := top + img.Width
+		for x := 0; x < w; x++ {
+			out[y*w+x] = (img.Pixels[top+2*x] + img.Pixels[top+2*x+1] +
+				img.Pixels[bottom+2*x] + img.Pixels[bottom+2*x+1]) / 4
+		}
+	}
+	return &frameImage{Width: w, Height: h, Pixels: out, BottomUp: img.BottomUp}
+}
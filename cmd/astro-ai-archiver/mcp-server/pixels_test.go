@@ -0,0 +1,90 @@
+package mcpserver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/astrogo/fitsio"
+)
+
+// writeTestFITS writes a 16-bit unsigned image (BZERO 32768) with the given
+// physical pixel values and extra header cards.
+func writeTestFITS(t *testing.T, width, height int, values []uint16, cards ...fitsio.Card) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "frame.fits")
+	w, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	f, err := fitsio.Create(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	img := fitsio.NewImage(16, []int{width, height})
+	defer img.Close()
+	if err := img.Header().Append(append([]fitsio.Card{{Name: "BZERO", Value: 32768}, {Name: "BSCALE", Value: 1}}, cards...)...); err != nil {
+		t.Fatal(err)
+	}
+
+	raw := make([]int16, len(values))
+	for i, v := range values {
+		raw[i] = int16(int32(v) - 32768)
+	}
+	if err := img.Write(&raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Write(img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFrameImageAppliesBZero(t *testing.T) {
+	values := []uint16{0, 100, 32768, 65535, 1000, 2000}
+	path := writeTestFITS(t, 3, 2, values)
+
+	img, err := loadFrameImage(path)
+	if err != nil {
+		t.Fatalf("loadFrameImage: %v", err)
+	}
+	if img.Width != 3 || img.Height != 2 {
+		t.Fatalf("size = %dx%d, expected 3x2", img.Width, img.Height)
+	}
+	for i, want := range values {
+		if img.Pixels[i] != float32(want) {
+			t.Errorf("pixel %d = %v, expected %d", i, img.Pixels[i], want)
+		}
+	}
+	if img.BayerPattern != "" {
+		t.Errorf("BayerPattern = %q, expected none", img.BayerPattern)
+	}
+}
+
+func TestFrameImageLuminanceBinsBayerCells(t *testing.T) {
+	values := []uint16{
+		100, 200, 10, 20,
+		300, 400, 30, 40,
+	}
+	path := writeTestFITS(t, 4, 2, values, fitsio.Card{Name: "BAYERPAT", Value: "rggb"})
+
+	img, err := loadFrameImage(path)
+	if err != nil {
+		t.Fatalf("loadFrameImage: %v", err)
+	}
+	if img.BayerPattern != "RGGB" {
+		t.Errorf("BayerPattern = %q, expected RGGB", img.BayerPattern)
+	}
+
+	lum := img.luminance()
+	if lum.Width != 2 || lum.Height != 1 {
+		t.Fatalf("luminance size = %dx%d, expected 2x1", lum.Width, lum.Height)
+	}
+	if lum.Pixels[0] != 250 || lum.Pixels[1] != 25 {
+		t.Errorf("luminance = %v, expected [250 25]", lum.Pixels)
+	}
+}
@@ -0,0 +1,294 @@
+package mcpserver
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/astro"
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+// planStep is the interval at which target altitudes are sampled over the night
+const planStep = 5 * time.Minute
+
+// planCandidate is a target considered for a night plan
+type planCandidate struct {
+	target   string
+	project  *tools.ProjectProgress
+	ra, dec  float64
+	position string
+	filters  []tools.PlannedFilter
+}
+
+// PlanNight lists the projects and archived targets that are above
+// req.MinAltitude during the astronomical darkness of a night, with their
+// observable window, Moon separation and the integration they still need.
+// Positions come from the indexed frames of a target, else from the bundled
+// catalog; everything is computed offline.
+func (d *Database) PlanNight(req tools.PlanRequest) (*tools.NightPlan, error) {
+	if req.Date == "" {
+		// Tonight: the evening date until local noon of the next day
+		req.Date = time.Now().Add(-12 * time.Hour).Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+		return nil, fmt.Errorf("invalid date %q: expected YYYY-MM-DD", req.Date)
+	}
+	lat, lon, ok := d.sessionSite(req.Latitude, req.Longitude)
+	if !ok {
+		return nil, fmt.Errorf("no site: pass latitude and longitude or configure site in the config")
+	}
+
+	plan := &tools.NightPlan{
+		Date:        req.Date,
+		Latitude:    lat,
+		Longitude:   lon,
+		MinAltitude: req.MinAltitude,
+		Targets:     []tools.PlannedTarget{},
+	}
+	start, end, ok := nightDarkness(req.Date, lat, lon)
+	if !ok {
+		return plan, nil
+	}
+	plan.DarkStart, plan.DarkEnd = start.Format(sessionTimeLayout), end.Format(sessionTimeLayout)
+	plan.DarkHours = roundHours(end.Sub(start).Hours())
+	plan.MoonIllumination = roundHundredths(astro.MoonIllumination(astro.JulianDay(start.Add(end.Sub(start) / 2))))
+
+	candidates, unresolved, err := d.planCandidates(req.IncludeArchive)
+	if err != nil {
+		return nil, err
+	}
+	plan.Unresolved = unresolved
+
+	for _, c := range candidates {
+		target, visible := planVisibility(c, lat, lon, req.MinAltitude, start, end)
+		if !visible {
+			plan.NotVisible++
+			continue
+		}
+		plan.Targets = append(plan.Targets, target)
+	}
+
+	// Projects by the integration they still need, then the rest of the
+	// archive by how long it can be imaged
+	sort.SliceStable(plan.Targets, func(i, j int) bool {
+		a, b := plan.Targets[i], plan.Targets[j]
+		if a.RemainingHours != b.RemainingHours {
+			return a.RemainingHours > b.RemainingHours
+		}
+		if (a.Project != "") != (b.Project != "") {
+			return a.Project != ""
+		}
+		if a.ObservableHours != b.ObservableHours {
+			return a.ObservableHours > b.ObservableHours
+		}
+		return strings.ToLower(a.Target) < strings.ToLower(b.Target)
+	})
+	if req.Limit > 0 && len(plan.Targets) > req.Limit {
+		plan.Targets = plan.Targets[:req.Limit]
+	}
+	return plan, nil
+}
+
+// planCandidates collects every project and, with includeArchive, every
+// archived target without a project. It also returns the targets whose
+// position is neither indexed nor in the catalog.
+func (d *Database) planCandidates(includeArchive bool) ([]*planCandidate, []string, error) {
+	positions, err := d.targetPositions()
+	if err != nil {
+		return nil, nil, err
+	}
+	done, err := d.targetIntegration()
+	if err != nil {
+		return nil, nil, err
+	}
+	projects, err := d.ProjectProgress("")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var candidates []*planCandidate
+	var unresolved []string
+	locate := func(c *planCandidate) bool {
+		if p, ok := positions[strings.ToLower(c.target)]; ok {
+			c.ra, c.dec, c.position = p[0], p[1], "archive"
+			return true
+		}
+		if obj, ok := astro.LookupObject(c.target); ok {
+			c.ra, c.dec, c.position = obj.RA, obj.Dec, "catalog"
+			return true
+		}
+		unresolved = append(unresolved, c.target)
+		return false
+	}
+
+	inProject := map[string]bool{}
+	for _, p := range projects {
+		inProject[strings.ToLower(p.Project.Target)] = true
+		c := &planCandidate{target: p.Project.Target, project: p}
+		for _, f := range p.Filters {
+			c.filters = append(c.filters, tools.PlannedFilter{
+				Filter:         f.Filter,
+				DoneHours:      f.DoneHours,
+				GoalHours:      f.GoalHours,
+				RemainingHours: f.RemainingHours,
+			})
+		}
+		sort.SliceStable(c.filters, func(i, j int) bool {
+			return c.filters[i].RemainingHours > c.filters[j].RemainingHours
+		})
+		if locate(c) {
+			candidates = append(candidates, c)
+		}
+	}
+
+	if includeArchive {
+		targets := make([]string, 0, len(done))
+		for key := range done {
+			if !inProject[key] {
+				targets = append(targets, key)
+			}
+		}
+		sort.Strings(targets)
+		for _, key := range targets {
+			t := done[key]
+			c := &planCandidate{target: t.name, filters: t.filters}
+			if locate(c) {
+				candidates = append(candidates, c)
+			}
+		}
+	}
+	return candidates, unresolved, nil
+}
+
+// targetPositions returns the mean indexed position (RA, Dec) of each target,
+// keyed by lower-case object name. RA is averaged across 0° when the frames
+// straddle it.
+func (d *Database) targetPositions() (map[string][2]float64, error) {
+	rows, err := d.db.Query(`
+		SELECT LOWER(f.object), AVG(s.ra), MAX(s.ra) - MIN(s.ra),
+		       AVG(CASE WHEN s.ra > 180 THEN s.ra - 360 ELSE s.ra END), AVG(s.dec)
+		FROM usable_files f
+		JOIN sky_index s ON s.file_id = f.id
+		WHERE f.object IS NOT NULL AND f.object != ''
+		GROUP BY LOWER(f.object)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read target positions: %w", err)
+	}
+	defer rows.Close()
+
+	positions := map[string][2]float64{}
+	for rows.Next() {
+		var name string
+		var ra, spread, wrapped, dec float64
+		if err := rows.Scan(&name, &ra, &spread, &wrapped, &dec); err != nil {
+			return nil, err
+		}
+		if spread > 180 {
+			ra = math.Mod(wrapped+360, 360)
+		}
+		positions[name] = [2]float64{ra, dec}
+	}
+	return positions, rows.Err()
+}
+
+// archivedTarget is the integration of an archived target per filter
+type archivedTarget struct {
+	name    string
+	filters []tools.PlannedFilter
+}
+
+// targetIntegration returns the integration of every archived target per
+// filter, keyed by lower-case object name. Like project progress, rejected
+// frames are left out and duplicate copies are counted once.
+func (d *Database) targetIntegration() (map[string]*archivedTarget, error) {
+	rows, err := d.db.Query(`
+		SELECT MAX(object), COALESCE(MAX(filter), ''), TOTAL(exposure)
+		FROM (
+			SELECT MAX(f.object) AS object, MAX(f.filter) AS filter, MAX(f.exposure) AS exposure
+			FROM usable_files f
+			WHERE f.object IS NOT NULL AND f.object != ''
+			GROUP BY COALESCE(f.hash, 'id:' || f.id)
+		)
+		GROUP BY LOWER(object), LOWER(filter)
+		ORDER BY TOTAL(exposure) DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum target integration: %w", err)
+	}
+	defer rows.Close()
+
+	targets := map[string]*archivedTarget{}
+	for rows.Next() {
+		var name, filter string
+		var seconds float64
+		if err := rows.Scan(&name, &filter, &seconds); err != nil {
+			return nil, err
+		}
+		key := strings.ToLower(name)
+		t := targets[key]
+		if t == nil {
+			t = &archivedTarget{name: name}
+			targets[key] = t
+		}
+		t.filters = append(t.filters, tools.PlannedFilter{Filter: filter, DoneHours: roundHours(seconds / 3600)})
+	}
+	return targets, rows.Err()
+}
+
+// planVisibility samples the altitude of a candidate over darkness and
+// returns its observable window, or false when it never reaches minAlt.
+func planVisibility(c *planCandidate, lat, lon, minAlt float64, start, end time.Time) (tools.PlannedTarget, bool) {
+	t := tools.PlannedTarget{
+		Target:   c.target,
+		RA:       roundHundredths(c.ra),
+		Dec:      roundHundredths(c.dec),
+		Position: c.position,
+		Filters:  c.filters,
+	}
+	if t.Filters == nil {
+		t.Filters = []tools.PlannedFilter{}
+	}
+	if c.project != nil {
+		t.Project = c.project.Project.Name
+		t.DoneHours = c.project.DoneHours
+		t.RemainingHours = c.project.RemainingHours
+	} else {
+		for _, f := range c.filters {
+			t.DoneHours += f.DoneHours
+		}
+		t.DoneHours = roundHours(t.DoneHours)
+	}
+
+	var first, last time.Time
+	maxAlt := math.Inf(-1)
+	observable := time.Duration(0)
+	for at := start; !at.After(end); at = at.Add(planStep) {
+		alt, _ := astro.Horizontal(c.ra, c.dec, lat, lon, astro.JulianDay(at))
+		maxAlt = math.Max(maxAlt, alt)
+		if alt < minAlt {
+			continue
+		}
+		if first.IsZero() {
+			first = at
+		}
+		last = at
+		observable += min(planStep, end.Sub(at))
+	}
+	if first.IsZero() {
+		return t, false
+	}
+
+	if last = last.Add(planStep); last.After(end) {
+		last = end
+	}
+	t.WindowStart, t.WindowEnd = first.Format(sessionTimeLayout), last.Format(sessionTimeLayout)
+	t.ObservableHours = roundHours(observable.Hours())
+	t.MaxAltitude = roundHundredths(maxAlt)
+	moonRA, moonDec, _ := astro.MoonPosition(astro.JulianDay(first.Add(last.Sub(first) / 2)))
+	t.MoonSeparation = roundHundredths(astro.AngularSeparation(c.ra, c.dec, moonRA, moonDec))
+	return t, true
+}
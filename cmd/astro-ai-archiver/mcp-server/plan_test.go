@@ -0,0 +1,85 @@
+package mcpserver
+
+import (
+	"testing"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+func TestPlanNight(t *testing.T) {
+	db, _ := newTestDatabase(t)
+
+	// Without a site there is nothing to plan for
+	if _, err := db.PlanNight(tools.PlanRequest{Date: "2025-03-20"}); err == nil {
+		t.Error("PlanNight without a site should fail")
+	}
+
+	coords := func(ra, dec float64) (*float64, *float64) { return &ra, &dec }
+	m42RA, m42Dec := coords(83.8, -5.4)
+	leoRA, leoDec := coords(170, 13)
+	m81RA, m81Dec := coords(148.9, 69.1)
+	frames := []*FITSFile{
+		{RelativePath: "m42/1.fits", Hash: "a1", Object: "M42", Filter: "Ha", Exposure: 1800, RA: m42RA, Dec: m42Dec},
+		{RelativePath: "m42/2.fits", Hash: "a2", Object: "M42", Filter: "Ha", Exposure: 1800, RA: m42RA, Dec: m42Dec},
+		{RelativePath: "leo/1.fits", Hash: "b1", Object: "Leo Triplet", Filter: "L", Exposure: 3600, RA: leoRA, Dec: leoDec},
+		{RelativePath: "m81/1.fits", Hash: "c1", Object: "M81", Filter: "L", Exposure: 3600, RA: m81RA, Dec: m81Dec},
+		{RelativePath: "blob/1.fits", Hash: "d1", Object: "Mystery Blob", Filter: "L", Exposure: 600},
+	}
+	insertFrames(t, db, frames...)
+	for _, p := range []tools.Project{
+		{Name: "Orion", Target: "M42", Goals: map[string]float64{"Ha": 2, "OIII": 3}},
+		{Name: "Sombrero", Target: "M104", Goals: map[string]float64{"L": 2}}, // No frames: catalog position
+	} {
+		if _, err := db.SaveProject(p); err != nil {
+			t.Fatalf("SaveProject: %v", err)
+		}
+	}
+
+	// Equator at the March equinox: dark from about 19:19 to 04:55 UTC, with
+	// local sidereal time running from about 7 h to 17 h
+	lat, lon := 0.0, 0.0
+	db.SetSite(&lat, &lon)
+	plan, err := db.PlanNight(tools.PlanRequest{Date: "2025-03-20", MinAltitude: 30, IncludeArchive: true})
+	if err != nil {
+		t.Fatalf("PlanNight: %v", err)
+	}
+	if plan.DarkStart[11:16] != "19:19" || plan.DarkEnd[11:16] != "04:55" {
+		t.Errorf("darkness = %s - %s", plan.DarkStart, plan.DarkEnd)
+	}
+	// M81 culminates at 21° from the equator; the blob has no position
+	if plan.NotVisible != 1 || len(plan.Unresolved) != 1 || plan.Unresolved[0] != "Mystery Blob" {
+		t.Errorf("not visible = %d, unresolved = %v", plan.NotVisible, plan.Unresolved)
+	}
+
+	var order []string
+	for _, target := range plan.Targets {
+		order = append(order, target.Target)
+	}
+	if len(order) != 3 || order[0] != "M42" || order[1] != "M104" || order[2] != "Leo Triplet" {
+		t.Fatalf("targets = %v, expected M42, M104, Leo Triplet", order)
+	}
+
+	// M42 sets through 30° four hours after transit, around 21:40 UTC
+	orion := plan.Targets[0]
+	if orion.Project != "Orion" || orion.Position != "archive" || orion.RemainingHours != 4 ||
+		orion.WindowStart[11:16] != "19:19" || orion.WindowEnd[11:13] != "21" ||
+		orion.ObservableHours < 2 || orion.ObservableHours > 2.6 {
+		t.Errorf("M42 = %+v", orion)
+	}
+	// Most needed filter first
+	if len(orion.Filters) != 2 || orion.Filters[0].Filter != "OIII" || orion.Filters[1].RemainingHours != 1 {
+		t.Errorf("M42 filters = %+v", orion.Filters)
+	}
+	if sombrero := plan.Targets[1]; sombrero.Position != "catalog" || sombrero.MaxAltitude < 75 {
+		t.Errorf("M104 = %+v", sombrero)
+	}
+	if leo := plan.Targets[2]; leo.Project != "" || leo.DoneHours != 1 || len(leo.Filters) != 1 {
+		t.Errorf("Leo Triplet = %+v", leo)
+	}
+
+	// Projects only, limited
+	plan, err = db.PlanNight(tools.PlanRequest{Date: "2025-03-20", MinAltitude: 30, Limit: 1})
+	if err != nil || len(plan.Targets) != 1 || plan.Targets[0].Target != "M42" {
+		t.Errorf("limited plan = %+v, %v", plan, err)
+	}
+}
@@ -0,0 +1,291 @@
+package mcpserver
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/astro"
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+// Preview rendering parameters
+const (
+	defaultPreviewSize  = 1024 // Longest side in pixels
+	maxPreviewSize      = 4096
+	jpegQuality         = 85
+	stfShadowsClip      = -2.8 // Shadows clipping point in normalized MADs below the median
+	stfTargetBackground = 0.25 // Target median brightness after the stretch
+)
+
+// RenderPreview returns an auto-stretched preview of a frame that fits within
+// size x size pixels, encoded as "jpeg" (default) or "png". Colour frames are
+// debayered. Previews are cached in the thumbs directory next to the database,
+// keyed by file hash, so they survive rescans and are renewed when the file
+// content changes.
+func (d *Database) RenderPreview(fileID int64, size int, format string) (*tools.Preview, error) {
+	file, err := d.GetFileByID(fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, fmt.Errorf("file not found: id %d", fileID)
+	}
+
+	if size <= 0 {
+		size = defaultPreviewSize
+	}
+	size = min(size, maxPreviewSize)
+
+	format = strings.ToLower(format)
+	switch format {
+	case "", "jpg", "jpeg":
+		format = "jpeg"
+	case "png":
+	default:
+		return nil, fmt.Errorf("unsupported preview format %q (use jpeg or png)", format)
+	}
+
+	preview := &tools.Preview{
+		FileID:   file.ID,
+		Path:     file.RelativePath,
+		MIMEType: "image/" + format,
+	}
+
+	key := file.Hash
+	if key == "" {
+		key = fmt.Sprintf("id%d-%d", file.ID, file.FileModTime)
+	}
+	cachePath := filepath.Join(filepath.Dir(d.filePath), "thumbs", fmt.Sprintf("%s-%d.%s", key, size, format))
+
+	if data, err := os.ReadFile(cachePath); err == nil {
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			preview.Data = data
+			preview.Width, preview.Height = cfg.Width, cfg.Height
+			preview.Cached = true
+			return preview, nil
+		}
+	}
+
+	frame, err := loadFrameImage(d.GetAbsolutePath(file.RelativePath))
+	if err != nil {
+		return nil, err
+	}
+	img := renderStretched(frame, size)
+
+	var buf bytes.Buffer
+	if format == "png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode preview: %w", err)
+	}
+
+	preview.Data = buf.Bytes()
+	preview.Width, preview.Height = img.Bounds().Dx(), img.Bounds().Dy()
+
+	// Caching is best effort; a failure only costs a re-render next time
+	if err := writeFileAtomic(cachePath, preview.Data); err != nil {
+		log.Warn().Err(err).Str("path", cachePath).Msg("Failed to cache preview")
+	}
+
+	return preview, nil
+}
+
+// ResolveFileID returns the id of the file with the given relative path, or 0
+// when the archive does not contain it.
+func (d *Database) ResolveFileID(relativePath string) (int64, error) {
+	file, err := d.GetFileByPath(relativePath)
+	if err != nil || file == nil {
+		return 0, err
+	}
+	return file.(*FITSFile).ID, nil
+}
+
+// renderStretched debayers, downsizes and stretches a frame into an 8-bit
+// image fitting within size x size pixels.
+func renderStretched(frame *frameImage, size int) image.Image {
+	planes, w, h := debayerSuperpixel(frame)
+
+	// Integer box binning keeps the preview fast and noise-free
+	factor := int(math.Ceil(float64(max(w, h)) / float64(size)))
+	if factor > 1 {
+		for i := range planes {
+			planes[i] = binPlane(planes[i], w, h, factor)
+		}
+		w, h = w/factor, h/factor
+	}
+
+	for _, p := range planes {
+		stretchPlane(p)
+	}
+
+	row := func(y int) int {
+		if frame.BottomUp {
+			return (h - 1 - y) * w
+		}
+		return y * w
+	}
+
+	if len(planes) == 1 {
+		img := image.NewGray(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			src := planes[0][row(y):]
+			for x := 0; x < w; x++ {
+				img.SetGray(x, y, color.Gray{Y: to8bit(src[x])})
+			}
+		}
+		return img
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		r, g, b := planes[0][row(y):], planes[1][row(y):], planes[2][row(y):]
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, color.RGBA{R: to8bit(r[x]), G: to8bit(g[x]), B: to8bit(b[x]), A: 255})
+		}
+	}
+	return img
+}
+
+// debayerSuperpixel splits a colour frame into R, G and B planes at half
+// resolution, one output pixel per 2x2 colour cell. Mono frames and unknown
+// patterns yield a single copy of the data.
+func debayerSuperpixel(frame *frameImage) ([][]float32, int, int) {
+	pattern := frame.BayerPattern
+	if len(pattern) != 4 || strings.Count(pattern, "G") != 2 || !strings.Contains(pattern, "R") || !strings.Contains(pattern, "B") {
+		mono := make([]float32, len(frame.Pixels))
+		copy(mono, frame.Pixels)
+		return [][]float32{mono}, frame.Width, frame.Height
+	}
+
+	// Offsets of the cell positions in pattern order: (0,0), (1,0), (0,1), (1,1)
+	offsets := [4]int{0, 1, frame.Width, frame.Width + 1}
+
+	w, h := frame.Width/2, frame.Height/2
+	r := make([]float32, w*h)
+	g := make([]float32, w*h)
+	b := make([]float32, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			base := 2*y*frame.Width + 2*x
+			i := y*w + x
+			for k, c := range pattern {
+				v := frame.Pixels[base+offsets[k]]
+				switch c {
+				case 'R':
+					r[i] = v
+				case 'G':
+					g[i] += v / 2
+				case 'B':
+					b[i] = v
+				}
+			}
+		}
+	}
+	return [][]float32{r, g, b}, w, h
+}
+
+// binPlane averages factor x factor blocks of a plane.
+func binPlane(p []float32, w, h, factor int) []float32 {
+	bw, bh := w/factor, h/factor
+	out := make([]float32, bw*bh)
+	scale := 1 / float32(factor*factor)
+	for y := 0; y < bh*factor; y++ {
+		src := p[y*w:]
+		dst := out[(y/factor)*bw:]
+		for x := 0; x < bw*factor; x++ {
+			dst[x/factor] += src[x] * scale
+		}
+	}
+	return out
+}
+
+// stretchPlane applies a screen transfer function auto-stretch in place: the
+// data is normalized to [0,1], the shadows are clipped a few noise levels
+// below the median, and a midtones transfer brings the median to
+// stfTargetBackground.
+func stretchPlane(p []float32) {
+	lo, hi := float32(math.Inf(1)), float32(math.Inf(-1))
+	for _, v := range p {
+		lo = min(lo, v)
+		hi = max(hi, v)
+	}
+	if hi <= lo {
+		for i := range p {
+			p[i] = 0
+		}
+		return
+	}
+
+	span := hi - lo
+	for i, v := range p {
+		p[i] = (v - lo) / span
+	}
+
+	med, madn := astro.BackgroundNoise(p)
+	c0 := math.Max(0, math.Min(1, med+stfShadowsClip*madn))
+	if c0 >= 1 {
+		c0 = 0
+	}
+	m := 0.5 // No stretch for featureless data
+	if med > c0 {
+		m = mtf(stfTargetBackground, (med-c0)/(1-c0))
+	}
+
+	for i, v := range p {
+		x := (float64(v) - c0) / (1 - c0)
+		p[i] = float32(mtf(m, math.Max(0, math.Min(1, x))))
+	}
+}
+
+// mtf is the midtones transfer function with balance m.
+func mtf(m, x float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	case x == m:
+		return 0.5
+	}
+	return (m - 1) * x / ((2*m-1)*x - m)
+}
+
+// to8bit converts a [0,1] value to a byte.
+func to8bit(v float32) uint8 {
+	return uint8(math.Round(float64(max(0, min(1, v))) * 255))
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place,
+// so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
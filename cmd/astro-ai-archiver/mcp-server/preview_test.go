@@ -0,0 +1,95 @@
+package mcpserver
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/astrogo/fitsio"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/astro"
+)
+
+func TestStretchPlaneMovesMedianToTarget(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	p := make([]float32, 100*100)
+	for i := range p {
+		p[i] = float32(1000 + rng.NormFloat64()*10)
+	}
+	p[0] = 60000 // a star
+
+	stretchPlane(p)
+
+	med, _ := astro.BackgroundNoise(p)
+	if math.Abs(med-stfTargetBackground) > 0.02 {
+		t.Errorf("stretched median = %.3f, expected ~%.2f", med, stfTargetBackground)
+	}
+	if p[0] != 1 {
+		t.Errorf("brightest pixel = %.3f, expected 1", p[0])
+	}
+}
+
+func TestDebayerSuperpixel(t *testing.T) {
+	frame := &frameImage{
+		Width:  2,
+		Height: 2,
+		Pixels: []float32{
+			10, 20, // G R
+			30, 40, // B G
+		},
+		BayerPattern: "GRBG",
+	}
+
+	planes, w, h := debayerSuperpixel(frame)
+	if len(planes) != 3 || w != 1 || h != 1 {
+		t.Fatalf("got %d planes of %dx%d, expected 3 of 1x1", len(planes), w, h)
+	}
+	if planes[0][0] != 20 || planes[1][0] != 25 || planes[2][0] != 30 {
+		t.Errorf("RGB = %v %v %v, expected 20 25 30", planes[0][0], planes[1][0], planes[2][0])
+	}
+}
+
+func TestRenderPreviewIsCached(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	values := make([]uint16, 64*48)
+	for i := range values {
+		values[i] = uint16(1000 + rng.Intn(50))
+	}
+	src := writeTestFITS(t, 64, 48, values, fitsio.Card{Name: "BAYERPAT", Value: "RGGB"})
+
+	db, dir := newTestDatabase(t)
+	if err := os.Rename(src, filepath.Join(dir, "frame.fits")); err != nil {
+		t.Fatal(err)
+	}
+	insertFrames(t, db, &FITSFile{
+		RelativePath: "frame.fits", Hash: "abc123", Object: "M31", Telescope: "RC8",
+		Exposure: 60, Filter: "L",
+	})
+	id, err := db.ResolveFileID("frame.fits")
+	if err != nil || id == 0 {
+		t.Fatalf("ResolveFileID = %d, %v", id, err)
+	}
+
+	first, err := db.RenderPreview(id, 16, "png")
+	if err != nil {
+		t.Fatalf("RenderPreview: %v", err)
+	}
+	// 64x48 debayered to 32x24, then binned by 2 to fit 16 pixels
+	if first.Width != 16 || first.Height != 12 || first.MIMEType != "image/png" || first.Cached {
+		t.Errorf("first preview = %dx%d %s cached=%v, expected 16x12 image/png uncached",
+			first.Width, first.Height, first.MIMEType, first.Cached)
+	}
+
+	second, err := db.RenderPreview(id, 16, "png")
+	if err != nil {
+		t.Fatalf("RenderPreview (cached): %v", err)
+	}
+	if !second.Cached || string(second.Data) != string(first.Data) {
+		t.Error("second preview was not served from the cache")
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".aaa", "thumbs", "abc123-16.png")); err != nil {
+		t.Errorf("cached preview file missing: %v", err)
+	}
+}
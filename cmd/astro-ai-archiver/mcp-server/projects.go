@@ -0,0 +1,305 @@
+package mcpserver
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+// Project sources
+const (
+	projectSourceTool   = "tool"
+	projectSourceConfig = "config"
+)
+
+// SaveProject creates a project or replaces the project with the same name,
+// including all of its goals.
+func (d *Database) SaveProject(p tools.Project) (*tools.Project, error) {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Target = strings.TrimSpace(p.Target)
+	if p.Name == "" || p.Target == "" {
+		return nil, fmt.Errorf("project name and target are required")
+	}
+	if len(p.Goals) == 0 {
+		return nil, fmt.Errorf("project %q has no goals", p.Name)
+	}
+	// Progress matches filters case-insensitively, so "Ha" and "HA" would
+	// both count the same frames
+	filters := map[string]string{}
+	for filter, hours := range p.Goals {
+		if strings.TrimSpace(filter) == "" || hours <= 0 {
+			return nil, fmt.Errorf("invalid goal %q: %g h (goals need a filter and a positive number of hours)", filter, hours)
+		}
+		if other, ok := filters[strings.ToLower(filter)]; ok {
+			return nil, fmt.Errorf("goals %q and %q are the same filter; filters are matched without regard to case", other, filter)
+		}
+		filters[strings.ToLower(filter)] = filter
+	}
+	if p.Source == "" {
+		p.Source = projectSourceTool
+	}
+
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO projects (name, target, telescope, camera, notes, source)
+		VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?)
+		ON CONFLICT(name) DO UPDATE SET
+			target = excluded.target,
+			telescope = excluded.telescope,
+			camera = excluded.camera,
+			notes = excluded.notes,
+			source = excluded.source
+	`, p.Name, p.Target, p.Telescope, p.Camera, p.Notes, p.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save project: %w", err)
+	}
+
+	var id int64
+	if err := tx.QueryRow("SELECT id FROM projects WHERE name = ?", p.Name).Scan(&id); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("DELETE FROM project_goals WHERE project_id = ?", id); err != nil {
+		return nil, err
+	}
+	for filter, hours := range p.Goals {
+		_, err := tx.Exec("INSERT INTO project_goals (project_id, filter, hours) VALUES (?, ?, ?)",
+			id, strings.TrimSpace(filter), hours)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save goal %s: %w", filter, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("project", p.Name).Str("target", p.Target).Int("goals", len(p.Goals)).Msg("Project saved")
+	return &p, nil
+}
+
+// DeleteProject removes a project and its goals. It returns false when no
+// project has that name.
+func (d *Database) DeleteProject(name string) (bool, error) {
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	result, err := d.db.Exec("DELETE FROM projects WHERE name = ?", name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete project: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n > 0 {
+		log.Info().Str("project", name).Msg("Project deleted")
+	}
+	return n > 0, nil
+}
+
+// SyncConfigProjects makes the config-file projects match the config: listed
+// projects are saved, and projects that came from the config but are no
+// longer listed are removed. Projects created with the tools are kept.
+func (d *Database) SyncConfigProjects(projects []ProjectConfig) error {
+	listed := map[string]bool{}
+	for _, pc := range projects {
+		goals := map[string]float64{}
+		for _, g := range pc.Goals {
+			goals[g.Filter] += g.Hours
+		}
+		_, err := d.SaveProject(tools.Project{
+			Name:      pc.Name,
+			Target:    pc.Target,
+			Telescope: pc.Telescope,
+			Camera:    pc.Camera,
+			Notes:     pc.Notes,
+			Goals:     goals,
+			Source:    projectSourceConfig,
+		})
+		if err != nil {
+			return err
+		}
+		listed[strings.TrimSpace(pc.Name)] = true
+	}
+
+	stale, err := d.loadProjects("")
+	if err != nil {
+		return err
+	}
+	for _, p := range stale {
+		if p.Source == projectSourceConfig && !listed[p.Name] {
+			if _, err := d.DeleteProject(p.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadProjects returns the project with the given name, or all projects
+// ordered by name when name is empty.
+func (d *Database) loadProjects(name string) ([]*tools.Project, error) {
+	query := `
+		SELECT p.id, p.name, p.target, COALESCE(p.telescope, ''), COALESCE(p.camera, ''),
+		       COALESCE(p.notes, ''), p.source, COALESCE(g.filter, ''), COALESCE(g.hours, 0)
+		FROM projects p
+		LEFT JOIN project_goals g ON g.project_id = p.id
+	`
+	var args []interface{}
+	if name != "" {
+		query += " WHERE p.name = ?"
+		args = append(args, name)
+	}
+	query += " ORDER BY p.name, g.filter"
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []*tools.Project
+	byID := map[int64]*tools.Project{}
+	for rows.Next() {
+		var id int64
+		var p tools.Project
+		var filter string
+		var hours float64
+		if err := rows.Scan(&id, &p.Name, &p.Target, &p.Telescope, &p.Camera, &p.Notes, &p.Source, &filter, &hours); err != nil {
+			return nil, err
+		}
+		project, ok := byID[id]
+		if !ok {
+			p.Goals = map[string]float64{}
+			project = &p
+			byID[id] = project
+			projects = append(projects, project)
+		}
+		if filter != "" {
+			project.Goals[filter] = hours
+		}
+	}
+	return projects, rows.Err()
+}
+
+// ProjectProgress returns the progress of the named project, or of all
+// projects when name is empty. Frames match on the exact target name and the
+// optional telescope and camera; rejected frames are left out and duplicate
+// copies of a frame (same hash) are counted once.
+func (d *Database) ProjectProgress(name string) ([]*tools.ProjectProgress, error) {
+	projects, err := d.loadProjects(name)
+	if err != nil {
+		return nil, err
+	}
+	if name != "" && len(projects) == 0 {
+		return nil, fmt.Errorf("project not found: %s", name)
+	}
+
+	result := make([]*tools.ProjectProgress, 0, len(projects))
+	for _, p := range projects {
+		progress, err := d.projectProgress(p)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, progress)
+	}
+	return result, nil
+}
+
+// projectProgress compares the integration of a project's frames with its goals.
+func (d *Database) projectProgress(p *tools.Project) (*tools.ProjectProgress, error) {
+	where := " WHERE f.object = ? COLLATE NOCASE"
+	args := []interface{}{p.Target}
+	if p.Telescope != "" {
+		where += " AND f.telescope LIKE ?"
+		args = append(args, "%"+p.Telescope+"%")
+	}
+	if p.Camera != "" {
+		where += " AND f.camera LIKE ?"
+		args = append(args, "%"+p.Camera+"%")
+	}
+
+	rows, err := d.db.Query(`
+		SELECT LOWER(filter), COUNT(*), TOTAL(exposure), COALESCE(MAX(observation_date), '')
+		FROM (
+			SELECT MAX(f.filter) AS filter, MAX(f.exposure) AS exposure, MAX(f.observation_date) AS observation_date
+			FROM usable_files f`+where+`
+			GROUP BY COALESCE(f.hash, 'id:' || f.id)
+		)
+		GROUP BY LOWER(filter)
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum project integration: %w", err)
+	}
+	defer rows.Close()
+
+	type done struct {
+		frames  int
+		seconds float64
+	}
+	byFilter := map[string]done{}
+	progress := &tools.ProjectProgress{Project: *p, Filters: []tools.FilterProgress{}}
+	for rows.Next() {
+		var filter, last sql.NullString
+		var dn done
+		if err := rows.Scan(&filter, &dn.frames, &dn.seconds, &last); err != nil {
+			return nil, err
+		}
+		byFilter[filter.String] = dn
+		if last.String > progress.LastImaged {
+			progress.LastImaged = last.String
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	filters := make([]string, 0, len(p.Goals))
+	for filter := range p.Goals {
+		filters = append(filters, filter)
+	}
+	sort.Strings(filters)
+
+	for _, filter := range filters {
+		goal := p.Goals[filter]
+		dn := byFilter[strings.ToLower(filter)]
+		hours := dn.seconds / 3600
+		fp := tools.FilterProgress{
+			Filter:         filter,
+			GoalHours:      goal,
+			DoneHours:      roundHours(hours),
+			RemainingHours: roundHours(math.Max(0, goal-hours)),
+			Frames:         dn.frames,
+			Percent:        math.Round(math.Min(100, 100*hours/goal)*10) / 10,
+		}
+		progress.Filters = append(progress.Filters, fp)
+		progress.GoalHours += goal
+		progress.DoneHours += math.Min(hours, goal)
+	}
+
+	progress.RemainingHours = roundHours(progress.GoalHours - progress.DoneHours)
+	if progress.GoalHours > 0 {
+		progress.Percent = math.Round(1000*progress.DoneHours/progress.GoalHours) / 10
+	}
+	progress.DoneHours = roundHours(progress.DoneHours)
+	return progress, nil
+}
+
+// roundHours rounds to hundredths of an hour for reporting.
+func roundHours(h float64) float64 {
+	return math.Round(h*100) / 100
+}
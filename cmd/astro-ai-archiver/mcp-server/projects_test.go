@@ -0,0 +1,63 @@
+package mcpserver
+
+import (
+	"testing"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+func TestProjectProgress(t *testing.T) {
+	db, _ := newTestDatabase(t)
+	insertNGC7000Scenario(t, db)
+
+	// Targets match without regard to case
+	_, err := db.SaveProject(tools.Project{
+		Name: "NGC7000 SHO", Target: "ngc7000",
+		Goals: map[string]float64{"Ha": 2, "OIII": 0.5, "SII": 1},
+	})
+	if err != nil {
+		t.Fatalf("SaveProject: %v", err)
+	}
+
+	progress, err := db.ProjectProgress("NGC7000 SHO")
+	if err != nil || len(progress) != 1 {
+		t.Fatalf("ProjectProgress = %v, %v", progress, err)
+	}
+	p := progress[0]
+	expected := map[string]tools.FilterProgress{
+		"Ha":   {Filter: "Ha", GoalHours: 2, DoneHours: 1.25, RemainingHours: 0.75, Frames: 3, Percent: 62.5},
+		"OIII": {Filter: "OIII", GoalHours: 0.5, DoneHours: 1, RemainingHours: 0, Frames: 1, Percent: 100},
+		"SII":  {Filter: "SII", GoalHours: 1, DoneHours: 0, RemainingHours: 1, Frames: 0, Percent: 0},
+	}
+	for _, fp := range p.Filters {
+		if fp != expected[fp.Filter] {
+			t.Errorf("progress of %s = %+v, expected %+v", fp.Filter, fp, expected[fp.Filter])
+		}
+	}
+	// Overall: 1.25 h Ha + 0.5 h OIII (capped) of 3.5 h
+	if p.GoalHours != 3.5 || p.DoneHours != 1.75 || p.RemainingHours != 1.75 || p.Percent != 50 {
+		t.Errorf("overall = %.2f of %.2f h (%.1f%%), %.2f h left", p.DoneHours, p.GoalHours, p.Percent, p.RemainingHours)
+	}
+
+	// Config projects replace their earlier version and disappear with the config entry
+	cfgProjects := []ProjectConfig{{Name: "M31 LRGB", Target: "M31", Goals: []GoalConfig{{Filter: "L", Hours: 4}}}}
+	if err := db.SyncConfigProjects(cfgProjects); err != nil {
+		t.Fatalf("SyncConfigProjects: %v", err)
+	}
+	if all, _ := db.ProjectProgress(""); len(all) != 2 || all[0].Project.Name != "M31 LRGB" || all[0].Filters[0].Frames != 1 {
+		t.Errorf("projects after config sync = %+v", all)
+	}
+	if err := db.SyncConfigProjects(nil); err != nil {
+		t.Fatalf("SyncConfigProjects: %v", err)
+	}
+	if all, _ := db.ProjectProgress(""); len(all) != 1 || all[0].Project.Name != "NGC7000 SHO" {
+		t.Errorf("tool project should survive config sync, got %+v", all)
+	}
+
+	if _, err := db.SaveProject(tools.Project{Name: "Empty", Target: "M1"}); err == nil {
+		t.Error("SaveProject without goals should fail")
+	}
+	if _, err := db.SaveProject(tools.Project{Name: "Twice", Target: "M1", Goals: map[string]float64{"Ha": 5, "HA": 5}}); err == nil {
+		t.Error("SaveProject with goals for Ha and HA should fail")
+	}
+}
@@ -0,0 +1,75 @@
+package prompts
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+func RegisterEfficiency(s *mcp.Server, db Database) {
+	s.AddPrompt(&mcp.Prompt{
+		Name:        "imaging_efficiency",
+		Description: "Review how well imaging sessions used the available astronomical darkness, with the efficiency report embedded",
+		Arguments: []*mcp.PromptArgument{
+			{
+				Name:        "date_from",
+				Description: "First observation date (YYYY-MM-DD, default: all)",
+				Required:    false,
+			},
+			{
+				Name:        "date_to",
+				Description: "Last observation date (YYYY-MM-DD, default: all)",
+				Required:    false,
+			},
+			{
+				Name:        "telescope",
+				Description: "Telescope name (partial match, default: all)",
+				Required:    false,
+			},
+		},
+	}, func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+
+		log.Info().Str("prompt", "imaging_efficiency").Interface("args", req.Params.Arguments).Msg("Prompt called")
+
+		request := tools.EfficiencyRequest{
+			DateFrom:  req.Params.Arguments["date_from"],
+			DateTo:    req.Params.Arguments["date_to"],
+			Telescope: req.Params.Arguments["telescope"],
+		}
+		report, err := db.EfficiencyReport(request)
+		if err != nil {
+			log.Error().Err(err).Str("prompt", "imaging_efficiency").Msg("Prompt failed")
+			return nil, fmt.Errorf("efficiency report failed: %w", err)
+		}
+
+		promptMsg := fmt.Sprintf(`Review how well my imaging sessions used the night.
+
+Below is the imaging efficiency report from the archive. Darkness is astronomical darkness (Sun more than 18° below the horizon) at each session's site; efficiency is the exposure time within darkness divided by the hours of darkness; gaps are pauses of more than 10 minutes between frames.
+
+%s
+Please provide:
+
+1. **Summary**: total darkness, integration and overall efficiency
+2. **Best and worst nights**: the most and least efficient sessions and what set them apart
+3. **Losses**: dark hours lost to gaps, and whether they look like weather (long gaps, early end) or equipment trouble (repeated short gaps)
+4. **Suggestions**: concrete ways to start earlier, run later or avoid the losses
+
+Use get_session for the gaps of individual sessions and conditions_report to check the weather on nights with large losses.`, tools.FormatEfficiencyReport(report))
+
+		return &mcp.GetPromptResult{
+			Description: "Imaging efficiency review",
+			Messages: []*mcp.PromptMessage{
+				{
+					Role: "user",
+					Content: &mcp.TextContent{
+						Text: promptMsg,
+					},
+				},
+			},
+		}, nil
+	})
+}
@@ -3,16 +3,20 @@ package prompts
 import (
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/rs/zerolog/log"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
 )
 
 // Database interface for prompts (same as tools)
 type Database interface {
 	GetFilePath() string
+	EfficiencyReport(req tools.EfficiencyRequest) (*tools.EfficiencyReport, error)
 }
 
 // RegisterAll registers all MCP prompts
 func RegisterAll(s *mcp.Server, db Database) {
 	RegisterYTD(s, db)
+	RegisterEfficiency(s, db)
 
-	log.Info().Int("prompts", 1).Msg("MCP prompts registered")
+	log.Info().Int("prompts", 2).Msg("MCP prompts registered")
 }
@@ -0,0 +1,82 @@
+package mcpserver
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestResetDatabaseConfirmation(t *testing.T) {
+	db, dir := newTestDatabase(t)
+
+	insertFrames(t, db,
+		&FITSFile{RelativePath: "a.fits", ObservationDate: sql.NullString{String: "2023-05-01", Valid: true}},
+		&FITSFile{RelativePath: "b.fits", ObservationDate: sql.NullString{String: "2024-05-01", Valid: true}},
+	)
+
+	server := newMCPServer(db, &Config{}, []string{dir})
+	reset := func(session *mcp.ClientSession, args map[string]interface{}) (map[string]interface{}, bool) {
+		t.Helper()
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "reset_database", Arguments: args})
+		if err != nil {
+			t.Fatalf("reset_database: %v", err)
+		}
+		structured, _ := result.StructuredContent.(map[string]interface{})
+		return structured, result.IsError
+	}
+	count := func() int64 {
+		n, _ := db.CountAllFiles()
+		return n
+	}
+
+	// Without elicitation a dry run hands out a token and deletes nothing
+	plain := newTestSession(t, server, nil)
+	dryRun, _ := reset(plain, map[string]interface{}{"year": 2023})
+	token, _ := dryRun["confirm_token"].(string)
+	if dryRun["status"] != "confirmation_required" || dryRun["records_to_delete"] != 1.0 || token == "" || count() != 2 {
+		t.Fatalf("dry run = %v with %d records left", dryRun, count())
+	}
+	// Another client cannot use the token, and does not use it up
+	other := newTestSession(t, server, nil)
+	if _, isError := reset(other, map[string]interface{}{"year": 2023, "confirm_token": token}); !isError || count() != 2 {
+		t.Errorf("token was accepted from another session")
+	}
+	if _, isError := reset(plain, map[string]interface{}{"confirm_token": token}); !isError || count() != 2 {
+		t.Errorf("token for 2023 deleted all years")
+	}
+	// A rejected token is used up
+	if _, isError := reset(plain, map[string]interface{}{"year": 2023, "confirm_token": token}); !isError {
+		t.Errorf("token was accepted twice")
+	}
+	dryRun, _ = reset(plain, map[string]interface{}{"year": 2023})
+	done, isError := reset(plain, map[string]interface{}{"year": 2023, "confirm_token": dryRun["confirm_token"]})
+	if isError || done["records_deleted"] != 1.0 || count() != 1 {
+		t.Fatalf("confirmed reset = %v with %d records left", done, count())
+	}
+	if backup, _ := done["backup"].(string); backup == "" {
+		t.Error("no backup reported")
+	} else if _, err := os.Stat(backup); err != nil {
+		t.Errorf("backup: %v", err)
+	}
+
+	// Clients with elicitation ask the user instead
+	answer := "decline"
+	asking := newTestSession(t, server, &mcp.ClientOptions{
+		ElicitationHandler: func(ctx context.Context, req *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+			return &mcp.ElicitResult{Action: answer, Content: map[string]any{"confirm": answer == "accept"}}, nil
+		},
+	})
+	if result, _ := reset(asking, nil); result["status"] != "cancelled" || count() != 1 {
+		t.Errorf("declined reset = %v with %d records left", result, count())
+	}
+	answer = "accept"
+	if result, _ := reset(asking, nil); result["status"] != "ok" || count() != 0 {
+		t.Errorf("accepted reset = %v with %d records left", result, count())
+	}
+	if result, _ := reset(asking, nil); result["status"] != "nothing_to_delete" {
+		t.Errorf("reset of an empty archive = %v", result)
+	}
+}
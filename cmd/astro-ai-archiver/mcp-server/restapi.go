@@ -0,0 +1,379 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+// restAPIPrefix is the path of version 1 of the REST API
+const restAPIPrefix = "/api/v1"
+
+// restRoute maps a REST endpoint to the MCP tool that serves it. Query
+// parameters, path parameters and a JSON request body become the tool's
+// arguments.
+type restRoute struct {
+	method  string
+	path    string            // Relative to restAPIPrefix, with {name} path parameters
+	tool    string            // MCP tool name
+	params  map[string]string // Path parameter → tool argument
+	summary string
+}
+
+var restRoutes = []restRoute{
+	{method: "GET", path: "/files", tool: "query_fits_archive", summary: "Search frames"},
+	{method: "GET", path: "/files/{path...}", tool: "get_file_details", params: map[string]string{"path": "file_path"}, summary: "Get one frame by path"},
+	{method: "GET", path: "/summary", tool: "get_archive_summary", summary: "Archive statistics"},
+	{method: "GET", path: "/sessions", tool: "list_sessions", summary: "List imaging sessions"},
+	{method: "GET", path: "/sessions/{id}", tool: "get_session", params: map[string]string{"id": "session_id"}, summary: "Get one imaging session"},
+	{method: "GET", path: "/scan", tool: "get_scan_status", summary: "Scan status"},
+	{method: "POST", path: "/scan", tool: "rescan_fits_directory", summary: "Start a scan"},
+	{method: "GET", path: "/sql", tool: "execute_sql_query", summary: "Run a read-only SQL query"},
+	{method: "POST", path: "/sql", tool: "execute_sql_query", summary: "Run a read-only SQL query"},
+}
+
+// restAPI serves the REST API. Requests are answered by calling the MCP
+// tools through an in-process client session, so REST clients get the same
+// validation, defaults and results as AI assistants.
+type restAPI struct {
+	session *mcp.ClientSession
+	tools   map[string]*mcp.Tool
+}
+
+// newRESTAPI connects an in-memory client session to the MCP server
+func newRESTAPI(ctx context.Context, server *mcp.Server) (*restAPI, error) {
+	session, err := connectInMemory(ctx, server, "astro-ai-archiver-rest", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect REST API to MCP server: %w", err)
+	}
+
+	api := &restAPI{session: session, tools: map[string]*mcp.Tool{}}
+	for tool, err := range session.Tools(ctx, nil) {
+		if err != nil {
+			session.Close()
+			return nil, fmt.Errorf("failed to list tools: %w", err)
+		}
+		api.tools[tool.Name] = tool
+	}
+	return api, nil
+}
+
+// connectInMemory connects a client named name to server within the process
+func connectInMemory(ctx context.Context, server *mcp.Server, name string, opts *mcp.ClientOptions) (*mcp.ClientSession, error) {
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	if _, err := server.Connect(ctx, serverTransport, nil); err != nil {
+		return nil, err
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: name, Version: Version}, opts)
+	return client.Connect(ctx, clientTransport, nil)
+}
+
+// Close ends the client session
+func (a *restAPI) Close() error {
+	return a.session.Close()
+}
+
+// register adds the REST endpoints and the OpenAPI document to mux
+func (a *restAPI) register(mux *http.ServeMux) {
+	for _, route := range restRoutes {
+		if a.tools[route.tool] == nil {
+			// Left out by the tool policy
+			mux.HandleFunc(route.method+" "+restAPIPrefix+route.path, func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusForbidden, map[string]string{"error": "tool " + route.tool + " is disabled on this server"})
+			})
+			continue
+		}
+		mux.HandleFunc(route.method+" "+restAPIPrefix+route.path, a.handler(route))
+	}
+	mux.HandleFunc("GET "+restAPIPrefix+"/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, a.openAPI())
+	})
+	mux.HandleFunc(restAPIPrefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown endpoint: " + r.Method + " " + r.URL.Path})
+	})
+}
+
+// handler calls the route's tool with the request's arguments and writes its
+// structured result
+func (a *restAPI) handler(route restRoute) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		args, err := a.arguments(route, r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Debug().Str("method", r.Method).Str("path", r.URL.Path).Str("tool", route.tool).Msg("REST request")
+
+		result, err := a.session.CallTool(r.Context(), &mcp.CallToolParams{Name: route.tool, Arguments: args})
+		if err != nil {
+			// Arguments the tool's input schema rejects
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if result.IsError {
+			message := toolText(result)
+			status := http.StatusBadRequest
+			if strings.Contains(message, "not found") {
+				status = http.StatusNotFound
+			}
+			writeJSON(w, status, map[string]string{"error": message})
+			return
+		}
+		if result.StructuredContent == nil {
+			writeJSON(w, http.StatusOK, map[string]string{"message": toolText(result)})
+			return
+		}
+		writeJSON(w, http.StatusOK, result.StructuredContent)
+	}
+}
+
+// arguments collects the tool arguments of a request: the JSON body of POST
+// requests, then query and path parameters converted to the types of the
+// tool's input schema.
+func (a *restAPI) arguments(route restRoute, r *http.Request) (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	if r.Method == http.MethodPost {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			return nil, err
+		}
+		if len(strings.TrimSpace(string(body))) > 0 {
+			if err := json.Unmarshal(body, &args); err != nil {
+				return nil, fmt.Errorf("request body must be a JSON object: %w", err)
+			}
+		}
+	}
+
+	properties := toolProperties(a.tools[route.tool])
+	set := func(name, value string) error {
+		property, ok := properties[name]
+		if !ok {
+			return fmt.Errorf("unknown parameter %q", name)
+		}
+		v, err := parseParameter(value, property)
+		if err != nil {
+			return fmt.Errorf("parameter %q: %w", name, err)
+		}
+		args[name] = v
+		return nil
+	}
+	for name, values := range r.URL.Query() {
+		if err := set(name, values[len(values)-1]); err != nil {
+			return nil, err
+		}
+	}
+	for param, name := range route.params {
+		if err := set(name, r.PathValue(param)); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// toolProperties returns the input schema properties of a tool
+func toolProperties(tool *mcp.Tool) map[string]interface{} {
+	if tool == nil {
+		return nil
+	}
+	schema, _ := tool.InputSchema.(map[string]interface{})
+	properties, _ := schema["properties"].(map[string]interface{})
+	return properties
+}
+
+// parseParameter converts a query or path parameter to the JSON type of its
+// schema property. Properties that accept several types, such as the
+// coordinates of cone_search, get a number when the value is one. A property
+// that is not a schema object, such as a boolean schema, is passed as a string.
+func parseParameter(value string, property interface{}) (interface{}, error) {
+	prop, ok := property.(map[string]interface{})
+	if !ok {
+		return value, nil
+	}
+	var types []string
+	switch t := prop["type"].(type) {
+	case string:
+		types = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+
+	for _, t := range types {
+		switch t {
+		case "number":
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				return f, nil
+			} else if len(types) == 1 {
+				return nil, fmt.Errorf("%q is not a number", value)
+			}
+		case "integer":
+			if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return i, nil
+			} else if len(types) == 1 {
+				return nil, fmt.Errorf("%q is not an integer", value)
+			}
+		case "boolean":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a boolean", value)
+			}
+			return b, nil
+		case "array":
+			items := []interface{}{}
+			for _, s := range strings.Split(value, ",") {
+				items = append(items, strings.TrimSpace(s))
+			}
+			return items, nil
+		}
+	}
+	return value, nil
+}
+
+// toolText joins the text content of a tool result
+func toolText(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, c := range result.Content {
+		if text, ok := c.(*mcp.TextContent); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Error().Err(err).Msg("Failed to write REST response")
+	}
+}
+
+// openAPI builds the OpenAPI 3 document of the REST API from the routes and
+// the input schemas of their tools, so it always matches what is served.
+func (a *restAPI) openAPI() map[string]interface{} {
+	errorResponse := map[string]interface{}{
+		"description": "Invalid request or tool error",
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"},
+			},
+		},
+	}
+
+	paths := map[string]interface{}{}
+	for _, route := range restRoutes {
+		tool := a.tools[route.tool]
+		if tool == nil {
+			continue
+		}
+		path := strings.ReplaceAll(route.path, "...}", "}")
+		properties := toolProperties(tool)
+
+		fromPath := map[string]string{}
+		for param, name := range route.params {
+			fromPath[name] = param
+		}
+		names := make([]string, 0, len(properties))
+		for name := range properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var parameters []interface{}
+		for _, name := range names {
+			property, _ := properties[name].(map[string]interface{})
+			parameter := map[string]interface{}{
+				"name":        name,
+				"in":          "query",
+				"description": property["description"],
+				"schema":      openAPISchema(property),
+			}
+			if param, ok := fromPath[name]; ok {
+				parameter["name"], parameter["in"], parameter["required"] = param, "path", true
+			} else if route.method == http.MethodPost {
+				continue // Taken from the request body
+			}
+			parameters = append(parameters, parameter)
+		}
+
+		operation := map[string]interface{}{
+			"operationId": strings.ToLower(route.method) + "_" + route.tool,
+			"summary":     route.summary,
+			"description": tool.Description + "\n\nServed by the MCP tool `" + route.tool + "`.",
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "Structured result of the tool",
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{"schema": map[string]interface{}{"type": "object"}},
+					},
+				},
+				"400": errorResponse,
+				"404": errorResponse,
+			},
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if route.method == http.MethodPost {
+			operation["requestBody"] = map[string]interface{}{
+				"required": false,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": tool.InputSchema},
+				},
+			}
+		}
+
+		item, _ := paths[restAPIPrefix+path].(map[string]interface{})
+		if item == nil {
+			item = map[string]interface{}{}
+			paths[restAPIPrefix+path] = item
+		}
+		item[strings.ToLower(route.method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Astro AI Archiver REST API",
+			"version":     Version,
+			"description": "JSON access to the FITS archive for clients that do not speak MCP. Every endpoint is served by an MCP tool of the same server.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"error": map[string]interface{}{"type": "string"}},
+				},
+			},
+		},
+	}
+}
+
+// openAPISchema converts a tool property to an OpenAPI 3.0 parameter schema,
+// which has no type lists: properties with several types become strings.
+func openAPISchema(property map[string]interface{}) map[string]interface{} {
+	schema := map[string]interface{}{}
+	for _, key := range []string{"type", "enum", "default", "items"} {
+		if v, ok := property[key]; ok {
+			schema[key] = v
+		}
+	}
+	if _, ok := schema["type"].(string); !ok {
+		schema["type"] = "string"
+	}
+	return schema
+}
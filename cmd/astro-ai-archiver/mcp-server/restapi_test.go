@@ -0,0 +1,189 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+func TestRESTAPI(t *testing.T) {
+	db, dir := newTestDatabase(t)
+
+	insertFrames(t, db,
+		&FITSFile{RelativePath: "m31/l1.fits", Hash: "a", Object: "M31", Filter: "L", Exposure: 120},
+		&FITSFile{RelativePath: "m31/l2.fits", Hash: "b", Object: "M31", Filter: "L", Exposure: 120},
+		&FITSFile{RelativePath: "m42/l1.fits", Hash: "c", Object: "M42", Filter: "L", Exposure: 30},
+	)
+
+	server := newMCPServer(db, &Config{}, []string{dir})
+	api, err := newRESTAPI(context.Background(), server)
+	if err != nil {
+		t.Fatalf("newRESTAPI: %v", err)
+	}
+	defer api.Close()
+
+	mux := http.NewServeMux()
+	api.register(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	call := func(method, path, body string) (int, map[string]interface{}) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("%s %s: invalid JSON: %v", method, path, err)
+		}
+		return resp.StatusCode, result
+	}
+
+	// Query parameters get the types of the tool's input schema
+	status, result := call("GET", "/api/v1/files?target=M31&limit=1", "")
+	if status != http.StatusOK || result["count"] != 1.0 || result["limit"] != 1.0 {
+		t.Errorf("GET /files = %d %v", status, result)
+	}
+	if status, result = call("GET", "/api/v1/files?limit=many", ""); status != http.StatusBadRequest {
+		t.Errorf("GET /files with a bad limit = %d %v", status, result)
+	}
+	if status, result = call("GET", "/api/v1/files?colour=red", ""); status != http.StatusBadRequest {
+		t.Errorf("GET /files with an unknown parameter = %d %v", status, result)
+	}
+
+	status, result = call("GET", "/api/v1/files/m31/l2.fits", "")
+	if file, _ := result["file"].(map[string]interface{}); status != http.StatusOK || file == nil {
+		t.Errorf("GET /files/{path} = %d %v", status, result)
+	}
+	if status, result = call("GET", "/api/v1/files/missing.fits", ""); status != http.StatusNotFound {
+		t.Errorf("GET /files/{path} of a missing file = %d %v", status, result)
+	}
+
+	status, result = call("GET", "/api/v1/summary", "")
+	if summary, _ := result["summary"].(map[string]interface{}); status != http.StatusOK || summary["total_files"] != 3.0 {
+		t.Errorf("GET /summary = %d %v", status, result)
+	}
+
+	status, result = call("POST", "/api/v1/sql", `{"query": "SELECT COUNT(*) AS n FROM fits_files"}`)
+	if status != http.StatusOK {
+		t.Errorf("POST /sql = %d %v", status, result)
+	}
+	if status, result = call("GET", "/api/v1/sql?query=DELETE%20FROM%20fits_files", ""); status != http.StatusBadRequest {
+		t.Errorf("GET /sql with a DELETE = %d %v", status, result)
+	}
+
+	// A scan runs in the background; the status endpoint tells when it is done
+	if status, result = call("POST", "/api/v1/scan", `{"force": true}`); status != http.StatusOK || result["status"] != "started" {
+		t.Fatalf("POST /scan = %d %v", status, result)
+	}
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, result = call("GET", "/api/v1/scan", ""); result["scanning"] == false {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("scan did not finish")
+		}
+	}
+
+	if status, _ = call("GET", "/api/v1/nothing", ""); status != http.StatusNotFound {
+		t.Errorf("GET of an unknown endpoint = %d", status)
+	}
+
+	// The OpenAPI document lists every route with the tool's parameters
+	status, result = call("GET", "/api/v1/openapi.json", "")
+	paths, _ := result["paths"].(map[string]interface{})
+	if status != http.StatusOK || len(paths) != 7 {
+		t.Fatalf("GET /openapi.json = %d with %d paths", status, len(paths))
+	}
+	session, _ := paths["/api/v1/sessions/{id}"].(map[string]interface{})
+	get, _ := session["get"].(map[string]interface{})
+	params, _ := get["parameters"].([]interface{})
+	if len(params) != 1 || params[0].(map[string]interface{})["in"] != "path" {
+		t.Errorf("parameters of GET /sessions/{id} = %v", params)
+	}
+}
+
+func TestRESTAPIToolPolicy(t *testing.T) {
+	db, dir := newTestDatabase(t)
+
+	// A read-only server, narrowed further as for an API key
+	cfg := &Config{}
+	cfg.Tools.ReadOnly = true
+	server := newMCPServer(db, cfg, []string{dir}, tools.ToolPolicy{Deny: []string{"execute_sql_query"}})
+	api, err := newRESTAPI(context.Background(), server)
+	if err != nil {
+		t.Fatalf("newRESTAPI: %v", err)
+	}
+	defer api.Close()
+
+	for _, name := range []string{"reset_database", "rescan_fits_directory", "execute_sql_query"} {
+		if api.tools[name] != nil {
+			t.Errorf("%s is registered on a read-only server", name)
+		}
+	}
+
+	mux := http.NewServeMux()
+	api.register(mux)
+	for _, tc := range []struct {
+		method, path string
+		status       int
+	}{
+		{"GET", "/api/v1/summary", http.StatusOK},
+		{"POST", "/api/v1/scan", http.StatusForbidden},
+		{"GET", "/api/v1/sql?query=SELECT%201", http.StatusForbidden},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != tc.status {
+			t.Errorf("%s %s = %d, want %d", tc.method, tc.path, rec.Code, tc.status)
+		}
+	}
+
+	// get_configuration reports the policy
+	result, err := api.session.CallTool(context.Background(), &mcp.CallToolParams{Name: "get_configuration"})
+	if err != nil {
+		t.Fatalf("get_configuration: %v", err)
+	}
+	config, _ := result.StructuredContent.(map[string]interface{})
+	access, _ := config["tools"].(map[string]interface{})
+	disabled, _ := access["disabled"].([]interface{})
+	if access["read_only"] != true || len(disabled) != 9 {
+		t.Errorf("get_configuration tools = %v", access)
+	}
+}
+
+func TestParseParameter(t *testing.T) {
+	tests := []struct {
+		value    string
+		property interface{}
+		want     interface{}
+	}{
+		{"12.5", map[string]interface{}{"type": "number"}, 12.5},
+		{"10", map[string]interface{}{"type": "integer"}, int64(10)},
+		{"true", map[string]interface{}{"type": "boolean"}, true},
+		{"M31", map[string]interface{}{"type": []interface{}{"number", "string"}}, "M31"},
+		// A boolean schema accepts anything and has no type to convert to
+		{"M31", true, "M31"},
+		{"M31", nil, "M31"},
+	}
+	for _, tt := range tests {
+		got, err := parseParameter(tt.value, tt.property)
+		if err != nil || got != tt.want {
+			t.Errorf("parseParameter(%q, %v) = %v, %v; expected %v", tt.value, tt.property, got, err, tt.want)
+		}
+	}
+	if _, err := parseParameter("ten", map[string]interface{}{"type": "integer"}); err == nil {
+		t.Error("parseParameter accepted a non-integer")
+	}
+}
@@ -14,6 +14,9 @@ import (
 
 	"github.com/astrogo/fitsio"
 	"github.com/rs/zerolog/log"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/astro"
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
 )
 
 // Scanner handles scanning directories for FITS files
@@ -24,14 +27,19 @@ type Scanner struct {
 	force       bool
 	workers     int
 	commonNames map[string]string // ASIAIR common-name → catalog designation (config + defaults)
+	skipDirs    map[string]bool   // Absolute directories the archive writes itself
 
 	// Statistics
-	scanned  atomic.Int64
-	added    atomic.Int64
-	updated  atomic.Int64
-	skipped  atomic.Int64
-	errors   []string
-	errorsMu sync.Mutex
+	scanned     atomic.Int64
+	added       atomic.Int64
+	updated     atomic.Int64
+	skipped     atomic.Int64
+	calibration atomic.Int64 // Calibration frames indexed
+	errors      []string
+	errorsMu    sync.Mutex
+
+	ninaFiles []string // N.I.N.A. Session Metadata CSVs, imported after the walk
+	guideLogs []string // PHD2 guide logs, imported after the walk
 
 	// Limit for testing
 	limit     int64
@@ -66,6 +74,18 @@ func NewScanner(db *Database, directories []string, recursive, force bool, worke
 		mergedNames[k] = v
 	}
 
+	// The database directory (with its backups) and the export root are
+	// written by the archive itself. When they sit inside a scan directory,
+	// exported stacking sets and Siril outputs must not come back as lights.
+	skipDirs := map[string]bool{}
+	if db != nil {
+		for _, dir := range []string{filepath.Dir(db.filePath), db.exportRoot()} {
+			if abs, err := filepath.Abs(dir); err == nil {
+				skipDirs[abs] = true
+			}
+		}
+	}
+
 	return &Scanner{
 		db:          db,
 		baseDirs:    directories,
@@ -73,6 +93,7 @@ func NewScanner(db *Database, directories []string, recursive, force bool, worke
 		force:       force,
 		workers:     workers,
 		commonNames: mergedNames,
+		skipDirs:    skipDirs,
 		errors:      make([]string, 0),
 		limit:       -1, // Limit to 25 files for testing
 		batchSize:   50, // Process in batches of 50 for better performance
@@ -111,6 +132,31 @@ func (s *Scanner) Scan() (*ScanResult, error) {
 		}
 	}
 
+	// Frame metadata from N.I.N.A. needs the frames to be indexed first.
+	// Unchanged CSVs are only read again when frames were added, as those
+	// may be listed in them.
+	ninaFrames := 0
+	reimport := s.force || s.added.Load() > 0
+	for _, file := range s.ninaFiles {
+		n, err := s.db.ImportNINAFile(file, reimport)
+		if err != nil {
+			s.addError(fmt.Sprintf("failed to import %s: %v", file, err))
+			continue
+		}
+		ninaFrames += n
+	}
+
+	// Guide logs found in the walk and in the configured log directories;
+	// new frames are correlated with logs imported earlier
+	guide, err := s.db.ImportGuideLogs(s.guideLogs)
+	if err != nil {
+		s.addError(fmt.Sprintf("failed to import guide logs: %v", err))
+		guide = &tools.GuideImportResult{}
+	}
+	for _, e := range guide.Errors {
+		s.addError(e)
+	}
+
 	duration := time.Since(startTime)
 
 	result := &ScanResult{
@@ -118,6 +164,9 @@ func (s *Scanner) Scan() (*ScanResult, error) {
 		FilesAdded:   int(s.added.Load()),
 		FilesUpdated: int(s.updated.Load()),
 		FilesSkipped: int(s.skipped.Load()),
+		Calibration:  int(s.calibration.Load()),
+		NINAFrames:   ninaFrames,
+		GuideLogs:    guide.Logs,
 		Errors:       s.errors,
 		Duration:     duration,
 	}
@@ -127,13 +176,61 @@ func (s *Scanner) Scan() (*ScanResult, error) {
 		Int("added", result.FilesAdded).
 		Int("updated", result.FilesUpdated).
 		Int("skipped", result.FilesSkipped).
+		Int("calibration", result.Calibration).
+		Int("nina_frames", result.NINAFrames).
+		Int("guide_logs", result.GuideLogs).
 		Int("errors", len(result.Errors)).
 		Dur("duration", duration).
 		Msg("Scan completed")
 
+	if _, _, err := s.db.RefreshSessions(); err != nil {
+		log.Error().Err(err).Msg("Session refresh after scan failed")
+	}
+	if _, err := s.db.RefreshEphemeris(); err != nil {
+		log.Error().Err(err).Msg("Ephemeris refresh after scan failed")
+	}
+
+	// Optional quality analysis of new and changed frames
+	if s.db.analyzeOnScan {
+		if _, err := s.db.AnalyzeFrames(nil, false, 0); err != nil {
+			log.Error().Err(err).Msg("Frame analysis after scan failed")
+		}
+	}
+
 	return result, nil
 }
 
+// CountFiles returns the number of FITS files a scan will visit, for
+// progress reporting. Unreadable directories are left out.
+func (s *Scanner) CountFiles() int {
+	count := 0
+	var walk func(dir string)
+	walk = func(dir string) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				if s.recursive && !s.isSkippedDir(filepath.Join(dir, entry.Name())) {
+					walk(filepath.Join(dir, entry.Name()))
+				}
+			} else if s.isFITSFile(entry.Name()) {
+				count++
+			}
+		}
+	}
+	for _, dir := range s.baseDirs {
+		walk(dir)
+	}
+	return count
+}
+
+// Scanned returns the number of FITS files visited so far by a running scan
+func (s *Scanner) Scanned() int {
+	return int(s.scanned.Load())
+}
+
 // walkDirectory recursively walks the directory tree
 func (s *Scanner) walkDirectory(dir string) error {
 	entries, err := os.ReadDir(dir)
@@ -168,9 +265,13 @@ func (s *Scanner) walkDirectory(dir string) error {
 		fullPath := filepath.Join(dir, entry.Name())
 
 		if entry.IsDir() {
-			if s.recursive && entry.Name() != "." && entry.Name() != ".." {
+			if s.recursive && entry.Name() != "." && entry.Name() != ".." && !s.isSkippedDir(fullPath) {
 				s.walkDirectory(fullPath)
 			}
+		} else if isPHD2GuideLog(entry.Name()) {
+			s.guideLogs = append(s.guideLogs, fullPath)
+		} else if isNINAFile(entry.Name()) {
+			s.ninaFiles = append(s.ninaFiles, fullPath)
 		} else if s.isFITSFile(entry.Name()) {
 			s.processed.Add(1)
 			fileChan <- fullPath
@@ -188,6 +289,14 @@ func (s *Scanner) walkDirectory(dir string) error {
 	return nil
 }
 
+// isSkippedDir reports whether a subdirectory is one the archive writes
+// itself. Scan directories are always scanned, even when the database sits
+// in one of them.
+func (s *Scanner) isSkippedDir(path string) bool {
+	abs, err := filepath.Abs(path)
+	return err == nil && s.skipDirs[abs]
+}
+
 // isFITSFile checks if a file has a FITS extension
 func (s *Scanner) isFITSFile(filename string) bool {
 	ext := strings.ToLower(filepath.Ext(filename))
@@ -227,6 +336,17 @@ func (s *Scanner) processFile(filePath string) {
 				log.Debug().Str("file", relPath).Msg("Skipped (unchanged)")
 				return
 			}
+		} else {
+			calibrationModTime, err := s.db.calibrationModTime(relPath)
+			if err != nil {
+				s.addError(fmt.Sprintf("database error for %s: %v", relPath, err))
+				return
+			}
+			if calibrationModTime == fileInfo.ModTime().Unix() {
+				s.skipped.Add(1)
+				log.Debug().Str("file", relPath).Msg("Skipped (unchanged calibration frame)")
+				return
+			}
 		}
 	}
 
@@ -237,8 +357,17 @@ func (s *Scanner) processFile(filePath string) {
 		return
 	}
 
-	// Skip non-LIGHT frames (calibration files)
+	// Calibration frames go to their own index; other non-LIGHT frames are skipped
 	if fitsFile.ImageType != "LIGHT" {
+		if frameType, master, ok := calibrationFrameType(fitsFile.ImageType); ok {
+			if err := s.db.InsertOrUpdateCalibration(fitsFile, frameType, master); err != nil {
+				s.addError(fmt.Sprintf("failed to index calibration frame %s: %v", relPath, err))
+				return
+			}
+			s.calibration.Add(1)
+			log.Debug().Str("file", relPath).Str("type", frameType).Bool("master", master).Msg("Indexed calibration frame")
+			return
+		}
 		s.skipped.Add(1)
 		log.Debug().Str("file", relPath).Str("type", fitsFile.ImageType).Msg("Skipped (not a light frame)")
 		return
@@ -314,39 +443,42 @@ func (s *Scanner) extractMetadata(filePath, relPath string, fileInfo os.FileInfo
 	// Try multiple common variations for each field
 
 	// Object/Target
-	file.Object = s.normalizeTarget(s.getStringHeader(header, "OBJECT", "TARGET", "OBJNAME"))
+	file.Object = s.normalizeTarget(getStringHeader(header, "OBJECT", "TARGET", "OBJNAME"))
 
 	// RA/DEC
-	file.RA = s.getFloatHeader(header, "RA", "OBJCTRA", "RA_OBJ")
-	file.Dec = s.getFloatHeader(header, "DEC", "OBJCTDEC", "DEC_OBJ")
+	file.RA = getFloatHeader(header, "RA", "OBJCTRA", "RA_OBJ")
+	file.Dec = getFloatHeader(header, "DEC", "OBJCTDEC", "DEC_OBJ")
+
+	// Plate-solved field geometry (only present when the frame was solved)
+	file.WCS = s.extractWCS(header)
 
 	// Telescope
-	file.Telescope = s.getStringHeader(header, "TELESCOP", "TELESCOPE", "SCOPE")
+	file.Telescope = getStringHeader(header, "TELESCOP", "TELESCOPE", "SCOPE")
 
 	// Focal length
-	file.FocalLength = s.getFloatHeader(header, "FOCALLEN", "FOCAL", "FOCAL_LENGTH")
+	file.FocalLength = getFloatHeader(header, "FOCALLEN", "FOCAL", "FOCAL_LENGTH")
 
 	// Exposure
-	if exp := s.getFloatHeader(header, "EXPTIME", "EXPOSURE", "EXPOS"); exp != nil {
+	if exp := getFloatHeader(header, "EXPTIME", "EXPOSURE", "EXPOS"); exp != nil {
 		file.Exposure = *exp
 	}
 
 	// Date/Time
-	if dateStr := s.getStringHeader(header, "DATE-OBS", "DATE_OBS", "DATEOBS"); dateStr != "" {
+	if dateStr := getStringHeader(header, "DATE-OBS", "DATE_OBS", "DATEOBS"); dateStr != "" {
 		if t, ok := parseDateTimeString(dateStr); ok {
 			file.UTCTime = sql.NullString{String: t.UTC().Format("2006-01-02T15:04:05"), Valid: true}
 		}
 	}
 
 	// Local time
-	if localStr := s.getStringHeader(header, "DATE-LOC", "DATE_LOCAL", "LOCTIME"); localStr != "" {
+	if localStr := getStringHeader(header, "DATE-LOC", "DATE_LOCAL", "LOCTIME"); localStr != "" {
 		if t, ok := parseDateTimeString(localStr); ok {
 			file.LocalTime = sql.NullString{String: t.Format("2006-01-02T15:04:05"), Valid: true}
 		}
 	}
 
 	// Julian date (stored as MJD-OBS)
-	file.JulianDate = s.getFloatHeader(header, "MJD-OBS", "JD", "JULIAN", "JD-OBS")
+	file.JulianDate = getFloatHeader(header, "MJD-OBS", "JD", "JULIAN", "JD-OBS")
 
 	// Observation date: astrophotography convention is UTC - 12h so that images taken
 	// after midnight are attributed to the evening session they belong to.
@@ -365,7 +497,7 @@ func (s *Scanner) extractMetadata(filePath, relPath string, fileInfo os.FileInfo
 		}
 	} else {
 		// Last resort: re-read DATE-OBS header directly
-		if rawDate := s.getStringHeader(header, "DATE-OBS", "DATE_OBS", "DATEOBS"); rawDate != "" {
+		if rawDate := getStringHeader(header, "DATE-OBS", "DATE_OBS", "DATEOBS"); rawDate != "" {
 			if t, ok := parseDateTimeString(rawDate); ok {
 				dateStr := observationDateFromTime(t)
 				file.ObservationDate = sql.NullString{String: dateStr, Valid: true}
@@ -379,7 +511,7 @@ func (s *Scanner) extractMetadata(filePath, relPath string, fileInfo os.FileInfo
 	}
 
 	// Software/Platform
-	file.Software = s.getStringHeader(header, "SWCREATE", "SOFTWARE", "PROGRAM", "CREATOR")
+	file.Software = getStringHeader(header, "SWCREATE", "SOFTWARE", "PROGRAM", "CREATOR")
 
 	// ASIAIR does not write an OBJECT header; derive the target name from the filename instead.
 	if file.Object == "" && strings.Contains(strings.ToUpper(file.Software), "ASIAIR") {
@@ -387,22 +519,42 @@ func (s *Scanner) extractMetadata(filePath, relPath string, fileInfo os.FileInfo
 	}
 
 	// Camera/Instrument
-	file.Camera = s.getStringHeader(header, "INSTRUME", "CAMERA", "DETECTOR")
+	file.Camera = getStringHeader(header, "INSTRUME", "CAMERA", "DETECTOR")
 
 	// Gain
-	file.Gain = s.getFloatHeader(header, "GAIN", "EGAIN")
+	file.Gain = getFloatHeader(header, "GAIN", "EGAIN")
 
 	// Offset
-	if offset := s.getIntHeader(header, "OFFSET", "PEDESTAL"); offset != nil {
+	if offset := getIntHeader(header, "OFFSET", "PEDESTAL"); offset != nil {
 		file.Offset = offset
 	}
 
 	// Filter
-	file.Filter = s.getStringHeader(header, "FILTER", "FILT", "FILTNAME")
+	file.Filter = getStringHeader(header, "FILTER", "FILT", "FILTNAME")
+
+	// Acquisition conditions
+	file.CCDTemp = getFloatHeader(header, "CCD-TEMP", "CCD_TEMP", "CCDTEMP")
+	file.SetTemp = getFloatHeader(header, "SET-TEMP", "SET_TEMP", "SETTEMP")
+	file.XBinning = getIntHeader(header, "XBINNING", "BINNING")
+	file.YBinning = getIntHeader(header, "YBINNING", "BINNING")
+	file.Naxis1 = getIntHeader(header, "NAXIS1")
+	file.Naxis2 = getIntHeader(header, "NAXIS2")
+	file.PixelSize = getFloatHeader(header, "XPIXSZ", "PIXSIZE1", "PIXSIZE")
+	file.BayerPattern = strings.ToUpper(getStringHeader(header, "BAYERPAT", "COLORTYP"))
+	file.Airmass = getFloatHeader(header, "AIRMASS")
+	file.ObjectAlt = getFloatHeader(header, "OBJCTALT", "ALTITUDE", "CENTALT")
+	file.SiteLat = getAngleHeader(header, "SITELAT", "OBSLAT", "LAT-OBS")
+	file.SiteLong = getAngleHeader(header, "SITELONG", "OBSLONG", "LONG-OBS")
+	file.FocuserPosition = getIntHeader(header, "FOCPOS", "FOCUSPOS")
+	file.FocuserTemp = getFloatHeader(header, "FOCTEMP", "FOCUSTEM")
+	file.PierSide = strings.ToUpper(getStringHeader(header, "PIERSIDE"))
+	file.Rotator = getFloatHeader(header, "ROTATOR", "ROTATANG", "ROTANGLE")
+	file.ReadoutMode = getStringHeader(header, "READOUTM", "READMODE")
+	file.Conditions = s.extractConditions(header)
 
 	// Image Type - only process LIGHT frames
 	// Normalize to uppercase to handle mixed-case values like ASIAIR's "Light" / "Light   "
-	file.ImageType = strings.ToUpper(s.getStringHeader(header, "IMAGETYP", "IMAGTYP", "FRAME"))
+	file.ImageType = strings.ToUpper(getStringHeader(header, "IMAGETYP", "IMAGTYP", "FRAME"))
 	if file.ImageType == "" {
 		file.ImageType = "LIGHT" // Default to LIGHT if not specified
 	}
@@ -410,8 +562,26 @@ func (s *Scanner) extractMetadata(filePath, relPath string, fileInfo os.FileInfo
 	return file, nil
 }
 
+// extractConditions reads the observing-conditions keywords, returning nil
+// when the header has none of them
+func (s *Scanner) extractConditions(header *fitsio.Header) *FrameConditions {
+	c := &FrameConditions{
+		AmbientTemp: getFloatHeader(header, "AMBTEMP", "AMB-TEMP"),
+		Humidity:    getFloatHeader(header, "HUMIDITY"),
+		Pressure:    getFloatHeader(header, "PRESSURE"),
+		DewPoint:    getFloatHeader(header, "DEWPOINT"),
+		SkyQuality:  getFloatHeader(header, "SKYQUAL", "MPSAS", "SQM"),
+		CloudCover:  getFloatHeader(header, "CLOUDCVR"),
+		WindSpeed:   getFloatHeader(header, "WINDSPD"),
+	}
+	if *c == (FrameConditions{}) {
+		return nil
+	}
+	return c
+}
+
 // getStringHeader tries multiple header keywords and returns first found value
-func (s *Scanner) getStringHeader(header *fitsio.Header, keys ...string) string {
+func getStringHeader(header *fitsio.Header, keys ...string) string {
 	for _, key := range keys {
 		if card := header.Get(key); card != nil {
 			if val, ok := card.Value.(string); ok && val != "" {
@@ -423,7 +593,7 @@ func (s *Scanner) getStringHeader(header *fitsio.Header, keys ...string) string
 }
 
 // getFloatHeader tries multiple header keywords and returns first found value
-func (s *Scanner) getFloatHeader(header *fitsio.Header, keys ...string) *float64 {
+func getFloatHeader(header *fitsio.Header, keys ...string) *float64 {
 	for _, key := range keys {
 		if card := header.Get(key); card != nil {
 			switch v := card.Value.(type) {
@@ -444,8 +614,24 @@ func (s *Scanner) getFloatHeader(header *fitsio.Header, keys ...string) *float64
 	return nil
 }
 
+// getAngleHeader tries multiple header keywords and returns the first angle
+// found, accepting numbers as well as sexagesimal strings like "+52 05 00"
+func getAngleHeader(header *fitsio.Header, keys ...string) *float64 {
+	for _, key := range keys {
+		if v := getFloatHeader(header, key); v != nil {
+			return v
+		}
+		if str := getStringHeader(header, key); str != "" {
+			if v, err := astro.ParseDegrees(str); err == nil {
+				return &v
+			}
+		}
+	}
+	return nil
+}
+
 // getIntHeader tries multiple header keywords and returns first found value
-func (s *Scanner) getIntHeader(header *fitsio.Header, keys ...string) *int {
+func getIntHeader(header *fitsio.Header, keys ...string) *int {
 	for _, key := range keys {
 		if card := header.Get(key); card != nil {
 			switch v := card.Value.(type) {
@@ -2,11 +2,15 @@ package mcpserver
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -42,6 +46,10 @@ func RunMCPServer(cmd *cobra.Command, args []string) {
 	// Initialize logging from config
 	InitLogging(cfg.Logging.Level, cfg.Logging.Format)
 
+	if err := cfg.Tools.Policy().Validate(); err != nil {
+		log.Fatal().Err(err).Msg("Invalid tool policy")
+	}
+
 	// Log configuration file path
 	log.Info().
 		Str("config", configFile).
@@ -63,8 +71,15 @@ func RunMCPServer(cmd *cobra.Command, args []string) {
 
 	log.Info().Str("database", db.filePath).Msg("Database initialized")
 
-	// Store the ASIAIR common-name map so rescan calls through db.NewScanner also use it.
-	db.SetCommonNames(cfg.CommonNames)
+	configureDatabase(db, cfg)
+
+	// Sessions are derived data; bring them up to date with what is already indexed
+	if _, _, err := db.RefreshSessions(); err != nil {
+		log.Error().Err(err).Msg("Failed to refresh sessions")
+	}
+	if _, err := db.RefreshEphemeris(); err != nil {
+		log.Error().Err(err).Msg("Failed to compute ephemeris")
+	}
 
 	// Start initial scan in background if configured
 	if cfg.Scan.OnStartup {
@@ -92,19 +107,7 @@ func RunMCPServer(cmd *cobra.Command, args []string) {
 	}
 
 	// Create MCP server
-	mcpServer = mcp.NewServer(&mcp.Implementation{
-		Name:    "astro-ai-archiver",
-		Version: Version,
-	}, nil)
-
-	// Register resources
-	registerResources(mcpServer, db)
-
-	// Register tools
-	tools.RegisterAll(mcpServer, db, cfg, expandedDirs, cfg.Scan.Recursive, Version)
-
-	// Register prompts
-	prompts.RegisterAll(mcpServer, db)
+	mcpServer = newMCPServer(db, cfg, expandedDirs)
 
 	// Start server with configured transport
 	log.Info().
@@ -112,11 +115,44 @@ func RunMCPServer(cmd *cobra.Command, args []string) {
 		Str("details", getTransportDetails(cfg)).
 		Msg("MCP server ready")
 
-	if err := startMCPServer(mcpServer, cfg); err != nil {
+	if err := startMCPServer(mcpServer, db, cfg, expandedDirs); err != nil {
 		log.Fatal().Err(err).Msg("Server error")
 	}
 }
 
+// newMCPServer creates an MCP server with the resources, the prompts and the
+// tools that the configured tool policy and the extra policies allow
+func newMCPServer(db *Database, cfg *Config, scanDirs []string, policies ...tools.ToolPolicy) *mcp.Server {
+	server := mcp.NewServer(&mcp.Implementation{
+		Name:    "astro-ai-archiver",
+		Version: Version,
+	}, nil)
+
+	registerResources(server, db)
+	tools.RegisterAll(server, db, cfg, scanDirs, cfg.Scan.Recursive, Version, policies...)
+	prompts.RegisterAll(server, db)
+	return server
+}
+
+// configureDatabase applies the configuration options the database needs
+// for scans, sessions and tools. The server and the one-shot commands share
+// it so they behave the same.
+func configureDatabase(db *Database, cfg *Config) {
+	// Store the ASIAIR common-name map so rescan calls through db.NewScanner also use it.
+	db.SetCommonNames(cfg.CommonNames)
+	db.SetAnalysisOptions(cfg.Analysis.OnScan, cfg.Analysis.Workers)
+	db.SetExportDirectory(cfg.Export.Directory)
+	db.SetAstroBinFilters(cfg.AstroBin.Filters)
+	db.SetSite(cfg.Site.Latitude, cfg.Site.Longitude)
+	if err := db.SetGuidingOptions(expandDirectories(cfg.Guiding.LogDirectories), cfg.Guiding.Timezone); err != nil {
+		log.Error().Err(err).Msg("Guide logs are read in local time")
+	}
+
+	if err := db.SyncConfigProjects(cfg.Projects); err != nil {
+		log.Error().Err(err).Msg("Failed to load projects from config")
+	}
+}
+
 // registerResources registers MCP resources
 func registerResources(s *mcp.Server, db *Database) {
 	// Database schema resource
@@ -155,6 +191,23 @@ Main table containing all FITS file metadata.
 - offset (INTEGER) - Camera offset setting
 - filter (TEXT NOT NULL) - Filter name (e.g., 'L', 'Ha', 'OIII', 'Red')
 - image_type (TEXT) - Image type (typically 'LIGHT')
+- ccd_temp (REAL) - Sensor temperature in °C (CCD-TEMP)
+- set_temp (REAL) - Cooler set point in °C (SET-TEMP)
+- x_binning (INTEGER) - Horizontal binning (XBINNING)
+- y_binning (INTEGER) - Vertical binning (YBINNING)
+- naxis1 (INTEGER) - Image width in pixels (NAXIS1)
+- naxis2 (INTEGER) - Image height in pixels (NAXIS2)
+- pixel_size (REAL) - Pixel size in µm including binning (XPIXSZ)
+- bayer_pattern (TEXT) - Bayer pattern of colour sensors, e.g. 'RGGB' (BAYERPAT); empty for mono
+- airmass (REAL) - Airmass at exposure start (AIRMASS)
+- object_alt (REAL) - Target altitude in degrees (OBJCTALT)
+- site_lat (REAL) - Observatory latitude in degrees (SITELAT)
+- site_long (REAL) - Observatory longitude in degrees, east positive (SITELONG)
+- focuser_position (INTEGER) - Focuser step position (FOCPOS)
+- focuser_temp (REAL) - Focuser temperature in °C (FOCTEMP)
+- pier_side (TEXT) - Pier side, 'EAST' or 'WEST' (PIERSIDE)
+- rotator (REAL) - Mechanical rotator angle in degrees (ROTATOR)
+- readout_mode (TEXT) - Camera readout mode (READOUTM)
 
 ### Indexes:
 - idx_object ON object
@@ -163,12 +216,183 @@ Main table containing all FITS file metadata.
 - idx_utc_time ON utc_time
 - idx_hash ON hash
 - idx_relative_path ON relative_path
+- idx_sky_index_band ON sky_index(dec_band, ra)
+- idx_frame_metrics_fwhm ON frame_metrics(fwhm)
+- idx_frame_marks_grade ON frame_marks(grade)
+- idx_sessions_date ON sessions(observation_date)
+- idx_calibration_type_camera ON calibration_files(frame_type, camera)
+- idx_calibration_date ON calibration_files(observation_date)
+
+## Table: fits_wcs
+Plate-solved field geometry, one row per solved frame (frames without CRVAL/CD or CDELT keywords have no row).
+
+### Columns:
+- file_id (INTEGER PRIMARY KEY) - References fits_files(id)
+- center_ra (REAL NOT NULL) - Solved field centre Right Ascension in degrees
+- center_dec (REAL NOT NULL) - Solved field centre Declination in degrees
+- rotation (REAL) - Position angle of the image Y axis in degrees east of north (0-360)
+- pixel_scale (REAL) - Image scale in arcseconds per pixel
+- corner1_ra, corner1_dec ... corner4_ra, corner4_dec (REAL) - Footprint corners in degrees,
+  at pixels (1,1), (NAXIS1,1), (NAXIS1,NAXIS2) and (1,NAXIS2)
+
+## Table: sky_index
+Spatial index used by the cone_search tool, one row per frame with known coordinates.
+
+### Columns:
+- file_id (INTEGER PRIMARY KEY) - References fits_files(id)
+- ra (REAL NOT NULL) - Indexed Right Ascension in degrees (0-360)
+- dec (REAL NOT NULL) - Indexed Declination in degrees
+- dec_band (INTEGER NOT NULL) - 1° declination band, floor(dec + 90)
+- source (TEXT NOT NULL) - 'wcs' (plate-solved centre) or 'header' (mount RA/Dec)
+
+## Table: frame_metrics
+Image quality metrics computed from the pixel data by the analyze_frames tool, or read from N.I.N.A. Session Metadata ImageMetaData.csv files during scans, one row per frame.
+
+### Columns:
+- file_id (INTEGER PRIMARY KEY) - References fits_files(id)
+- file_hash (TEXT) - fits_files.hash at analysis time; metrics are stale when it differs
+- background (REAL) - Median sky background in ADU
+- noise (REAL) - Background noise in ADU (scaled median absolute deviation)
+- star_count (INTEGER) - Number of detected stars
+- hfr (REAL) - Median half-flux radius in pixels (NULL when no stars were measured)
+- fwhm (REAL) - Median star FWHM in pixels (NULL when no stars were measured)
+- eccentricity (REAL) - Median star eccentricity, 0 = round (NULL when no stars were measured)
+- analyzed_at (INTEGER) - Analysis time (Unix timestamp; NULL for metrics taken from N.I.N.A.)
+- source (TEXT) - 'pixels' (analyze_frames) or 'nina' (star metrics from ImageMetaData.csv; replaced by a pixel analysis)
+- guiding_rms, guiding_rms_ra, guiding_rms_dec (REAL) - Guiding RMS during the exposure in arcseconds (N.I.N.A.)
+- focuser_position (INTEGER), focuser_temp (REAL) - Focuser position and temperature in °C (N.I.N.A.)
+- adu_mean, adu_median, adu_stdev (REAL), adu_min, adu_max (INTEGER) - Image ADU statistics (N.I.N.A.)
+
+## Table: nina_acquisitions
+AcquisitionDetails.csv files of the N.I.N.A. Session Metadata plugin, one row per file. nina_files holds the imported CSV files (path, file_mod_time).
+
+### Columns:
+- relative_path (TEXT PRIMARY KEY) - Path of the CSV file
+- target, ra, dec (TEXT) - Target name and coordinates as written by N.I.N.A.
+- telescope, camera (TEXT), focal_length, focal_ratio, pixel_size (REAL), bit_depth (INTEGER)
+- latitude, longitude, elevation (REAL) - Observer location
+
+## Table: frame_marks
+Accept/reject grades set with the grade_frames tool. Keyed by file hash, so grades survive rescans and moves.
+
+### Columns:
+- hash (TEXT PRIMARY KEY) - fits_files.hash of the graded frame
+- grade (TEXT NOT NULL) - 'accepted' or 'rejected'
+- reason (TEXT) - Why the frame was graded
+- marked_at (INTEGER) - Grading time (Unix timestamp)
+
+## Table: sessions
+Imaging sessions derived from fits_files after every scan: one row per observation_date, telescope and camera.
+
+### Columns:
+- id (INTEGER PRIMARY KEY) - Session id
+- observation_date (TEXT NOT NULL) - Night of the session (YYYY-MM-DD)
+- telescope, camera (TEXT NOT NULL) - Equipment ('' when unknown)
+- start_time, end_time (TEXT) - UTC start of the first and end of the last frame
+- targets, filters (TEXT) - Sorted lists, one name per line (separated by char(10))
+- frame_count (INTEGER) - Number of frames
+- integration (REAL) - Total exposure in seconds
+- gap_count (INTEGER) - Pauses of more than 10 minutes between frames
+- gap_seconds (REAL) - Total length of those pauses in seconds
+- longest_gap (REAL) - Longest pause in seconds
+- site_lat, site_long (REAL) - Session site: average SITELAT/SITELONG of the frames, else the configured site
+- dark_start, dark_end (TEXT) - Astronomical darkness (Sun below -18°) of the night at the site, UTC; NULL without a site or darkness
+- dark_seconds (REAL) - Length of astronomical darkness
+- dark_integration (REAL) - Exposure seconds within darkness; dark_integration / dark_seconds is the imaging efficiency
+- lost_seconds (REAL) - Gap seconds within darkness
+- signature (TEXT) - Internal change marker used by the incremental refresh
+
+## Table: projects
+Imaging projects managed with save_project/delete_project or the config file.
+
+### Columns:
+- id (INTEGER PRIMARY KEY) - Project id
+- name (TEXT UNIQUE) - Project name
+- target (TEXT) - Target object name (matches fits_files.object, case-insensitive)
+- telescope, camera (TEXT) - Optional equipment restriction (partial match)
+- notes (TEXT) - Free-form notes
+- source (TEXT) - 'tool' or 'config'
+- added_at (INTEGER) - Creation time (Unix timestamp)
+
+## Table: project_goals
+Integration goals of projects, one row per project and filter.
+
+### Columns:
+- project_id (INTEGER) - References projects(id)
+- filter (TEXT) - Filter name
+- hours (REAL) - Integration goal in hours
+
+## Table: calibration_files
+Calibration frames indexed during scans (IMAGETYP dark, flat, bias/offset/zero, dark flat; masters included).
+
+### Columns:
+- id (INTEGER PRIMARY KEY), relative_path (TEXT UNIQUE), hash (TEXT), file_mod_time (INTEGER)
+- frame_type (TEXT) - 'DARK', 'FLAT', 'BIAS' or 'DARKFLAT'
+- master (INTEGER) - 1 for master frames
+- telescope, camera, filter (TEXT)
+- exposure (REAL), gain (REAL), offset (INTEGER), x_binning, y_binning (INTEGER)
+- ccd_temp, set_temp (REAL) - Sensor temperature and cooler set point in °C
+- naxis1, naxis2 (INTEGER), readout_mode (TEXT), rotator (REAL)
+- utc_time (TEXT), observation_date (TEXT)
+- indexed_at (INTEGER) - Indexing time (Unix timestamp)
+
+## Table: guide_sessions
+PHD2 guide sessions ("Guiding Begins" to "Guiding Ends") from guide logs imported during scans.
+
+### Columns:
+- id (INTEGER PRIMARY KEY), log_id (INTEGER) - References guide_logs(id) (path, file_mod_time)
+- night (TEXT) - Local evening date of the session start (YYYY-MM-DD)
+- mount (TEXT) - Mount name as written by PHD2
+- pixel_scale (REAL) - Guide camera scale in arcseconds per pixel
+- start_time, end_time (TEXT) - UTC, same format as fits_files.utc_time
+- samples (INTEGER), dithers (INTEGER)
+- rms_ra, rms_dec, rms_total (REAL) - Guiding RMS in arcseconds
+
+## Table: guide_samples / guide_events
+Guide steps (session_id, time in Unix seconds UTC, ra and dec raw offsets in guide camera pixels) and events (session_id, time, kind 'dither', 'settle_start', 'settle_done' or 'settle_failed', detail).
+
+## Table: frame_guiding
+Guiding during each light frame's exposure, for frames that started while guiding.
+
+### Columns:
+- file_id (INTEGER PRIMARY KEY) - References fits_files(id)
+- guide_session_id (INTEGER) - References guide_sessions(id)
+- samples (INTEGER) - Guide steps during the exposure (0 = none)
+- rms_ra, rms_dec, rms_total (REAL) - Guiding RMS during the exposure in arcseconds
+- dithers (INTEGER) - Dithers during the exposure
+
+## Table: frame_ephemeris
+Moon and target geometry at mid-exposure, computed offline from utc_time, the frame position (plate solve or RA/DEC) and the site (SITELAT/SITELONG or the configured site).
+
+### Columns:
+- file_id (INTEGER PRIMARY KEY) - References fits_files(id)
+- moon_illumination (REAL) - Illuminated fraction of the Moon, 0 = new, 1 = full
+- moon_alt (REAL) - Moon altitude in degrees, negative below the horizon
+- moon_separation (REAL) - Angle between the Moon and the target in degrees
+- target_alt, target_az (REAL) - Target altitude and azimuth (from north through east) in degrees
+- airmass (REAL) - NULL below the horizon
+- site (TEXT) - Configured site ("lat,long") the row was computed with, empty for frames with SITELAT/SITELONG
+
+## Table: frame_conditions
+Observing conditions from the headers of each frame (AMBTEMP, HUMIDITY, PRESSURE, DEWPOINT, SKYQUAL/MPSAS, CLOUDCVR, WINDSPD), for frames that recorded any.
+
+### Columns:
+- file_id (INTEGER PRIMARY KEY) - References fits_files(id)
+- ambient_temp (REAL) - °C, dew_point (REAL) - °C
+- humidity (REAL) - Relative humidity in %
+- pressure (REAL) - hPa
+- sky_quality (REAL) - Sky brightness in mag/arcsec² (higher is darker)
+- cloud_cover (REAL) - %
+- wind_speed (REAL)
+
+## View: usable_files
+All fits_files columns for frames that are not graded as rejected. Use it instead of fits_files for integration totals.
 
 ### Notes:
 - All dates should be queried in ISO8601 format
 - Use LIKE for partial text matching on object, telescope, camera
 - Use BETWEEN for date ranges on utc_time
-- Only LIGHT frames are stored (no DARK, FLAT, BIAS calibration frames)
+- fits_files holds LIGHT frames only; DARK, FLAT, BIAS and DARKFLAT frames are in calibration_files
 
 ### Example Queries:
 -- Files by month
@@ -182,6 +406,31 @@ SELECT filter, AVG(exposure) as avg_exp, COUNT(*) as count
 FROM fits_files 
 GROUP BY filter;
 
+-- Integration per cooler set point and binning
+SELECT set_temp, x_binning, COUNT(*) as frames, SUM(exposure) / 3600.0 as hours
+FROM fits_files
+GROUP BY set_temp, x_binning;
+
+-- Solved framing of a target across nights
+SELECT f.observation_date, w.center_ra, w.center_dec, w.rotation, w.pixel_scale
+FROM fits_files f
+JOIN fits_wcs w ON w.file_id = f.id
+WHERE f.object = 'NGC7000'
+ORDER BY f.observation_date;
+
+-- Sharpest Ha subs of a target
+SELECT f.relative_path, m.fwhm, m.eccentricity, m.star_count
+FROM fits_files f
+JOIN frame_metrics m ON m.file_id = f.id
+WHERE f.object = 'M33' AND f.filter = 'Ha'
+ORDER BY m.fwhm;
+
+-- Integration per filter without rejected frames
+SELECT filter, COUNT(*) as frames, SUM(exposure) / 3600.0 as hours
+FROM usable_files
+WHERE object = 'M33'
+GROUP BY filter;
+
 -- Specific target with date range
 SELECT object, filter, exposure, utc_time, relative_path
 FROM fits_files
@@ -201,7 +450,38 @@ ORDER BY utc_time;
 		}, nil
 	})
 
-	log.Info().Int("resources", 1).Msg("MCP resources registered")
+	// Frame previews
+	s.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: "fits://preview/{id}",
+		Name:        "FITS Frame Preview",
+		Description: "Auto-stretched JPEG preview of the frame with the given database id",
+		MIMEType:    "image/jpeg",
+	}, func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		log.Info().Str("resource", req.Params.URI).Msg("Resource requested")
+
+		id, err := strconv.ParseInt(strings.TrimPrefix(req.Params.URI, "fits://preview/"), 10, 64)
+		if err != nil {
+			return nil, mcp.ResourceNotFoundError(req.Params.URI)
+		}
+
+		preview, err := db.RenderPreview(id, 0, "jpeg")
+		if err != nil {
+			log.Error().Err(err).Str("resource", req.Params.URI).Msg("Resource failed")
+			return nil, err
+		}
+
+		return &mcp.ReadResourceResult{
+			Contents: []*mcp.ResourceContents{
+				{
+					URI:      req.Params.URI,
+					MIMEType: preview.MIMEType,
+					Blob:     preview.Data,
+				},
+			},
+		}, nil
+	})
+
+	log.Info().Int("resources", 1).Int("templates", 1).Msg("MCP resources registered")
 }
 
 // loadConfig loads configuration from file using viper
@@ -274,7 +554,7 @@ func expandDirectories(patterns []string) []string {
 }
 
 // startMCPServer starts the MCP server with the configured transport
-func startMCPServer(server *mcp.Server, cfg *Config) error {
+func startMCPServer(server *mcp.Server, db *Database, cfg *Config, scanDirs []string) error {
 	transportType := getTransportType(cfg)
 
 	switch transportType {
@@ -282,15 +562,18 @@ func startMCPServer(server *mcp.Server, cfg *Config) error {
 		return server.Run(context.Background(), &mcp.StdioTransport{})
 
 	case "http":
-		return startHTTPServer(server, cfg)
+		return startHTTPServer(server, db, cfg, scanDirs)
 
 	default:
 		return fmt.Errorf("unsupported transport type: %s (supported: stdio, http)", transportType)
 	}
 }
 
-// startHTTPServer starts the HTTP server using StreamableHTTPHandler
-func startHTTPServer(server *mcp.Server, cfg *Config) error {
+// startHTTPServer starts the HTTP server using StreamableHTTPHandler, with
+// the REST API and the web dashboard next to it. API keys with a tool policy
+// of their own get an MCP server of their own, so their tool list and REST
+// API only hold what the key may use.
+func startHTTPServer(server *mcp.Server, db *Database, cfg *Config, scanDirs []string) error {
 	// Get HTTP configuration
 	host := cfg.Transport.HTTP.Host
 	if host == "" {
@@ -301,29 +584,79 @@ func startHTTPServer(server *mcp.Server, cfg *Config) error {
 		port = 8080
 	}
 
-	// Create HTTP handler
-	handler := mcp.NewStreamableHTTPHandler(func(req *http.Request) *mcp.Server {
-		return server
-	}, nil)
+	keys := cfg.Transport.HTTP.APIKeys
+	if err := checkHTTPAuth(host, keys, cfg.Transport.HTTP.AllowUnauthenticated); err != nil {
+		return err
+	}
 
-	// Create HTTP server
-	httpServer := &http.Server{
-		Addr:    fmt.Sprintf("%s:%d", host, port),
-		Handler: handler,
+	var tlsConfig *tls.Config
+	if cfg.Transport.HTTP.TLS.Enabled {
+		cert, err := tlsCertificate(cfg.Transport.HTTP.TLS, filepath.Join(filepath.Dir(db.filePath), "tls"), host)
+		if err != nil {
+			return err
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
 	}
 
 	// Setup graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	handler, closeAPI, err := newHTTPHandler(ctx, server, db)
+	if err != nil {
+		return err
+	}
+	defer closeAPI()
+
+	keyHandlers := map[string]http.Handler{}
+	for _, k := range keys {
+		policy := k.Tools.Policy()
+		if policy.IsZero() {
+			continue
+		}
+		keyHandler, closeKeyAPI, err := newHTTPHandler(ctx, newMCPServer(db, cfg, scanDirs, policy), db)
+		if err != nil {
+			return err
+		}
+		defer closeKeyAPI()
+		keyHandlers[k.Name] = keyHandler
+	}
+	route := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h, ok := keyHandlers[apiKeyName(r.Context())]; ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+
+	// Create HTTP server. Requests are authenticated before they reach any
+	// handler.
+	httpServer := &http.Server{
+		Addr:      net.JoinHostPort(host, strconv.Itoa(port)),
+		Handler:   requireAPIKey(keys, route),
+		TLSConfig: tlsConfig,
+	}
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+
 	// Handle shutdown signals
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	// Start server in goroutine
 	go func() {
-		log.Info().Str("addr", httpServer.Addr).Msg("Starting HTTP server")
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Info().Str("addr", httpServer.Addr).Str("rest_api", restAPIPrefix).
+			Str("dashboard", fmt.Sprintf("%s://%s%s", scheme, httpServer.Addr, dashboardPrefix)).
+			Bool("tls", tlsConfig != nil).Int("api_keys", len(keys)).Msg("Starting HTTP server")
+		var err error
+		if tlsConfig != nil {
+			err = httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Error().Err(err).Msg("HTTP server error")
 			cancel()
 		}
@@ -345,6 +678,52 @@ func startHTTPServer(server *mcp.Server, cfg *Config) error {
 	return httpServer.Shutdown(shutdownCtx)
 }
 
+// newHTTPHandler serves an MCP server over HTTP: the MCP endpoint, the REST
+// API, which answers through the same tools, and the web dashboard. The
+// returned function closes the REST API's session.
+func newHTTPHandler(ctx context.Context, server *mcp.Server, db *Database) (http.Handler, func() error, error) {
+	handler := mcp.NewStreamableHTTPHandler(func(req *http.Request) *mcp.Server {
+		return server
+	}, nil)
+
+	api, err := newRESTAPI(ctx, server)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mux := http.NewServeMux()
+	api.register(mux)
+	registerDashboard(mux, db)
+	mux.Handle("/", handler)
+	return mux, api.Close, nil
+}
+
+// checkHTTPAuth validates the API keys and refuses to open the server to the
+// network without them, unless allow_unauthenticated says that is intended
+func checkHTTPAuth(host string, keys []APIKeyConfig, allowUnauthenticated bool) error {
+	if err := validateAPIKeys(keys); err != nil {
+		return err
+	}
+	if len(keys) > 0 || isLoopbackHost(host) {
+		return nil
+	}
+	if !allowUnauthenticated {
+		return fmt.Errorf("HTTP transport on %s needs api_keys; set allow_unauthenticated: true to serve it without authentication", host)
+	}
+	log.Warn().Str("host", host).Msg("HTTP transport is reachable from the network without API keys; anyone can call every tool")
+	return nil
+}
+
+// isLoopbackHost reports whether the HTTP transport only listens on this
+// computer
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 // getTransportType returns the transport type with stdio as default
 func getTransportType(cfg *Config) string {
 	if cfg.Transport.Type == "" {
@@ -369,7 +748,14 @@ func getTransportDetails(cfg *Config) string {
 		if port == 0 {
 			port = 8080
 		}
-		return fmt.Sprintf("http transport on %s:%d", host, port)
+		details := fmt.Sprintf("http transport on %s:%d", host, port)
+		if cfg.Transport.HTTP.TLS.Enabled {
+			details += " with TLS"
+		}
+		if n := len(cfg.Transport.HTTP.APIKeys); n > 0 {
+			details += fmt.Sprintf(", %d API key(s)", n)
+		}
+		return details
 	default:
 		return "unknown transport"
 	}
@@ -0,0 +1,427 @@
+package mcpserver
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// sessionGapThreshold is the shortest pause between two frames of a session
+// that is reported as a gap. Shorter pauses (dithering, autofocus, meridian
+// flips) are part of normal acquisition.
+const sessionGapThreshold = 10 * time.Minute
+
+// sessionTimeLayout is the format of session start and end times, matching
+// fits_files.utc_time
+const sessionTimeLayout = "2006-01-02T15:04:05"
+
+// sessionKey identifies a session: one night with one telescope and camera
+type sessionKey struct {
+	date, telescope, camera string
+}
+
+// sessionAggregate is a session as summarised by SQL, before gaps are known
+type sessionAggregate struct {
+	key         sessionKey
+	frames      int
+	integration float64
+	targets     []string
+	filters     []string
+	siteLat     *float64
+	siteLong    *float64
+	signature   string
+}
+
+// RefreshSessions brings the sessions table up to date with fits_files.
+// Only sessions whose frames changed since the last refresh are rebuilt, and
+// sessions without frames are removed. It returns the number of sessions
+// rebuilt and removed.
+func (d *Database) RefreshSessions() (int, int, error) {
+	rows, err := d.db.Query(`
+		SELECT observation_date, COALESCE(telescope, ''), COALESCE(camera, ''),
+		       COUNT(*), TOTAL(exposure), SUM(id),
+		       COALESCE(MIN(utc_time), ''), COALESCE(MAX(utc_time), ''), TOTAL(julianday(utc_time)),
+		       json_group_array(DISTINCT NULLIF(object, '')),
+		       json_group_array(DISTINCT NULLIF(filter, '')),
+		       AVG(site_lat), AVG(site_long)
+		FROM fits_files
+		WHERE observation_date IS NOT NULL AND observation_date != ''
+		GROUP BY 1, 2, 3
+	`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to aggregate sessions: %w", err)
+	}
+	var current []sessionAggregate
+	for rows.Next() {
+		var a sessionAggregate
+		var idSum int64
+		var timeSum float64
+		var first, last, targets, filters string
+		if err := rows.Scan(&a.key.date, &a.key.telescope, &a.key.camera,
+			&a.frames, &a.integration, &idSum, &first, &last, &timeSum, &targets, &filters,
+			&a.siteLat, &a.siteLong); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		a.targets = jsonList(targets)
+		a.filters = jsonList(filters)
+		// The site is part of the signature so a newly configured site is picked up
+		site := ""
+		if lat, lon, ok := d.sessionSite(a.siteLat, a.siteLong); ok {
+			a.siteLat, a.siteLong = &lat, &lon
+			site = fmt.Sprintf("%.4f,%.4f", lat, lon)
+		} else {
+			a.siteLat, a.siteLong = nil, nil
+		}
+		// The sum of the frame times catches a frame moved between the first and
+		// the last, which changes the gaps but not the other aggregates
+		a.signature = fmt.Sprintf("%d|%g|%d|%s|%s|%.6f|%s|%s|%s", a.frames, a.integration, idSum, first, last,
+			timeSum, joinList(a.targets), joinList(a.filters), site)
+		current = append(current, a)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, 0, err
+	}
+
+	stored := map[sessionKey]string{}
+	rows, err = d.db.Query("SELECT observation_date, telescope, camera, signature FROM sessions")
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read sessions: %w", err)
+	}
+	for rows.Next() {
+		var k sessionKey
+		var sig string
+		if err := rows.Scan(&k.date, &k.telescope, &k.camera, &sig); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		stored[k] = sig
+	}
+	if err := rows.Close(); err != nil {
+		return 0, 0, err
+	}
+
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	rebuilt := 0
+	for _, a := range current {
+		sig, ok := stored[a.key]
+		delete(stored, a.key)
+		if ok && sig == a.signature {
+			continue
+		}
+
+		spans, err := d.sessionSpans(a.key)
+		if err != nil {
+			return 0, 0, err
+		}
+		start, end, gaps := sessionTimeline(spans)
+		var gapSeconds, longest float64
+		for _, g := range gaps {
+			gapSeconds += g.Seconds
+			longest = max(longest, g.Seconds)
+		}
+		var dark sessionDarkness
+		if a.siteLat != nil {
+			dark = measureDarkness(a.key.date, *a.siteLat, *a.siteLong, spans, gaps)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO sessions (
+				observation_date, telescope, camera, start_time, end_time, targets, filters,
+				frame_count, integration, gap_count, gap_seconds, longest_gap,
+				site_lat, site_long, dark_start, dark_end, dark_seconds, dark_integration, lost_seconds,
+				signature, refreshed_at
+			) VALUES (?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?,
+				?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, strftime('%s', 'now'))
+			ON CONFLICT(observation_date, telescope, camera) DO UPDATE SET
+				start_time = excluded.start_time,
+				end_time = excluded.end_time,
+				targets = excluded.targets,
+				filters = excluded.filters,
+				frame_count = excluded.frame_count,
+				integration = excluded.integration,
+				gap_count = excluded.gap_count,
+				gap_seconds = excluded.gap_seconds,
+				longest_gap = excluded.longest_gap,
+				site_lat = excluded.site_lat,
+				site_long = excluded.site_long,
+				dark_start = excluded.dark_start,
+				dark_end = excluded.dark_end,
+				dark_seconds = excluded.dark_seconds,
+				dark_integration = excluded.dark_integration,
+				lost_seconds = excluded.lost_seconds,
+				signature = excluded.signature,
+				refreshed_at = excluded.refreshed_at
+		`, a.key.date, a.key.telescope, a.key.camera, start, end,
+			joinList(a.targets), joinList(a.filters),
+			a.frames, a.integration, len(gaps), gapSeconds, longest,
+			a.siteLat, a.siteLong, dark.start, dark.end, dark.seconds, dark.integration, dark.lost,
+			a.signature)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to store session: %w", err)
+		}
+		rebuilt++
+	}
+
+	// Whatever is left has no frames anymore
+	for k := range stored {
+		_, err := tx.Exec("DELETE FROM sessions WHERE observation_date = ? AND telescope = ? AND camera = ?",
+			k.date, k.telescope, k.camera)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to remove session: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+
+	if rebuilt > 0 || len(stored) > 0 {
+		log.Info().Int("rebuilt", rebuilt).Int("removed", len(stored)).Msg("Sessions refreshed")
+	}
+	return rebuilt, len(stored), nil
+}
+
+// frameSpan is the start time and exposure of one frame
+type frameSpan struct {
+	start    time.Time
+	exposure float64
+}
+
+// sessionSpans returns the timed frames of a session in time order.
+func (d *Database) sessionSpans(key sessionKey) ([]frameSpan, error) {
+	rows, err := d.db.Query(`
+		SELECT utc_time, COALESCE(exposure, 0)
+		FROM fits_files
+		WHERE observation_date = ? AND COALESCE(telescope, '') = ? AND COALESCE(camera, '') = ?
+		  AND utc_time IS NOT NULL
+		ORDER BY utc_time
+	`, key.date, key.telescope, key.camera)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session frames: %w", err)
+	}
+	defer rows.Close()
+
+	var spans []frameSpan
+	for rows.Next() {
+		var utc string
+		var span frameSpan
+		if err := rows.Scan(&utc, &span.exposure); err != nil {
+			return nil, err
+		}
+		if t, ok := parseDateTimeString(utc); ok {
+			span.start = t
+			spans = append(spans, span)
+		}
+	}
+	return spans, rows.Err()
+}
+
+// sessionTimeline returns the start of the first frame, the end of the last
+// frame and the pauses longer than sessionGapThreshold between frames.
+// Spans must be sorted by start time.
+func sessionTimeline(spans []frameSpan) (string, string, []SessionGap) {
+	if len(spans) == 0 {
+		return "", "", nil
+	}
+
+	gaps := []SessionGap{}
+	end := spans[0].start
+	for _, s := range spans {
+		if pause := s.start.Sub(end); pause > sessionGapThreshold {
+			gaps = append(gaps, SessionGap{
+				Start:   end.Format(sessionTimeLayout),
+				End:     s.start.Format(sessionTimeLayout),
+				Seconds: pause.Seconds(),
+			})
+		}
+		if frameEnd := s.start.Add(time.Duration(s.exposure * float64(time.Second))); frameEnd.After(end) {
+			end = frameEnd
+		}
+	}
+	return spans[0].start.Format(sessionTimeLayout), end.Format(sessionTimeLayout), gaps
+}
+
+// sessionSelect is the column list scanned by scanSession
+const sessionSelect = `
+	SELECT id, observation_date, telescope, camera, COALESCE(start_time, ''), COALESCE(end_time, ''),
+	       COALESCE(targets, ''), COALESCE(filters, ''), frame_count, integration,
+	       gap_count, gap_seconds, longest_gap,
+	       site_lat, site_long, COALESCE(dark_start, ''), COALESCE(dark_end, ''),
+	       dark_seconds, dark_integration, lost_seconds
+	FROM sessions
+`
+
+// scanSession reads a row selected with sessionSelect
+func scanSession(scanner interface{ Scan(...interface{}) error }) (*Session, error) {
+	s := &Session{}
+	var targets, filters string
+	err := scanner.Scan(&s.ID, &s.ObservationDate, &s.Telescope, &s.Camera, &s.StartTime, &s.EndTime,
+		&targets, &filters, &s.FrameCount, &s.Integration, &s.GapCount, &s.GapSeconds, &s.LongestGap,
+		&s.SiteLat, &s.SiteLong, &s.DarkStart, &s.DarkEnd, &s.DarkSeconds, &s.DarkIntegration, &s.LostSeconds)
+	if err != nil {
+		return nil, err
+	}
+	if s.DarkSeconds > 0 {
+		efficiency := roundHundredths(s.DarkIntegration / s.DarkSeconds)
+		s.Efficiency = &efficiency
+	}
+	s.Targets = splitList(targets)
+	s.Filters = splitList(filters)
+	return s, nil
+}
+
+// ListSessions returns sessions, newest night first. Supported filters are
+// date_from and date_to (observation dates), target, filter, telescope and
+// camera.
+func (d *Database) ListSessions(filters map[string]interface{}, limit, offset int) (interface{}, error) {
+	query := sessionSelect + " WHERE 1=1"
+	var args []interface{}
+
+	if dateFrom, ok := filters["date_from"].(string); ok && dateFrom != "" {
+		query += " AND observation_date >= ?"
+		args = append(args, dateFrom)
+	}
+	if dateTo, ok := filters["date_to"].(string); ok && dateTo != "" {
+		query += " AND observation_date <= ?"
+		args = append(args, dateTo)
+	}
+	if target, ok := filters["target"].(string); ok && target != "" {
+		query += " AND targets LIKE ?"
+		args = append(args, "%"+target+"%")
+	}
+	if filter, ok := filters["filter"].(string); ok && filter != "" {
+		query += " AND char(10) || filters || char(10) LIKE ?"
+		args = append(args, "%"+listSeparator+filter+listSeparator+"%")
+	}
+	if telescope, ok := filters["telescope"].(string); ok && telescope != "" {
+		query += " AND telescope LIKE ?"
+		args = append(args, "%"+telescope+"%")
+	}
+	if camera, ok := filters["camera"].(string); ok && camera != "" {
+		query += " AND camera LIKE ?"
+		args = append(args, "%"+camera+"%")
+	}
+
+	query += " ORDER BY observation_date DESC, start_time LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// GetSession returns a session with its per-target/filter breakdown, gaps and
+// observing conditions, or nil when there is no session with that id.
+func (d *Database) GetSession(id int64) (interface{}, error) {
+	s, err := scanSession(d.db.QueryRow(sessionSelect+" WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.Query(`
+		SELECT COALESCE(f.object, ''), COALESCE(f.filter, ''), COUNT(*), TOTAL(f.exposure),
+		       SUM(EXISTS (SELECT 1 FROM frame_marks fm WHERE fm.hash = f.hash AND fm.grade = 'rejected'))
+		FROM fits_files f
+		WHERE f.observation_date = ? AND COALESCE(f.telescope, '') = ? AND COALESCE(f.camera, '') = ?
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+	`, s.ObservationDate, s.Telescope, s.Camera)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	s.Breakdown = []SessionGroup{}
+	for rows.Next() {
+		var g SessionGroup
+		if err := rows.Scan(&g.Target, &g.Filter, &g.Frames, &g.Integration, &g.Rejected); err != nil {
+			return nil, err
+		}
+		s.Breakdown = append(s.Breakdown, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	spans, err := d.sessionSpans(sessionKey{s.ObservationDate, s.Telescope, s.Camera})
+	if err != nil {
+		return nil, err
+	}
+	_, _, s.Gaps = sessionTimeline(spans)
+	darkGapSeconds(s.Gaps, s.DarkStart, s.DarkEnd)
+
+	if s.Conditions, err = d.sessionConditions(s.ID); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// listSeparator separates the items of the targets and filters columns.
+// FITS header values are printable ASCII, so no name contains a newline,
+// while commas are common ("Sh2-129, Ou4").
+const listSeparator = "\n"
+
+// joinList joins items for storage in a list column
+func joinList(items []string) string {
+	return strings.Join(items, listSeparator)
+}
+
+// splitList splits a stored list into its sorted, non-empty items.
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, listSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	sort.Strings(items)
+	return items
+}
+
+// jsonList returns the sorted, non-empty items of a JSON array built by
+// json_group_array; NULLs are left out.
+func jsonList(s string) []string {
+	var values []*string
+	if err := json.Unmarshal([]byte(s), &values); err != nil {
+		return []string{}
+	}
+	items := []string{}
+	for _, v := range values {
+		if v != nil {
+			if item := strings.TrimSpace(*v); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	sort.Strings(items)
+	return items
+}
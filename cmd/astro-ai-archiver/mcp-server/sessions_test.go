@@ -0,0 +1,106 @@
+package mcpserver
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestRefreshSessionsIsIncremental(t *testing.T) {
+	db, _ := newTestDatabase(t)
+
+	frame := func(path, night, utc, object, filter string) *FITSFile {
+		return &FITSFile{
+			RelativePath: path, Object: object, Filter: filter, Exposure: 300,
+			Telescope: "RC8", Camera: "ASI2600MM",
+			UTCTime:         sql.NullString{String: utc, Valid: true},
+			ObservationDate: sql.NullString{String: night, Valid: true},
+		}
+	}
+
+	// Night 1: two Ha frames, a 55 minute pause, one OIII frame past midnight
+	insertFrames(t, db,
+		frame("n1/a.fits", "2025-09-01", "2025-09-01T21:00:00", "NGC7000", "Ha"),
+		frame("n1/b.fits", "2025-09-01", "2025-09-01T21:05:00", "NGC7000", "Ha"),
+		frame("n1/c.fits", "2025-09-01", "2025-09-02T00:05:00", "NGC7000", "OIII"),
+		frame("n2/a.fits", "2025-09-03", "2025-09-03T22:00:00", "M31", "L"),
+	)
+
+	if rebuilt, removed, err := db.RefreshSessions(); err != nil || rebuilt != 2 || removed != 0 {
+		t.Fatalf("RefreshSessions = %d, %d, %v; expected 2 rebuilt", rebuilt, removed, err)
+	}
+
+	list, err := db.ListSessions(map[string]interface{}{"filter": "OIII"}, 10, 0)
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	sessions := list.([]*Session)
+	if len(sessions) != 1 {
+		t.Fatalf("ListSessions(OIII) returned %d sessions, expected 1", len(sessions))
+	}
+	s := sessions[0]
+	if s.FrameCount != 3 || s.Integration != 900 || s.StartTime != "2025-09-01T21:00:00" ||
+		s.EndTime != "2025-09-02T00:10:00" || s.GapCount != 1 || s.LongestGap != 10500 {
+		t.Errorf("session = %+v", s)
+	}
+
+	detail, err := db.GetSession(s.ID)
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if d := detail.(*Session); len(d.Breakdown) != 2 || len(d.Gaps) != 1 || d.Gaps[0].Start != "2025-09-01T21:10:00" {
+		t.Errorf("session detail = %+v", d)
+	}
+
+	// Only the night that changed is rebuilt; the session keeps its id
+	insertFrames(t, db, frame("n1/d.fits", "2025-09-01", "2025-09-02T00:10:00", "NGC7000", "OIII"))
+	if rebuilt, _, _ := db.RefreshSessions(); rebuilt != 1 {
+		t.Errorf("second refresh rebuilt %d sessions, expected 1", rebuilt)
+	}
+	if detail, _ := db.GetSession(s.ID); detail == nil || detail.(*Session).FrameCount != 4 {
+		t.Errorf("session %d after refresh = %+v", s.ID, detail)
+	}
+
+	// Moving a frame inside the night changes the gaps, not the first or last time
+	moved := frame("n1/b.fits", "2025-09-01", "2025-09-01T23:00:00", "NGC7000", "Ha")
+	insertFrames(t, db, moved)
+	if rebuilt, _, _ := db.RefreshSessions(); rebuilt != 1 {
+		t.Errorf("refresh after moving a frame rebuilt %d sessions, expected 1", rebuilt)
+	}
+	detail, _ = db.GetSession(s.ID)
+	if d := detail.(*Session); d.GapCount != 2 || d.LongestGap != 6900 ||
+		len(d.Gaps) != 2 || d.Gaps[0].Start != "2025-09-01T21:05:00" || d.Gaps[1].Start != "2025-09-01T23:05:00" {
+		t.Errorf("session after moving a frame = %+v", d)
+	}
+
+	// Names may contain commas
+	insertFrames(t, db, frame("n2/b.fits", "2025-09-03", "2025-09-03T22:05:00", "Sh2-129, Ou4", "OIII"))
+	db.RefreshSessions()
+	list, err = db.ListSessions(map[string]interface{}{"target": "Ou4", "filter": "L"}, 10, 0)
+	if err != nil || len(list.([]*Session)) != 1 {
+		t.Fatalf("ListSessions(Ou4, L) = %v, %v", list, err)
+	}
+	if targets := list.([]*Session)[0].Targets; len(targets) != 2 || targets[0] != "M31" || targets[1] != "Sh2-129, Ou4" {
+		t.Errorf("targets = %q", targets)
+	}
+
+	if _, err := db.DeleteFilesByYear(2025); err != nil {
+		t.Fatalf("DeleteFilesByYear: %v", err)
+	}
+	if list, _ := db.ListSessions(nil, 10, 0); len(list.([]*Session)) != 0 {
+		t.Errorf("sessions left after deleting all frames: %+v", list)
+	}
+}
+
+func TestSessionTimelineOverlappingFrames(t *testing.T) {
+	start := time.Date(2025, 1, 1, 20, 0, 0, 0, time.UTC)
+	spans := []frameSpan{
+		{start: start, exposure: 1800},
+		{start: start.Add(5 * time.Minute), exposure: 60}, // Starts while the first is still exposing
+		{start: start.Add(35 * time.Minute), exposure: 60},
+	}
+	first, last, gaps := sessionTimeline(spans)
+	if first != "2025-01-01T20:00:00" || last != "2025-01-01T20:36:00" || len(gaps) != 0 {
+		t.Errorf("timeline = %s .. %s, %d gaps", first, last, len(gaps))
+	}
+}
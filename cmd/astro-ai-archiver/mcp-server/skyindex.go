@@ -0,0 +1,183 @@
+package mcpserver
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/astro"
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/tools"
+)
+
+// The sky index stores one position per frame in 1° declination bands, indexed
+// on (dec_band, ra). A cone search only has to read the bands overlapping the
+// cone and, away from the poles, a bounded RA window inside those bands, so it
+// stays fast regardless of archive size.
+//
+// The indexed position is the plate-solved field centre when available
+// (source "wcs"), otherwise the mount coordinates from the header ("header").
+
+const skyIndexBandCount = 180 // 1° bands covering Dec -90..+90
+
+// decBand returns the declination band for dec (degrees)
+func decBand(dec float64) int {
+	band := int(math.Floor(dec + 90))
+	if band < 0 {
+		return 0
+	}
+	if band >= skyIndexBandCount {
+		return skyIndexBandCount - 1
+	}
+	return band
+}
+
+// upsertSkyIndex stores the sky position of a file, or removes it when the file
+// has no usable coordinates.
+func upsertSkyIndex(tx *sql.Tx, fileID int64, file *FITSFile) error {
+	var ra, dec float64
+	var source string
+
+	switch {
+	case file.WCS != nil:
+		ra, dec, source = file.WCS.CenterRA, file.WCS.CenterDec, "wcs"
+	case file.RA != nil && file.Dec != nil:
+		ra, dec, source = *file.RA, *file.Dec, "header"
+	default:
+		_, err := tx.Exec("DELETE FROM sky_index WHERE file_id = ?", fileID)
+		return err
+	}
+
+	if dec < -90 || dec > 90 {
+		_, err := tx.Exec("DELETE FROM sky_index WHERE file_id = ?", fileID)
+		return err
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO sky_index (file_id, ra, dec, dec_band, source)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(file_id) DO UPDATE SET
+			ra = excluded.ra,
+			dec = excluded.dec,
+			dec_band = excluded.dec_band,
+			source = excluded.source
+	`, fileID, astro.NormalizeDegrees(ra), dec, decBand(dec), source)
+	return err
+}
+
+// ConeSearch returns the frames whose indexed position lies within radius
+// degrees of (ra, dec), sorted by angular separation. The optional filters are
+// the same as for QueryFiles.
+func (d *Database) ConeSearch(ra, dec, radius float64, filters map[string]interface{}, limit int) ([]tools.ConeSearchMatch, error) {
+	if radius <= 0 || radius > 180 {
+		return nil, fmt.Errorf("radius must be between 0 and 180 degrees")
+	}
+
+	// Candidate query: overlapping declination bands, plus an RA window when
+	// the cone does not contain a pole.
+	decMin := math.Max(-90, dec-radius)
+	decMax := math.Min(90, dec+radius)
+	query := `
+		SELECT file_id, ra, dec FROM sky_index
+		WHERE dec_band BETWEEN ? AND ? AND dec BETWEEN ? AND ?`
+	args := []interface{}{decBand(decMin), decBand(decMax), decMin, decMax}
+
+	if decMin > -90 && decMax < 90 {
+		// Half-width of the cone in RA at its widest declination
+		halfWidth := math.Asin(math.Sin(radius*math.Pi/180)/math.Cos(dec*math.Pi/180)) * 180 / math.Pi
+		if !math.IsNaN(halfWidth) && halfWidth < 180 {
+			raMin := ra - halfWidth
+			raMax := ra + halfWidth
+			switch {
+			case raMin < 0:
+				query += " AND (ra >= ? OR ra <= ?)"
+				args = append(args, raMin+360, raMax)
+			case raMax >= 360:
+				query += " AND (ra >= ? OR ra <= ?)"
+				args = append(args, raMin, raMax-360)
+			default:
+				query += " AND ra BETWEEN ? AND ?"
+				args = append(args, raMin, raMax)
+			}
+		}
+	}
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sky index lookup failed: %w", err)
+	}
+
+	separations := map[int64]float64{}
+	for rows.Next() {
+		var id int64
+		var candRA, candDec float64
+		if err := rows.Scan(&id, &candRA, &candDec); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if sep := astro.AngularSeparation(ra, dec, candRA, candDec); sep <= radius {
+			separations[id] = sep
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	log.Debug().
+		Float64("ra", ra).
+		Float64("dec", dec).
+		Float64("radius", radius).
+		Int("within_radius", len(separations)).
+		Msg("Cone search candidates")
+
+	// Load the matching files in chunks, applying the optional filters
+	ids := make([]int64, 0, len(separations))
+	for id := range separations {
+		ids = append(ids, id)
+	}
+
+	where, filterArgs := buildFileFilters(filters)
+	matches := []tools.ConeSearchMatch{}
+	const chunkSize = 500
+	for start := 0; start < len(ids); start += chunkSize {
+		chunk := ids[start:min(start+chunkSize, len(ids))]
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(chunk)), ",")
+		chunkArgs := make([]interface{}, 0, len(chunk)+len(filterArgs))
+		for _, id := range chunk {
+			chunkArgs = append(chunkArgs, id)
+		}
+		chunkArgs = append(chunkArgs, filterArgs...)
+
+		fileRows, err := d.db.Query(fitsFileSelect+" WHERE f.id IN ("+placeholders+")"+where, chunkArgs...)
+		if err != nil {
+			return nil, err
+		}
+		for fileRows.Next() {
+			file, err := scanFITSFile(fileRows)
+			if err != nil {
+				fileRows.Close()
+				return nil, err
+			}
+			matches = append(matches, tools.ConeSearchMatch{
+				File:       file,
+				Separation: separations[file.ID],
+			})
+		}
+		if err := fileRows.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Separation < matches[j].Separation
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,163 @@
+package mcpserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// selfSignedValidity is how long a generated certificate is valid. It is
+// renewed at startup during the last month.
+const selfSignedValidity = 2 * 365 * 24 * time.Hour
+
+// tlsCertificate loads the certificate of the HTTP transport: the configured
+// files, or a self-signed certificate in dir that is generated on first use.
+// The generated certificate is kept, so clients that trust it once keep
+// trusting it across restarts.
+func tlsCertificate(cfg TLSConfig, dir, host string) (tls.Certificate, error) {
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return tls.Certificate{}, fmt.Errorf("tls needs both cert_file and key_file")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		return cert, nil
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	hosts := certificateHosts(host)
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil && certificateCovers(cert, hosts) {
+		return cert, nil
+	}
+
+	if err := writeSelfSignedCertificate(certFile, keyFile, hosts); err != nil {
+		return tls.Certificate{}, err
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	log.Info().Str("cert", certFile).Strs("hosts", hosts).Str("sha256", certificateFingerprint(cert)).Msg("Generated self-signed TLS certificate")
+	return cert, nil
+}
+
+// certificateHosts lists the names and addresses a self-signed certificate is
+// issued for: localhost, the configured host and, when listening on all
+// interfaces, this machine's name and addresses.
+func certificateHosts(host string) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	add := func(h string) {
+		for _, existing := range hosts {
+			if strings.EqualFold(existing, h) {
+				return
+			}
+		}
+		hosts = append(hosts, h)
+	}
+
+	if host != "" && host != "0.0.0.0" && host != "::" {
+		add(host)
+		return hosts
+	}
+	if name, err := os.Hostname(); err == nil && name != "" {
+		add(name)
+	}
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ip, ok := addr.(*net.IPNet); ok && !ip.IP.IsLoopback() && !ip.IP.IsLinkLocalUnicast() {
+				add(ip.IP.String())
+			}
+		}
+	}
+	return hosts
+}
+
+// certificateCovers reports whether a stored certificate is valid for
+// another month and names every host
+func certificateCovers(cert tls.Certificate, hosts []string) bool {
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil || time.Now().Add(30*24*time.Hour).After(leaf.NotAfter) {
+		return false
+	}
+	for _, h := range hosts {
+		if leaf.VerifyHostname(h) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// writeSelfSignedCertificate writes a new ECDSA key and a self-signed
+// certificate for hosts
+func writeSelfSignedCertificate(certFile, keyFile string, hosts []string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate TLS key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return fmt.Errorf("failed to generate certificate serial number: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Astro AI Archiver"}, CommonName: hosts[len(hosts)-1]},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("failed to create TLS certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode TLS key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certFile), 0700); err != nil {
+		return fmt.Errorf("failed to create TLS directory: %w", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return fmt.Errorf("failed to write TLS key: %w", err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return fmt.Errorf("failed to write TLS certificate: %w", err)
+	}
+	return nil
+}
+
+// certificateFingerprint is the SHA-256 fingerprint browsers show for a
+// certificate, to check a self-signed one before trusting it
+func certificateFingerprint(cert tls.Certificate) string {
+	sum := sha256.Sum256(cert.Certificate[0])
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,50 @@
+package mcpserver
+
+import (
+	"bytes"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTLSCertificate(t *testing.T) {
+	dir := t.TempDir()
+
+	cert, err := tlsCertificate(TLSConfig{Enabled: true}, dir, "astro.local")
+	if err != nil {
+		t.Fatalf("tlsCertificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	for _, host := range []string{"localhost", "127.0.0.1", "astro.local"} {
+		if err := leaf.VerifyHostname(host); err != nil {
+			t.Errorf("certificate is not valid for %s: %v", host, err)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(dir, "key.pem")); err != nil || info.Mode().Perm()&0077 != 0 {
+		t.Errorf("key.pem must only be readable by the owner: %v %v", info.Mode(), err)
+	}
+
+	// The generated certificate is kept across restarts
+	again, err := tlsCertificate(TLSConfig{Enabled: true}, dir, "astro.local")
+	if err != nil || !bytes.Equal(again.Certificate[0], cert.Certificate[0]) {
+		t.Errorf("certificate was regenerated: %v", err)
+	}
+	// ... unless it does not name the configured host
+	other, err := tlsCertificate(TLSConfig{Enabled: true}, dir, "observatory.lan")
+	if err != nil || bytes.Equal(other.Certificate[0], cert.Certificate[0]) {
+		t.Errorf("certificate was not regenerated for a new host: %v", err)
+	}
+
+	// Configured files are used as they are
+	configured := TLSConfig{Enabled: true, CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem")}
+	if loaded, err := tlsCertificate(configured, t.TempDir(), "elsewhere"); err != nil || !bytes.Equal(loaded.Certificate[0], other.Certificate[0]) {
+		t.Errorf("configured certificate not loaded: %v", err)
+	}
+	if _, err := tlsCertificate(TLSConfig{Enabled: true, CertFile: configured.CertFile}, dir, ""); err == nil {
+		t.Error("a certificate without key was accepted")
+	}
+}
@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+func RegisterAnalyzeFrames(s *mcp.Server, db Database) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "analyze_frames",
+		Description: "Compute image quality metrics from the pixel data of frames: median background, noise, star count, median HFR/FWHM (pixels) and eccentricity. Frames that already have current metrics are skipped unless force is set. Processes at most 'limit' frames per call; 'remaining' in the result tells how many matching frames still need analysis. Query the results with query_fits_archive (fwhm_max, sort_by, ...).",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"target": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: target object name (partial match)",
+				},
+				"filter": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: filter name (exact match)",
+				},
+				"telescope": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: telescope identifier (partial match)",
+				},
+				"camera": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: camera identifier (partial match)",
+				},
+				"date_from": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: start date in ISO8601 format",
+				},
+				"date_to": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: end date in ISO8601 format",
+				},
+				"force": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Re-analyze frames that already have metrics",
+					"default":     false,
+				},
+				"limit": map[string]interface{}{
+					"type":        "number",
+					"description": "Maximum number of frames to analyze in this call (default: 100, max: 1000)",
+					"default":     100,
+				},
+			},
+		},
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
+		log.Info().Str("tool", "analyze_frames").Interface("params", args).Msg("Tool called")
+
+		force := false
+		if f, ok := args["force"].(bool); ok {
+			force = f
+		}
+		limit := 100
+		if l, ok := args["limit"].(float64); ok && l > 0 {
+			limit = int(l)
+			if limit > 1000 {
+				limit = 1000
+			}
+		}
+
+		result, err := db.AnalyzeFrames(args, force, limit)
+		if err != nil {
+			log.Error().Err(err).Str("tool", "analyze_frames").Msg("Tool failed")
+			return nil, nil, fmt.Errorf("analysis failed: %w", err)
+		}
+
+		log.Trace().Str("tool", "analyze_frames").Interface("response", result).Msg("Tool response")
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Analyzed %d frames (%d failed, %d remaining) in %s",
+					result.Analyzed, result.Failed, result.Remaining, result.Duration)},
+			},
+		}, result, nil
+	})
+}
@@ -0,0 +1,55 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+func RegisterAstroBinAcquisitions(s *mcp.Server, db Database) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "astrobin_acquisitions",
+		Description: "Build the acquisition details of an image for AstroBin: the lights of a target (or of a project's target, telescope and camera) grouped by observation night, filter, exposure, gain and binning, as AstroBin's acquisition CSV ready to import. Rejected frames are left out and duplicates counted once. The filter column holds AstroBin filter ids from astrobin.filters in the config; unmapped filters are listed and left empty.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"target": map[string]interface{}{
+					"type":        "string",
+					"description": "Target object name (exact match, case-insensitive)",
+				},
+				"project": map[string]interface{}{
+					"type":        "string",
+					"description": "Project name; uses the project's target, telescope and camera instead of target",
+				},
+			},
+		},
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
+		log.Info().Str("tool", "astrobin_acquisitions").Interface("params", args).Msg("Tool called")
+
+		target, _ := args["target"].(string)
+		project, _ := args["project"].(string)
+
+		result, err := db.AstroBinAcquisitions(target, project)
+		if err != nil {
+			log.Error().Err(err).Str("tool", "astrobin_acquisitions").Msg("Tool failed")
+			return nil, nil, fmt.Errorf("failed to build acquisitions: %w", err)
+		}
+
+		log.Trace().Str("tool", "astrobin_acquisitions").Interface("response", result).Msg("Tool response")
+
+		text := fmt.Sprintf("%s: %d frames, %.2f h in %d acquisition rows", result.Target, result.Frames, result.IntegrationHours, len(result.Sessions))
+		if len(result.UnmappedFilters) > 0 {
+			text += fmt.Sprintf("\nFilters without an AstroBin id: %s", strings.Join(result.UnmappedFilters, ", "))
+		}
+		text += "\n\n" + result.CSV
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+}
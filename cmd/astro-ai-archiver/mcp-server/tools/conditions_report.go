@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+func RegisterConditionsReport(s *mcp.Server, db Database) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "conditions_report",
+		Description: "Correlate observing conditions with frame quality per imaging session: integration, integration lost to rejected frames, mean FWHM, and the temperature, humidity, dew point, pressure, sky quality (mag/arcsec²), cloud cover and wind speed recorded in the frame headers. Thresholds keep only sessions where a frame went beyond them, e.g. humidity_above 85 answers 'how much integration did I lose on humid nights'. Per-frame values are in the frame_conditions table for SQL queries.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"date_from": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: first observation date (YYYY-MM-DD)",
+				},
+				"date_to": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: last observation date (YYYY-MM-DD)",
+				},
+				"telescope": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: telescope name (partial match)",
+				},
+				"camera": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: camera name (partial match)",
+				},
+				"humidity_above": map[string]interface{}{
+					"type":        "number",
+					"description": "Optional: only sessions with a frame above this relative humidity (%)",
+				},
+				"sky_quality_below": map[string]interface{}{
+					"type":        "number",
+					"description": "Optional: only sessions with a frame below this sky quality (mag/arcsec²)",
+				},
+				"cloud_cover_above": map[string]interface{}{
+					"type":        "number",
+					"description": "Optional: only sessions with a frame above this cloud cover (%)",
+				},
+				"wind_speed_above": map[string]interface{}{
+					"type":        "number",
+					"description": "Optional: only sessions with a frame above this wind speed",
+				},
+			},
+		},
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
+		log.Info().Str("tool", "conditions_report").Interface("params", args).Msg("Tool called")
+
+		request := ConditionsRequest{}
+		request.DateFrom, _ = args["date_from"].(string)
+		request.DateTo, _ = args["date_to"].(string)
+		request.Telescope, _ = args["telescope"].(string)
+		request.Camera, _ = args["camera"].(string)
+		for name, target := range map[string]**float64{
+			"humidity_above":    &request.HumidityAbove,
+			"sky_quality_below": &request.SkyQualityBelow,
+			"cloud_cover_above": &request.CloudCoverAbove,
+			"wind_speed_above":  &request.WindSpeedAbove,
+		} {
+			if v, ok := args[name].(float64); ok {
+				*target = &v
+			}
+		}
+
+		report, err := db.ConditionsReport(request)
+		if err != nil {
+			log.Error().Err(err).Str("tool", "conditions_report").Msg("Tool failed")
+			return nil, nil, fmt.Errorf("conditions report failed: %w", err)
+		}
+
+		log.Trace().Str("tool", "conditions_report").Interface("response", report).Msg("Tool response")
+
+		if len(report.Sessions) == 0 {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: "No sessions found"},
+				},
+			}, report, nil
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "%d sessions, %.2f h integration, %.2f h lost to %d rejected frames\n",
+			len(report.Sessions), report.IntegrationHours, report.RejectedHours, report.RejectedFrames)
+		for _, s := range report.Sessions {
+			fmt.Fprintf(&b, "%s %s / %s: %.2f h, %.2f h rejected", s.ObservationDate, s.Telescope, s.Camera,
+				s.IntegrationHours, s.RejectedHours)
+			c := s.Conditions
+			for _, v := range []struct {
+				label, unit string
+				value       *float64
+			}{
+				{"humidity max", "%", c.HumidityMax},
+				{"sky", " mag/arcsec²", c.SkyQualityAvg},
+				{"clouds max", "%", c.CloudCoverMax},
+				{"wind max", "", c.WindSpeedMax},
+				{"temp", "°C", c.AmbientTempAvg},
+			} {
+				if v.value != nil {
+					fmt.Fprintf(&b, ", %s %.1f%s", v.label, *v.value, v.unit)
+				}
+			}
+			b.WriteString("\n")
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: b.String()},
+			},
+		}, report, nil
+	})
+}
@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/astro"
+)
+
+func RegisterConeSearch(s *mcp.Server, db Database) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "cone_search",
+		Description: "Find frames within a radius of a sky position, sorted by angular separation. Uses the plate-solved field centre when available, otherwise the mount RA/Dec from the header. Coordinates may be decimal degrees or sexagesimal (RA in hours, e.g. '05:35:17', Dec in degrees, e.g. '-05:23:28').",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"ra": map[string]interface{}{
+					"type":        []string{"string", "number"},
+					"description": "Right Ascension: decimal degrees (83.8) or sexagesimal hours ('05:35:17.3', '5h35m17s')",
+				},
+				"dec": map[string]interface{}{
+					"type":        []string{"string", "number"},
+					"description": "Declination: decimal degrees (-5.4) or sexagesimal degrees ('-05:23:28', '-5°23′28″')",
+				},
+				"radius": map[string]interface{}{
+					"type":        "number",
+					"description": "Search radius in degrees (default: 1)",
+					"default":     1,
+				},
+				"target": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: target object name (partial match)",
+				},
+				"filter": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: filter name (exact match)",
+				},
+				"telescope": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: telescope identifier (partial match)",
+				},
+				"camera": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: camera identifier (partial match)",
+				},
+				"date_from": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: start date in ISO8601 format",
+				},
+				"date_to": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: end date in ISO8601 format",
+				},
+				"limit": map[string]interface{}{
+					"type":        "number",
+					"description": "Maximum number of results to return (default: 100, max: 1000)",
+					"default":     100,
+				},
+			},
+			"required": []string{"ra", "dec"},
+		},
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
+		log.Info().Str("tool", "cone_search").Interface("params", args).Msg("Tool called")
+
+		ra, err := coordinateArg(args, "ra", astro.ParseRA)
+		if err != nil {
+			log.Error().Err(err).Str("tool", "cone_search").Msg("Tool failed")
+			return nil, nil, err
+		}
+		dec, err := coordinateArg(args, "dec", astro.ParseDec)
+		if err != nil {
+			log.Error().Err(err).Str("tool", "cone_search").Msg("Tool failed")
+			return nil, nil, err
+		}
+
+		radius := 1.0
+		if r, ok := args["radius"].(float64); ok {
+			radius = r
+		}
+
+		limit := 100
+		if l, ok := args["limit"].(float64); ok {
+			limit = int(l)
+			if limit > 1000 {
+				limit = 1000
+			}
+		}
+
+		matches, err := db.ConeSearch(ra, dec, radius, args, limit)
+		if err != nil {
+			log.Error().Err(err).Str("tool", "cone_search").Msg("Tool failed")
+			return nil, nil, fmt.Errorf("cone search failed: %w", err)
+		}
+
+		response := map[string]interface{}{
+			"center":     map[string]float64{"ra": ra, "dec": dec},
+			"radius_deg": radius,
+			"matches":    matches,
+			"count":      len(matches),
+		}
+
+		log.Trace().Str("tool", "cone_search").Interface("response", response).Msg("Tool response")
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Found %d frames within %.2f° of RA %.4f°, Dec %.4f°", len(matches), radius, ra, dec)},
+			},
+		}, response, nil
+	})
+}
+
+// coordinateArg reads a coordinate argument that may be a JSON number
+// (decimal degrees) or a string understood by parse.
+func coordinateArg(args map[string]interface{}, name string, parse func(string) (float64, error)) (float64, error) {
+	switch v := args[name].(type) {
+	case float64:
+		return parse(fmt.Sprintf("%g", v))
+	case string:
+		return parse(v)
+	default:
+		return 0, fmt.Errorf("%s is required", name)
+	}
+}
@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+// confirmationTTL is how long the token of a destructive dry run is valid
+const confirmationTTL = 5 * time.Minute
+
+// confirmation is an outstanding confirmation token for one tool and scope,
+// issued to one client
+type confirmation struct {
+	tool    string
+	scope   string
+	owner   confirmationOwner
+	expires time.Time
+}
+
+// confirmationOwner identifies the client a token was issued to: its MCP
+// session and, on the HTTP transport, the name of its API key
+type confirmationOwner struct {
+	session *mcp.ServerSession
+	apiKey  string
+}
+
+// requestOwner returns the client that made a tool call
+func requestOwner(req *mcp.CallToolRequest) confirmationOwner {
+	if req == nil {
+		return confirmationOwner{}
+	}
+	owner := confirmationOwner{session: req.Session}
+	if req.Extra != nil && req.Extra.TokenInfo != nil {
+		owner.apiKey = req.Extra.TokenInfo.UserID
+	}
+	return owner
+}
+
+var (
+	confirmMutex  sync.Mutex
+	confirmations = map[string]confirmation{}
+)
+
+// newConfirmation returns a one-time token with which the client making req
+// confirms a call of a destructive tool on scope, and when it expires
+func newConfirmation(req *mcp.CallToolRequest, tool, scope string) (string, time.Time, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create confirmation token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+	expires := time.Now().Add(confirmationTTL)
+
+	confirmMutex.Lock()
+	defer confirmMutex.Unlock()
+	for t, c := range confirmations {
+		if time.Now().After(c.expires) {
+			delete(confirmations, t)
+		}
+	}
+	confirmations[token] = confirmation{tool: tool, scope: scope, owner: requestOwner(req), expires: expires}
+	return token, expires, nil
+}
+
+// useConfirmation consumes a token. It fails when the token is unknown,
+// expired, already used, or was issued for another tool or scope. A token of
+// another client is treated as unknown and left for its owner.
+func useConfirmation(req *mcp.CallToolRequest, token, tool, scope string) error {
+	confirmMutex.Lock()
+	defer confirmMutex.Unlock()
+
+	c, ok := confirmations[token]
+	if !ok || c.tool != tool || c.owner != requestOwner(req) {
+		return fmt.Errorf("unknown or already used confirm_token; call %s without it for a new one", tool)
+	}
+	delete(confirmations, token)
+	if time.Now().After(c.expires) {
+		return fmt.Errorf("confirm_token expired; call %s without it for a new one", tool)
+	}
+	if c.scope != scope {
+		return fmt.Errorf("confirm_token was issued for %s, not %s", c.scope, scope)
+	}
+	return nil
+}
+
+// elicitConfirmation asks the user to confirm a destructive call when the
+// client supports elicitation. asked is false when the client cannot ask,
+// so the caller falls back to a confirmation token.
+func elicitConfirmation(ctx context.Context, req *mcp.CallToolRequest, message string) (confirmed, asked bool) {
+	if req == nil || req.Session == nil {
+		return false, false
+	}
+	params := req.Session.InitializeParams()
+	if params == nil || params.Capabilities == nil || params.Capabilities.Elicitation == nil {
+		return false, false
+	}
+
+	result, err := req.Session.Elicit(ctx, &mcp.ElicitParams{
+		Message: message,
+		RequestedSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"confirm": map[string]interface{}{
+					"type":        "boolean",
+					"title":       "Confirm",
+					"description": "Check to go ahead",
+				},
+			},
+			"required": []string{"confirm"},
+		},
+	})
+	if err != nil {
+		log.Warn().Err(err).Msg("Elicitation failed, falling back to a confirmation token")
+		return false, false
+	}
+	return result.Action == "accept" && result.Content["confirm"] == true, true
+}
@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+func RegisterDeleteProject(s *mcp.Server, db Database) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "delete_project",
+		Description: "Delete an imaging project and its goals. Frames are not affected. Projects defined in the config file come back at the next start unless they are removed there.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"name": map[string]interface{}{
+					"type":        "string",
+					"description": "Project name",
+				},
+			},
+			"required": []string{"name"},
+		},
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
+		log.Info().Str("tool", "delete_project").Interface("params", args).Msg("Tool called")
+
+		name, ok := args["name"].(string)
+		if !ok || name == "" {
+			log.Error().Str("tool", "delete_project").Msg("Tool failed: name required")
+			return nil, nil, fmt.Errorf("name is required")
+		}
+
+		deleted, err := db.DeleteProject(name)
+		if err != nil {
+			log.Error().Err(err).Str("tool", "delete_project").Msg("Tool failed")
+			return nil, nil, fmt.Errorf("failed to delete project: %w", err)
+		}
+		if !deleted {
+			log.Error().Str("tool", "delete_project").Str("name", name).Msg("Tool failed: project not found")
+			return nil, nil, fmt.Errorf("project not found: %s", name)
+		}
+
+		response := map[string]interface{}{
+			"deleted": name,
+		}
+
+		log.Trace().Str("tool", "delete_project").Interface("response", response).Msg("Tool response")
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Project %q deleted", name)},
+			},
+		}, response, nil
+	})
+}
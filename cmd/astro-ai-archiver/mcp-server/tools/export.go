@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+func RegisterExport(s *mcp.Server, db Database) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "export",
+		Description: "Write query results to a CSV, JSON Lines or Parquet file in the export directory, for spreadsheets, pandas or DuckDB. Give either a read-only SELECT in sql, or query_fits_archive filters to export the matching frames with all file columns, quality metrics and grade (rejected frames are left out). Returns the file path and row count, not the rows.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"sql": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: read-only SELECT query; when given, the filters are ignored",
+				},
+				"format": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"csv", "jsonl", "parquet"},
+					"description": "Output format (default: csv)",
+					"default":     "csv",
+				},
+				"name": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: file name without extension (default: export and time stamp); existing files are not overwritten",
+				},
+				"target": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: target object name (partial match)",
+				},
+				"filter": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: filter name (exact match)",
+				},
+				"telescope": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: telescope identifier (partial match)",
+				},
+				"camera": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: camera identifier (partial match)",
+				},
+				"date_from": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: start date in ISO8601 format",
+				},
+				"date_to": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: end date in ISO8601 format",
+				},
+				"fwhm_max": map[string]interface{}{
+					"type":        "number",
+					"description": "Optional: maximum median star FWHM in pixels (analyzed frames only)",
+				},
+				"grade": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"accepted", "rejected", "ungraded"},
+					"description": "Optional: only frames with this grade",
+				},
+				"include_rejected": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Include rejected frames (default: false)",
+				},
+				"sort_by": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: sort column as in query_fits_archive",
+				},
+			},
+		},
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
+		log.Info().Str("tool", "export").Interface("params", args).Msg("Tool called")
+
+		request := DataExportRequest{Filters: args}
+		request.SQL, _ = args["sql"].(string)
+		request.Format, _ = args["format"].(string)
+		request.Name, _ = args["name"].(string)
+
+		result, err := db.ExportData(request)
+		if err != nil {
+			log.Error().Err(err).Str("tool", "export").Msg("Tool failed")
+			return nil, nil, fmt.Errorf("export failed: %w", err)
+		}
+
+		log.Trace().Str("tool", "export").Interface("response", result).Msg("Tool response")
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Exported %d rows to %s", result.Rows, result.Path)},
+			},
+		}, result, nil
+	})
+}
@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+func RegisterExportStackingSet(s *mcp.Server, db Database) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "export_stacking_set",
+		Description: "Write the selected light frames as a stacking set into a new directory: per-filter lists of absolute paths (lists/<filter>.txt), a lights/<filter> folder of symlinks for PixInsight WBPP, and a Siril script (stack.ssf) that registers and stacks each filter. Select lights by target, session or query filters; rejected frames are left out. Frames are not calibrated; use match_calibration for darks and flats.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"target": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: target object name (partial match)",
+				},
+				"session_id": map[string]interface{}{
+					"type":        "number",
+					"description": "Optional: session id from list_sessions",
+				},
+				"filter": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: filter name (exact match)",
+				},
+				"telescope": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: telescope identifier (partial match)",
+				},
+				"camera": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: camera identifier (partial match)",
+				},
+				"date_from": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: start date in ISO8601 format",
+				},
+				"date_to": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: end date in ISO8601 format",
+				},
+				"fwhm_max": map[string]interface{}{
+					"type":        "number",
+					"description": "Optional: maximum median star FWHM in pixels (analyzed frames only)",
+				},
+				"eccentricity_max": map[string]interface{}{
+					"type":        "number",
+					"description": "Optional: maximum median star eccentricity (analyzed frames only)",
+				},
+				"grade": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"accepted", "ungraded"},
+					"description": "Optional: only frames with this grade",
+				},
+				"name": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: name of the export directory (default: target and time stamp)",
+				},
+				"symlinks": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Write the lights/<filter> symlink tree (default: true)",
+					"default":     true,
+				},
+				"siril": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Write the Siril script; implies symlinks (default: true)",
+					"default":     true,
+				},
+			},
+		},
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
+		log.Info().Str("tool", "export_stacking_set").Interface("params", args).Msg("Tool called")
+
+		request := ExportRequest{Filters: args, Symlinks: true, Siril: true}
+		if id, ok := args["session_id"].(float64); ok {
+			request.SessionID = int64(id)
+		}
+		request.Name, _ = args["name"].(string)
+		if v, ok := args["symlinks"].(bool); ok {
+			request.Symlinks = v
+		}
+		if v, ok := args["siril"].(bool); ok {
+			request.Siril = v
+		}
+
+		result, err := db.ExportStackingSet(request)
+		if err != nil {
+			log.Error().Err(err).Str("tool", "export_stacking_set").Msg("Tool failed")
+			return nil, nil, fmt.Errorf("export failed: %w", err)
+		}
+
+		log.Trace().Str("tool", "export_stacking_set").Interface("response", result).Msg("Tool response")
+
+		text := fmt.Sprintf("Exported %d frames in %d filters to %s", result.Frames, len(result.Filters), result.Directory)
+		if len(result.Errors) > 0 {
+			text += fmt.Sprintf(" (%d errors)", len(result.Errors))
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+}
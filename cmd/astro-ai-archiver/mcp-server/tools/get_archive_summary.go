@@ -11,11 +11,22 @@ import (
 func RegisterGetArchiveSummary(s *mcp.Server, db Database) {
 	mcp.AddTool(s, &mcp.Tool{
 		Name:        "get_archive_summary",
-		Description: "Get summary statistics and lists of unique targets, filters, telescopes, cameras",
+		Description: "Get summary statistics and lists of unique targets, filters, telescopes, cameras. File count and total exposure leave out frames graded as rejected unless include_rejected is set.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"include_rejected": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Count frames graded as rejected in the totals (default: false)",
+					"default":     false,
+				},
+			},
+		},
 	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
-		log.Info().Str("tool", "get_archive_summary").Msg("Tool called")
+		log.Info().Str("tool", "get_archive_summary").Interface("params", args).Msg("Tool called")
 
-		summary, err := db.GetArchiveSummary()
+		includeRejected, _ := args["include_rejected"].(bool)
+		summary, err := db.GetArchiveSummary(includeRejected)
 		if err != nil {
 			log.Error().Err(err).Str("tool", "get_archive_summary").Msg("Tool failed")
 			return nil, nil, fmt.Errorf("failed to get summary: %w", err)
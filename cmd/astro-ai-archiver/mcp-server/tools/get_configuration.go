@@ -8,10 +8,10 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-func RegisterGetConfiguration(s *mcp.Server, cfg Config, dbPath string, version string) {
+func RegisterGetConfiguration(s *mcp.Server, cfg Config, dbPath string, version string, access ToolAccess) {
 	mcp.AddTool(s, &mcp.Tool{
 		Name:        "get_configuration",
-		Description: "Show current AAA configuration (scan directory, database path, etc.)",
+		Description: "Show current AAA configuration (scan directory, database path, etc.) and which tools the tool policy enables",
 	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
 		log.Info().Str("tool", "get_configuration").Msg("Tool called")
 
@@ -23,6 +23,7 @@ func RegisterGetConfiguration(s *mcp.Server, cfg Config, dbPath string, version
 			"database_path":    dbPath,
 			"log_level":        cfg.GetLoggingLevel(),
 			"log_format":       cfg.GetLoggingFormat(),
+			"tools":            access,
 		}
 
 		log.Trace().Str("tool", "get_configuration").Interface("response", config).Msg("Tool response")
@@ -30,7 +31,9 @@ func RegisterGetConfiguration(s *mcp.Server, cfg Config, dbPath string, version
 		scanDirs := cfg.GetScanDirectories()
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
-				&mcp.TextContent{Text: fmt.Sprintf("Configuration: %d scan director%s", len(scanDirs), map[bool]string{true: "y", false: "ies"}[len(scanDirs) == 1])},
+				&mcp.TextContent{Text: fmt.Sprintf("Configuration: %d scan director%s, %d of %d tools enabled%s",
+					len(scanDirs), map[bool]string{true: "y", false: "ies"}[len(scanDirs) == 1],
+					len(access.Enabled), len(access.Enabled)+len(access.Disabled), map[bool]string{true: " (read-only)", false: ""}[access.ReadOnly])},
 			},
 		}, config, nil
 	})
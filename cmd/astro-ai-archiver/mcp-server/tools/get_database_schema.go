@@ -38,8 +38,264 @@ func RegisterGetDatabaseSchema(s *mcp.Server) {
 				{"name": "offset", "type": "INTEGER", "description": "Camera offset setting"},
 				{"name": "filter", "type": "TEXT", "description": "Filter name (e.g., 'L', 'Ha', 'OIII')"},
 				{"name": "image_type", "type": "TEXT", "description": "Image type (typically 'LIGHT')"},
+				{"name": "ccd_temp", "type": "REAL", "description": "Sensor temperature in °C (CCD-TEMP)"},
+				{"name": "set_temp", "type": "REAL", "description": "Cooler set point in °C (SET-TEMP)"},
+				{"name": "x_binning", "type": "INTEGER", "description": "Horizontal binning (XBINNING)"},
+				{"name": "y_binning", "type": "INTEGER", "description": "Vertical binning (YBINNING)"},
+				{"name": "naxis1", "type": "INTEGER", "description": "Image width in pixels (NAXIS1)"},
+				{"name": "naxis2", "type": "INTEGER", "description": "Image height in pixels (NAXIS2)"},
+				{"name": "pixel_size", "type": "REAL", "description": "Pixel size in µm including binning (XPIXSZ)"},
+				{"name": "bayer_pattern", "type": "TEXT", "description": "Bayer pattern of colour sensors, e.g. 'RGGB' (BAYERPAT); empty for mono"},
+				{"name": "airmass", "type": "REAL", "description": "Airmass at exposure start (AIRMASS)"},
+				{"name": "object_alt", "type": "REAL", "description": "Target altitude in degrees (OBJCTALT)"},
+				{"name": "site_lat", "type": "REAL", "description": "Observatory latitude in degrees (SITELAT)"},
+				{"name": "site_long", "type": "REAL", "description": "Observatory longitude in degrees, east positive (SITELONG)"},
+				{"name": "focuser_position", "type": "INTEGER", "description": "Focuser step position (FOCPOS)"},
+				{"name": "focuser_temp", "type": "REAL", "description": "Focuser temperature in °C (FOCTEMP)"},
+				{"name": "pier_side", "type": "TEXT", "description": "Pier side, 'EAST' or 'WEST' (PIERSIDE)"},
+				{"name": "rotator", "type": "REAL", "description": "Mechanical rotator angle in degrees (ROTATOR)"},
+				{"name": "readout_mode", "type": "TEXT", "description": "Camera readout mode (READOUTM)"},
 			},
 			"indexes": []string{"object", "filter", "telescope", "utc_time", "hash", "relative_path"},
+			"related_tables": []map[string]interface{}{
+				{
+					"table":       "fits_wcs",
+					"description": "Plate-solved field geometry, one row per solved frame. Join with fits_files ON fits_wcs.file_id = fits_files.id",
+					"columns": []map[string]interface{}{
+						{"name": "file_id", "type": "INTEGER", "description": "References fits_files(id)"},
+						{"name": "center_ra", "type": "REAL", "description": "Solved field centre Right Ascension in degrees"},
+						{"name": "center_dec", "type": "REAL", "description": "Solved field centre Declination in degrees"},
+						{"name": "rotation", "type": "REAL", "description": "Position angle of the image Y axis in degrees east of north (0-360)"},
+						{"name": "pixel_scale", "type": "REAL", "description": "Image scale in arcseconds per pixel"},
+						{"name": "corner1_ra", "type": "REAL", "description": "Footprint corner at pixel (1,1), RA in degrees"},
+						{"name": "corner1_dec", "type": "REAL", "description": "Footprint corner at pixel (1,1), Dec in degrees"},
+						{"name": "corner2_ra", "type": "REAL", "description": "Footprint corner at pixel (NAXIS1,1), RA in degrees"},
+						{"name": "corner2_dec", "type": "REAL", "description": "Footprint corner at pixel (NAXIS1,1), Dec in degrees"},
+						{"name": "corner3_ra", "type": "REAL", "description": "Footprint corner at pixel (NAXIS1,NAXIS2), RA in degrees"},
+						{"name": "corner3_dec", "type": "REAL", "description": "Footprint corner at pixel (NAXIS1,NAXIS2), Dec in degrees"},
+						{"name": "corner4_ra", "type": "REAL", "description": "Footprint corner at pixel (1,NAXIS2), RA in degrees"},
+						{"name": "corner4_dec", "type": "REAL", "description": "Footprint corner at pixel (1,NAXIS2), Dec in degrees"},
+					},
+				},
+				{
+					"table":       "sky_index",
+					"description": "Spatial index used by cone_search, one row per frame with known coordinates. Join with fits_files ON sky_index.file_id = fits_files.id",
+					"columns": []map[string]interface{}{
+						{"name": "file_id", "type": "INTEGER", "description": "References fits_files(id)"},
+						{"name": "ra", "type": "REAL", "description": "Indexed Right Ascension in degrees (0-360)"},
+						{"name": "dec", "type": "REAL", "description": "Indexed Declination in degrees"},
+						{"name": "dec_band", "type": "INTEGER", "description": "1° declination band, floor(dec + 90)"},
+						{"name": "source", "type": "TEXT", "description": "'wcs' (plate-solved centre) or 'header' (mount RA/Dec)"},
+					},
+				},
+				{
+					"table":       "frame_metrics",
+					"description": "Image quality metrics from analyze_frames or from N.I.N.A. ImageMetaData.csv files, one row per frame. Join with fits_files ON frame_metrics.file_id = fits_files.id",
+					"columns": []map[string]interface{}{
+						{"name": "file_id", "type": "INTEGER", "description": "References fits_files(id)"},
+						{"name": "file_hash", "type": "TEXT", "description": "fits_files.hash at analysis time; metrics are stale when it differs"},
+						{"name": "background", "type": "REAL", "description": "Median sky background in ADU"},
+						{"name": "noise", "type": "REAL", "description": "Background noise in ADU (scaled median absolute deviation)"},
+						{"name": "star_count", "type": "INTEGER", "description": "Number of detected stars"},
+						{"name": "hfr", "type": "REAL", "description": "Median half-flux radius in pixels, NULL when no stars were measured"},
+						{"name": "fwhm", "type": "REAL", "description": "Median star FWHM in pixels, NULL when no stars were measured"},
+						{"name": "eccentricity", "type": "REAL", "description": "Median star eccentricity (0 = round), NULL when no stars were measured"},
+						{"name": "analyzed_at", "type": "INTEGER", "description": "Analysis time (Unix timestamp; NULL for metrics taken from N.I.N.A.)"},
+						{"name": "source", "type": "TEXT", "description": "'pixels' (analyze_frames) or 'nina' (star metrics from ImageMetaData.csv, replaced by a pixel analysis)"},
+						{"name": "guiding_rms", "type": "REAL", "description": "Total guiding RMS during the exposure in arcseconds (N.I.N.A.)"},
+						{"name": "guiding_rms_ra", "type": "REAL", "description": "RA guiding RMS in arcseconds (N.I.N.A.)"},
+						{"name": "guiding_rms_dec", "type": "REAL", "description": "Dec guiding RMS in arcseconds (N.I.N.A.)"},
+						{"name": "focuser_position", "type": "INTEGER", "description": "Focuser position in steps (N.I.N.A.)"},
+						{"name": "focuser_temp", "type": "REAL", "description": "Focuser temperature in °C (N.I.N.A.)"},
+						{"name": "adu_mean", "type": "REAL", "description": "Mean ADU (N.I.N.A.)"},
+						{"name": "adu_median", "type": "REAL", "description": "Median ADU (N.I.N.A.)"},
+						{"name": "adu_stdev", "type": "REAL", "description": "ADU standard deviation (N.I.N.A.)"},
+						{"name": "adu_min", "type": "INTEGER", "description": "Minimum ADU (N.I.N.A.)"},
+						{"name": "adu_max", "type": "INTEGER", "description": "Maximum ADU (N.I.N.A.)"},
+					},
+				},
+				{
+					"table":       "nina_acquisitions",
+					"description": "AcquisitionDetails.csv files of the N.I.N.A. Session Metadata plugin, one row per file. nina_files holds the imported CSV files (path, file_mod_time)",
+					"columns": []map[string]interface{}{
+						{"name": "relative_path", "type": "TEXT", "description": "Path of the CSV file"},
+						{"name": "target", "type": "TEXT", "description": "Target name"},
+						{"name": "ra", "type": "TEXT", "description": "Target RA as written by N.I.N.A."},
+						{"name": "dec", "type": "TEXT", "description": "Target Dec as written by N.I.N.A."},
+						{"name": "telescope", "type": "TEXT", "description": "Telescope name"},
+						{"name": "focal_length", "type": "REAL", "description": "Focal length in mm"},
+						{"name": "focal_ratio", "type": "REAL", "description": "Focal ratio"},
+						{"name": "camera", "type": "TEXT", "description": "Camera name"},
+						{"name": "pixel_size", "type": "REAL", "description": "Pixel size in µm"},
+						{"name": "bit_depth", "type": "INTEGER", "description": "Camera bit depth"},
+						{"name": "latitude", "type": "REAL", "description": "Observer latitude in degrees"},
+						{"name": "longitude", "type": "REAL", "description": "Observer longitude in degrees"},
+						{"name": "elevation", "type": "REAL", "description": "Observer elevation in metres"},
+					},
+				},
+				{
+					"table":       "frame_marks",
+					"description": "Accept/reject grades from grade_frames, keyed by file hash so they survive rescans. Join with fits_files ON frame_marks.hash = fits_files.hash",
+					"columns": []map[string]interface{}{
+						{"name": "hash", "type": "TEXT", "description": "fits_files.hash of the graded frame"},
+						{"name": "grade", "type": "TEXT", "description": "'accepted' or 'rejected'"},
+						{"name": "reason", "type": "TEXT", "description": "Why the frame was graded"},
+						{"name": "marked_at", "type": "INTEGER", "description": "Grading time (Unix timestamp)"},
+					},
+				},
+				{
+					"table":       "sessions",
+					"description": "Imaging sessions, one row per observation_date, telescope and camera, refreshed after every scan. Match frames ON fits_files.observation_date = sessions.observation_date AND fits_files.telescope = sessions.telescope AND fits_files.camera = sessions.camera",
+					"columns": []map[string]interface{}{
+						{"name": "id", "type": "INTEGER", "description": "Session id"},
+						{"name": "observation_date", "type": "TEXT", "description": "Night of the session (YYYY-MM-DD)"},
+						{"name": "telescope", "type": "TEXT", "description": "Telescope ('' when unknown)"},
+						{"name": "camera", "type": "TEXT", "description": "Camera ('' when unknown)"},
+						{"name": "start_time", "type": "TEXT", "description": "UTC start of the first frame"},
+						{"name": "end_time", "type": "TEXT", "description": "UTC end of the last frame"},
+						{"name": "targets", "type": "TEXT", "description": "Sorted target names, one per line (separated by char(10))"},
+						{"name": "filters", "type": "TEXT", "description": "Sorted filter names, one per line (separated by char(10))"},
+						{"name": "frame_count", "type": "INTEGER", "description": "Number of frames"},
+						{"name": "integration", "type": "REAL", "description": "Total exposure in seconds"},
+						{"name": "gap_count", "type": "INTEGER", "description": "Pauses of more than 10 minutes between frames"},
+						{"name": "gap_seconds", "type": "REAL", "description": "Total length of those pauses in seconds"},
+						{"name": "longest_gap", "type": "REAL", "description": "Longest pause in seconds"},
+						{"name": "site_lat", "type": "REAL", "description": "Session site latitude (frames' SITELAT, else the configured site)"},
+						{"name": "site_long", "type": "REAL", "description": "Session site longitude, east positive"},
+						{"name": "dark_start", "type": "TEXT", "description": "Start of astronomical darkness (UTC), NULL without a site or darkness"},
+						{"name": "dark_end", "type": "TEXT", "description": "End of astronomical darkness (UTC)"},
+						{"name": "dark_seconds", "type": "REAL", "description": "Length of astronomical darkness in seconds"},
+						{"name": "dark_integration", "type": "REAL", "description": "Exposure seconds within darkness"},
+						{"name": "lost_seconds", "type": "REAL", "description": "Gap seconds within darkness"},
+						{"name": "signature", "type": "TEXT", "description": "Internal change marker used by the incremental refresh"},
+					},
+				},
+				{
+					"table":       "projects",
+					"description": "Imaging projects from save_project or the config file. Goals are in project_goals",
+					"columns": []map[string]interface{}{
+						{"name": "id", "type": "INTEGER", "description": "Project id"},
+						{"name": "name", "type": "TEXT", "description": "Unique project name"},
+						{"name": "target", "type": "TEXT", "description": "Target object name (matches fits_files.object, case-insensitive)"},
+						{"name": "telescope", "type": "TEXT", "description": "Optional telescope restriction (partial match)"},
+						{"name": "camera", "type": "TEXT", "description": "Optional camera restriction (partial match)"},
+						{"name": "notes", "type": "TEXT", "description": "Free-form notes"},
+						{"name": "source", "type": "TEXT", "description": "'tool' or 'config'"},
+						{"name": "added_at", "type": "INTEGER", "description": "Creation time (Unix timestamp)"},
+					},
+				},
+				{
+					"table":       "project_goals",
+					"description": "Integration goals per project and filter. Join with projects ON project_goals.project_id = projects.id",
+					"columns": []map[string]interface{}{
+						{"name": "project_id", "type": "INTEGER", "description": "References projects(id)"},
+						{"name": "filter", "type": "TEXT", "description": "Filter name"},
+						{"name": "hours", "type": "REAL", "description": "Integration goal in hours"},
+					},
+				},
+				{
+					"table":       "calibration_files",
+					"description": "Calibration frames (darks, flats, bias, dark flats and masters) indexed during scans; used by match_calibration",
+					"columns": []map[string]interface{}{
+						{"name": "id", "type": "INTEGER", "description": "Primary key"},
+						{"name": "relative_path", "type": "TEXT", "description": "Path relative to the scan directory"},
+						{"name": "hash", "type": "TEXT", "description": "File content hash"},
+						{"name": "file_mod_time", "type": "INTEGER", "description": "File modification time (Unix timestamp)"},
+						{"name": "frame_type", "type": "TEXT", "description": "'DARK', 'FLAT', 'BIAS' or 'DARKFLAT'"},
+						{"name": "master", "type": "INTEGER", "description": "1 for master frames"},
+						{"name": "telescope", "type": "TEXT", "description": "Telescope"},
+						{"name": "camera", "type": "TEXT", "description": "Camera"},
+						{"name": "filter", "type": "TEXT", "description": "Filter (flats)"},
+						{"name": "exposure", "type": "REAL", "description": "Exposure in seconds"},
+						{"name": "gain", "type": "REAL", "description": "Camera gain"},
+						{"name": "offset", "type": "INTEGER", "description": "Camera offset"},
+						{"name": "x_binning", "type": "INTEGER", "description": "XBINNING"},
+						{"name": "y_binning", "type": "INTEGER", "description": "YBINNING"},
+						{"name": "ccd_temp", "type": "REAL", "description": "Sensor temperature in °C"},
+						{"name": "set_temp", "type": "REAL", "description": "Cooler set point in °C"},
+						{"name": "naxis1", "type": "INTEGER", "description": "Image width in pixels"},
+						{"name": "naxis2", "type": "INTEGER", "description": "Image height in pixels"},
+						{"name": "readout_mode", "type": "TEXT", "description": "Camera readout mode"},
+						{"name": "rotator", "type": "REAL", "description": "Rotator angle in degrees"},
+						{"name": "utc_time", "type": "TEXT", "description": "Capture time (UTC)"},
+						{"name": "observation_date", "type": "TEXT", "description": "Night of capture (YYYY-MM-DD)"},
+						{"name": "indexed_at", "type": "INTEGER", "description": "Indexing time (Unix timestamp)"},
+					},
+				},
+				{
+					"table":       "guide_sessions",
+					"description": "PHD2 guide sessions from guide logs imported during scans. guide_logs holds the log files (id, path, file_mod_time)",
+					"columns": []map[string]interface{}{
+						{"name": "id", "type": "INTEGER", "description": "Primary key"},
+						{"name": "log_id", "type": "INTEGER", "description": "References guide_logs(id)"},
+						{"name": "night", "type": "TEXT", "description": "Local evening date of the session start (YYYY-MM-DD)"},
+						{"name": "mount", "type": "TEXT", "description": "Mount name as written by PHD2"},
+						{"name": "pixel_scale", "type": "REAL", "description": "Guide camera scale in arcseconds per pixel"},
+						{"name": "start_time", "type": "TEXT", "description": "Guiding start (UTC)"},
+						{"name": "end_time", "type": "TEXT", "description": "Guiding end (UTC)"},
+						{"name": "samples", "type": "INTEGER", "description": "Guide steps"},
+						{"name": "rms_ra", "type": "REAL", "description": "RA RMS in arcseconds"},
+						{"name": "rms_dec", "type": "REAL", "description": "Dec RMS in arcseconds"},
+						{"name": "rms_total", "type": "REAL", "description": "Total RMS in arcseconds"},
+						{"name": "dithers", "type": "INTEGER", "description": "Dithers"},
+					},
+				},
+				{
+					"table":       "guide_samples",
+					"description": "PHD2 guide steps; guide_events (session_id, time, kind, detail) holds dithers and settling with kind 'dither', 'settle_start', 'settle_done' or 'settle_failed'",
+					"columns": []map[string]interface{}{
+						{"name": "session_id", "type": "INTEGER", "description": "References guide_sessions(id)"},
+						{"name": "time", "type": "REAL", "description": "Unix seconds (UTC)"},
+						{"name": "ra", "type": "REAL", "description": "Raw RA offset in guide camera pixels"},
+						{"name": "dec", "type": "REAL", "description": "Raw Dec offset in guide camera pixels"},
+					},
+				},
+				{
+					"table":       "frame_guiding",
+					"description": "Guiding during each light frame's exposure. Join with fits_files ON frame_guiding.file_id = fits_files.id",
+					"columns": []map[string]interface{}{
+						{"name": "file_id", "type": "INTEGER", "description": "References fits_files(id)"},
+						{"name": "guide_session_id", "type": "INTEGER", "description": "References guide_sessions(id)"},
+						{"name": "samples", "type": "INTEGER", "description": "Guide steps during the exposure (0 = none)"},
+						{"name": "rms_ra", "type": "REAL", "description": "RA RMS in arcseconds"},
+						{"name": "rms_dec", "type": "REAL", "description": "Dec RMS in arcseconds"},
+						{"name": "rms_total", "type": "REAL", "description": "Total RMS in arcseconds"},
+						{"name": "dithers", "type": "INTEGER", "description": "Dithers during the exposure"},
+					},
+				},
+				{
+					"table":       "frame_ephemeris",
+					"description": "Moon and target geometry at mid-exposure, computed offline from utc_time, the frame position and the site. Join with fits_files ON frame_ephemeris.file_id = fits_files.id",
+					"columns": []map[string]interface{}{
+						{"name": "file_id", "type": "INTEGER", "description": "References fits_files(id)"},
+						{"name": "moon_illumination", "type": "REAL", "description": "Illuminated fraction of the Moon, 0 = new, 1 = full"},
+						{"name": "moon_alt", "type": "REAL", "description": "Moon altitude in degrees, negative below the horizon"},
+						{"name": "moon_separation", "type": "REAL", "description": "Angle between the Moon and the target in degrees"},
+						{"name": "target_alt", "type": "REAL", "description": "Target altitude in degrees"},
+						{"name": "target_az", "type": "REAL", "description": "Target azimuth in degrees from north through east"},
+						{"name": "airmass", "type": "REAL", "description": "Airmass, NULL below the horizon"},
+					},
+				},
+				{
+					"table":       "frame_conditions",
+					"description": "Observing conditions from the frame headers, for frames that recorded any. Join with fits_files ON frame_conditions.file_id = fits_files.id",
+					"columns": []map[string]interface{}{
+						{"name": "file_id", "type": "INTEGER", "description": "References fits_files(id)"},
+						{"name": "ambient_temp", "type": "REAL", "description": "Ambient temperature in °C (AMBTEMP)"},
+						{"name": "humidity", "type": "REAL", "description": "Relative humidity in % (HUMIDITY)"},
+						{"name": "pressure", "type": "REAL", "description": "Air pressure in hPa (PRESSURE)"},
+						{"name": "dew_point", "type": "REAL", "description": "Dew point in °C (DEWPOINT)"},
+						{"name": "sky_quality", "type": "REAL", "description": "Sky brightness in mag/arcsec², higher is darker (SKYQUAL or MPSAS)"},
+						{"name": "cloud_cover", "type": "REAL", "description": "Cloud cover in % (CLOUDCVR)"},
+						{"name": "wind_speed", "type": "REAL", "description": "Wind speed (WINDSPD)"},
+					},
+				},
+				{
+					"table":       "usable_files",
+					"description": "View with all fits_files columns for frames not graded as rejected. Use it instead of fits_files for integration totals.",
+					"columns":     []map[string]interface{}{},
+				},
+			},
 		}
 
 		log.Trace().Str("tool", "get_database_schema").Interface("response", schema).Msg("Tool response")
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+func RegisterGetFramePreview(s *mcp.Server, db Database) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "get_frame_preview",
+		Description: "Show a frame: returns an auto-stretched (STF) preview image of a FITS file, debayered for colour sensors. Identify the frame by file_id or file_path (relative path as returned by query_fits_archive). The file is also available as resource fits://preview/{id}.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"file_id": map[string]interface{}{
+					"type":        "number",
+					"description": "Database id of the frame",
+				},
+				"file_path": map[string]interface{}{
+					"type":        "string",
+					"description": "Relative path of the frame",
+				},
+				"size": map[string]interface{}{
+					"type":        "number",
+					"description": "Longest side of the preview in pixels (default: 1024, max: 4096)",
+					"default":     1024,
+				},
+				"format": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"jpeg", "png"},
+					"description": "Image format (default: jpeg)",
+					"default":     "jpeg",
+				},
+			},
+		},
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
+		log.Info().Str("tool", "get_frame_preview").Interface("params", args).Msg("Tool called")
+
+		var fileID int64
+		if id, ok := args["file_id"].(float64); ok {
+			fileID = int64(id)
+		} else if path, ok := args["file_path"].(string); ok && path != "" {
+			id, err := db.ResolveFileID(path)
+			if err != nil {
+				log.Error().Err(err).Str("tool", "get_frame_preview").Msg("Tool failed")
+				return nil, nil, fmt.Errorf("failed to get file: %w", err)
+			}
+			if id == 0 {
+				log.Error().Str("tool", "get_frame_preview").Str("path", path).Msg("Tool failed: file not found")
+				return nil, nil, fmt.Errorf("file not found: %s", path)
+			}
+			fileID = id
+		} else {
+			log.Error().Str("tool", "get_frame_preview").Msg("Tool failed: file_id or file_path required")
+			return nil, nil, fmt.Errorf("file_id or file_path is required")
+		}
+
+		size := 0
+		if sz, ok := args["size"].(float64); ok {
+			size = int(sz)
+		}
+		format, _ := args["format"].(string)
+
+		preview, err := db.RenderPreview(fileID, size, format)
+		if err != nil {
+			log.Error().Err(err).Str("tool", "get_frame_preview").Msg("Tool failed")
+			return nil, nil, fmt.Errorf("failed to render preview: %w", err)
+		}
+
+		log.Trace().Str("tool", "get_frame_preview").Interface("response", preview).Msg("Tool response")
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.ImageContent{Data: preview.Data, MIMEType: preview.MIMEType},
+				&mcp.TextContent{Text: fmt.Sprintf("Preview of %s (%dx%d)", preview.Path, preview.Width, preview.Height)},
+			},
+		}, preview, nil
+	})
+}
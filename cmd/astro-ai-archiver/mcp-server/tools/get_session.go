@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+func RegisterGetSession(s *mcp.Server, db Database) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "get_session",
+		Description: "Get one imaging session by id (from list_sessions) with frames and integration per target and filter, rejected frame counts, the list of acquisition gaps, and the observing conditions (temperature, humidity, sky quality, clouds, wind) when the frames recorded them",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"session_id": map[string]interface{}{
+					"type":        "number",
+					"description": "Session id as returned by list_sessions",
+				},
+			},
+			"required": []string{"session_id"},
+		},
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
+		log.Info().Str("tool", "get_session").Interface("params", args).Msg("Tool called")
+
+		id, ok := args["session_id"].(float64)
+		if !ok {
+			log.Error().Str("tool", "get_session").Msg("Tool failed: session_id required")
+			return nil, nil, fmt.Errorf("session_id is required")
+		}
+
+		session, err := db.GetSession(int64(id))
+		if err != nil {
+			log.Error().Err(err).Str("tool", "get_session").Msg("Tool failed")
+			return nil, nil, fmt.Errorf("failed to get session: %w", err)
+		}
+		if session == nil {
+			log.Error().Str("tool", "get_session").Int64("session_id", int64(id)).Msg("Tool failed: session not found")
+			return nil, nil, fmt.Errorf("session not found: %d", int64(id))
+		}
+
+		response := map[string]interface{}{
+			"session": session,
+		}
+
+		log.Trace().Str("tool", "get_session").Interface("response", response).Msg("Tool response")
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Session details retrieved"},
+			},
+		}, response, nil
+	})
+}
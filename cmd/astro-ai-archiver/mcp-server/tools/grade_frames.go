@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+// gradeControlArgs are grade_frames arguments that are not query filters
+var gradeControlArgs = map[string]bool{"grade": true, "reason": true, "file_ids": true, "file_paths": true}
+
+func RegisterGradeFrames(s *mcp.Server, db Database) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "grade_frames",
+		Description: "Mark frames as accepted or rejected, or clear their marks, in bulk. Select frames by file_ids/file_paths or by query_fits_archive filters (e.g. target plus fwhm_min to reject soft frames). Marks are keyed by file hash and survive rescans. Rejected frames are left out of get_archive_summary totals and query_fits_archive results unless include_rejected is set; in SQL use the usable_files view.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"grade": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"accepted", "rejected", "clear"},
+					"description": "Mark to set; 'clear' removes existing marks",
+				},
+				"reason": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: why the frames were graded (e.g. 'clouds', 'satellite trail')",
+				},
+				"file_ids": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "number"},
+					"description": "Optional: ids of the frames to grade",
+				},
+				"file_paths": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Optional: relative paths of the frames to grade",
+				},
+				"target": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: target object name (partial match)",
+				},
+				"filter": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: filter name (exact match)",
+				},
+				"telescope": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: telescope identifier (partial match)",
+				},
+				"camera": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: camera identifier (partial match)",
+				},
+				"date_from": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: start date in ISO8601 format",
+				},
+				"date_to": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: end date in ISO8601 format",
+				},
+				"fwhm_min": map[string]interface{}{
+					"type":        "number",
+					"description": "Optional: minimum median star FWHM in pixels",
+				},
+				"eccentricity_min": map[string]interface{}{
+					"type":        "number",
+					"description": "Optional: minimum median star eccentricity",
+				},
+				"star_count_max": map[string]interface{}{
+					"type":        "number",
+					"description": "Optional: maximum number of detected stars",
+				},
+				"airmass_min": map[string]interface{}{
+					"type":        "number",
+					"description": "Optional: minimum airmass",
+				},
+			},
+			"required": []string{"grade"},
+		},
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
+		log.Info().Str("tool", "grade_frames").Interface("params", args).Msg("Tool called")
+
+		grade, _ := args["grade"].(string)
+		reason, _ := args["reason"].(string)
+
+		selection := GradeSelection{Filters: map[string]interface{}{}}
+		for k, v := range args {
+			if !gradeControlArgs[k] {
+				selection.Filters[k] = v
+			}
+		}
+		if ids, ok := args["file_ids"].([]interface{}); ok {
+			for _, id := range ids {
+				if n, ok := id.(float64); ok {
+					selection.FileIDs = append(selection.FileIDs, int64(n))
+				}
+			}
+		}
+		if paths, ok := args["file_paths"].([]interface{}); ok {
+			for _, p := range paths {
+				path, _ := p.(string)
+				id, err := db.ResolveFileID(path)
+				if err != nil {
+					log.Error().Err(err).Str("tool", "grade_frames").Msg("Tool failed")
+					return nil, nil, fmt.Errorf("failed to look up file: %w", err)
+				}
+				if id == 0 {
+					log.Error().Str("tool", "grade_frames").Str("path", path).Msg("Tool failed")
+					return nil, nil, fmt.Errorf("file not found: %s", path)
+				}
+				selection.FileIDs = append(selection.FileIDs, id)
+			}
+		}
+
+		result, err := db.GradeFrames(selection, grade, reason)
+		if err != nil {
+			log.Error().Err(err).Str("tool", "grade_frames").Msg("Tool failed")
+			return nil, nil, fmt.Errorf("grading failed: %w", err)
+		}
+
+		log.Trace().Str("tool", "grade_frames").Interface("response", result).Msg("Tool response")
+
+		text := fmt.Sprintf("Marked %d of %d frames as %s", result.Graded, result.Matched, result.Grade)
+		if result.Grade == "clear" {
+			text = fmt.Sprintf("Cleared marks of %d of %d frames", result.Graded, result.Matched)
+		}
+		if result.NoHash > 0 {
+			text += fmt.Sprintf(" (%d skipped without file hash)", result.NoHash)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, result, nil
+	})
+}
@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+func RegisterGuidingReport(s *mcp.Server, db Database) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "guiding_report",
+		Description: "Report guiding performance from imported PHD2 guide logs, per mount and night or per mount overall: guiding hours, RA/Dec/total RMS in arcseconds, dithers, and the mean and worst RMS of light frames exposed while guiding. Guide logs are imported during scans from the scan directories and guiding.log_directories; per-frame RMS is in the frame_guiding table for SQL queries.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"group_by": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"night", "mount"},
+					"description": "Group per mount and night (default) or per mount",
+					"default":     "night",
+				},
+				"mount": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: mount name as written by PHD2 (partial match)",
+				},
+				"date_from": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: first night (YYYY-MM-DD, evening date)",
+				},
+				"date_to": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: last night (YYYY-MM-DD, evening date)",
+				},
+				"refresh": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Import new guide logs from guiding.log_directories first (default: false)",
+				},
+			},
+		},
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
+		log.Info().Str("tool", "guiding_report").Interface("params", args).Msg("Tool called")
+
+		if refresh, _ := args["refresh"].(bool); refresh {
+			if _, err := db.ImportGuideLogs(nil); err != nil {
+				log.Error().Err(err).Str("tool", "guiding_report").Msg("Tool failed")
+				return nil, nil, fmt.Errorf("guide log import failed: %w", err)
+			}
+		}
+
+		request := GuidingReportRequest{}
+		request.GroupBy, _ = args["group_by"].(string)
+		request.Mount, _ = args["mount"].(string)
+		request.DateFrom, _ = args["date_from"].(string)
+		request.DateTo, _ = args["date_to"].(string)
+
+		summaries, err := db.GuidingReport(request)
+		if err != nil {
+			log.Error().Err(err).Str("tool", "guiding_report").Msg("Tool failed")
+			return nil, nil, fmt.Errorf("guiding report failed: %w", err)
+		}
+
+		response := map[string]interface{}{
+			"count":   len(summaries),
+			"results": summaries,
+		}
+
+		log.Trace().Str("tool", "guiding_report").Interface("response", response).Msg("Tool response")
+
+		if len(summaries) == 0 {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: "No guide sessions found"},
+				},
+			}, response, nil
+		}
+
+		var b strings.Builder
+		for _, g := range summaries {
+			label := g.Mount
+			if label == "" {
+				label = "unknown mount"
+			}
+			if g.Night != "" {
+				label += " " + g.Night
+			}
+			fmt.Fprintf(&b, "%s: %.2f h guided, RMS %.2f\" (RA %.2f\", Dec %.2f\"), %d dithers",
+				label, g.Hours, g.RMSTotal, g.RMSRA, g.RMSDec, g.Dithers)
+			if g.Frames > 0 {
+				fmt.Fprintf(&b, ", %d frames (mean %.2f\", worst %.2f\")", g.Frames, g.FrameRMSMean, g.FrameRMSWorst)
+			}
+			b.WriteString("\n")
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: b.String()},
+			},
+		}, response, nil
+	})
+}
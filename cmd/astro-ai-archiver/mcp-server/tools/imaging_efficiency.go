@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+func RegisterImagingEfficiency(s *mcp.Server, db Database) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "imaging_efficiency",
+		Description: "Report how well imaging sessions used the night: hours of astronomical darkness (Sun more than 18° below the horizon, computed offline for the session's site), hours integrated in total and within darkness, efficiency (dark integration / darkness), and dark hours lost to gaps of more than 10 minutes between frames (weather, equipment). The site comes from SITELAT/SITELONG headers or the configured site; sessions without one have no darkness.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"date_from": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: first observation date (YYYY-MM-DD)",
+				},
+				"date_to": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: last observation date (YYYY-MM-DD)",
+				},
+				"telescope": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: telescope name (partial match)",
+				},
+				"camera": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: camera name (partial match)",
+				},
+			},
+		},
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
+		log.Info().Str("tool", "imaging_efficiency").Interface("params", args).Msg("Tool called")
+
+		request := EfficiencyRequest{}
+		request.DateFrom, _ = args["date_from"].(string)
+		request.DateTo, _ = args["date_to"].(string)
+		request.Telescope, _ = args["telescope"].(string)
+		request.Camera, _ = args["camera"].(string)
+
+		report, err := db.EfficiencyReport(request)
+		if err != nil {
+			log.Error().Err(err).Str("tool", "imaging_efficiency").Msg("Tool failed")
+			return nil, nil, fmt.Errorf("efficiency report failed: %w", err)
+		}
+
+		log.Trace().Str("tool", "imaging_efficiency").Interface("response", report).Msg("Tool response")
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: FormatEfficiencyReport(report)},
+			},
+		}, report, nil
+	})
+}
+
+// FormatEfficiencyReport renders an efficiency report as plain text, one
+// line per session after the totals. The imaging_efficiency prompt embeds it.
+func FormatEfficiencyReport(report *EfficiencyReport) string {
+	if len(report.Sessions) == 0 {
+		return "No sessions found"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d sessions: %.2f h of darkness, %.2f h integrated (%.2f h in darkness)",
+		len(report.Sessions), report.DarkHours, report.IntegrationHours, report.DarkIntegrationHours)
+	if report.Efficiency != nil {
+		fmt.Fprintf(&b, ", efficiency %.0f%%", *report.Efficiency*100)
+	}
+	fmt.Fprintf(&b, ", %.2f h of darkness lost to gaps\n", report.LostHours)
+	if report.WithoutSite > 0 {
+		fmt.Fprintf(&b, "%d sessions have no site (SITELAT/SITELONG or site in the config) and no darkness\n", report.WithoutSite)
+	}
+
+	for _, s := range report.Sessions {
+		fmt.Fprintf(&b, "%s %s / %s: %.2f h integrated", s.ObservationDate, s.Telescope, s.Camera, s.IntegrationHours)
+		switch {
+		case !s.HasSite:
+		case s.DarkHours == 0:
+			b.WriteString(", no astronomical darkness")
+		default:
+			fmt.Fprintf(&b, ", %.2f h dark (%s - %s UTC)", s.DarkHours, clockTime(s.DarkStart), clockTime(s.DarkEnd))
+			if s.Efficiency != nil {
+				fmt.Fprintf(&b, ", efficiency %.0f%%", *s.Efficiency*100)
+			}
+		}
+		if s.Gaps > 0 {
+			fmt.Fprintf(&b, ", %d gaps (longest %.0f min", s.Gaps, s.LongestGapMinutes)
+			if s.LostHours > 0 {
+				fmt.Fprintf(&b, ", %.2f h of darkness lost", s.LostHours)
+			}
+			b.WriteString(")")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// clockTime returns the HH:MM part of a session time stamp
+func clockTime(t string) string {
+	if len(t) >= 16 {
+		return t[11:16]
+	}
+	return t
+}
@@ -8,12 +8,34 @@ import (
 type Database interface {
 	QueryFiles(filters map[string]interface{}, limit, offset int) (interface{}, error)
 	GetFileByPath(path string) (interface{}, error)
-	GetArchiveSummary() (*ArchiveSummary, error)
+	GetArchiveSummary(includeRejected bool) (*ArchiveSummary, error)
 	ExecuteReadOnlyQuery(query string) ([]map[string]interface{}, error)
 	GetFilePath() string
 	NewScanner(directories []string, recursive, force bool) interface{}
 	DeleteAllFiles() (int64, error)
 	DeleteFilesByYear(year int) (int64, error)
+	CountAllFiles() (int64, error)
+	CountFilesByYear(year int) (int64, error)
+	Backup(reason string) (string, error)
+	ConeSearch(ra, dec, radius float64, filters map[string]interface{}, limit int) ([]ConeSearchMatch, error)
+	AnalyzeFrames(filters map[string]interface{}, force bool, limit int) (*AnalysisResult, error)
+	ResolveFileID(relativePath string) (int64, error)
+	RenderPreview(fileID int64, size int, format string) (*Preview, error)
+	GradeFrames(selection GradeSelection, grade, reason string) (*GradeResult, error)
+	ListSessions(filters map[string]interface{}, limit, offset int) (interface{}, error)
+	GetSession(id int64) (interface{}, error)
+	SaveProject(project Project) (*Project, error)
+	DeleteProject(name string) (bool, error)
+	ProjectProgress(name string) ([]*ProjectProgress, error)
+	MatchCalibration(req CalibrationRequest) (*CalibrationReport, error)
+	ExportStackingSet(req ExportRequest) (*ExportResult, error)
+	ExportData(req DataExportRequest) (*DataExportResult, error)
+	AstroBinAcquisitions(target, project string) (*AstroBinResult, error)
+	ImportGuideLogs(paths []string) (*GuideImportResult, error)
+	GuidingReport(req GuidingReportRequest) ([]GuidingSummary, error)
+	ConditionsReport(req ConditionsRequest) (*ConditionsReport, error)
+	EfficiencyReport(req EfficiencyRequest) (*EfficiencyReport, error)
+	PlanNight(req PlanRequest) (*NightPlan, error)
 }
 
 // Config interface defines methods needed by tools
@@ -24,12 +46,14 @@ type Config interface {
 	GetDatabasePath() string
 	GetLoggingLevel() string
 	GetLoggingFormat() string
+	GetToolPolicy() ToolPolicy
 }
 
 // ArchiveSummary holds summary statistics
 type ArchiveSummary struct {
 	TotalFiles       int       `json:"total_files"`
 	TotalExposure    float64   `json:"total_exposure_seconds"`
+	RejectedFiles    int       `json:"rejected_files"` // Frames graded as rejected, left out of the totals by default
 	OldestDate       time.Time `json:"oldest_observation"`
 	NewestDate       time.Time `json:"newest_observation"`
 	UniqueTargets    []string  `json:"unique_targets"`
@@ -38,6 +62,360 @@ type ArchiveSummary struct {
 	UniqueCameras    []string  `json:"unique_cameras"`
 }
 
+// ConeSearchMatch is a frame found by a cone search with its distance to the search centre
+type ConeSearchMatch struct {
+	File       interface{} `json:"file"`
+	Separation float64     `json:"separation_deg"`
+}
+
+// AnalysisResult summarises a frame analysis pass
+type AnalysisResult struct {
+	Analyzed  int      `json:"analyzed"`
+	Failed    int      `json:"failed"`
+	Remaining int      `json:"remaining"` // Matching frames still without current metrics
+	Errors    []string `json:"errors"`
+	Duration  string   `json:"duration"`
+}
+
+// Preview is a rendered, auto-stretched image of a frame
+type Preview struct {
+	FileID   int64  `json:"file_id"`
+	Path     string `json:"path"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	MIMEType string `json:"mime_type"`
+	Cached   bool   `json:"cached"`
+	Data     []byte `json:"-"`
+}
+
+// GradeSelection picks the frames to grade: explicit file ids, or all frames
+// matching query_fits_archive filters
+type GradeSelection struct {
+	FileIDs []int64
+	Filters map[string]interface{}
+}
+
+// GradeResult summarises a grading operation
+type GradeResult struct {
+	Grade   string `json:"grade"`   // "accepted", "rejected" or "clear"
+	Matched int    `json:"matched"` // Frames selected
+	Graded  int    `json:"graded"`  // Frames whose mark was set or cleared
+	NoHash  int    `json:"no_hash"` // Frames skipped because they have no content hash
+}
+
+// Project is an imaging project: a target with integration goals per filter
+type Project struct {
+	Name      string             `json:"name"`
+	Target    string             `json:"target"`              // Exact object name (case-insensitive)
+	Telescope string             `json:"telescope,omitempty"` // Optional: only count frames of this telescope (partial match)
+	Camera    string             `json:"camera,omitempty"`    // Optional: only count frames of this camera (partial match)
+	Notes     string             `json:"notes,omitempty"`
+	Goals     map[string]float64 `json:"goals"`  // Hours per filter
+	Source    string             `json:"source"` // "tool" or "config"
+}
+
+// FilterProgress is the progress of one filter goal of a project
+type FilterProgress struct {
+	Filter         string  `json:"filter"`
+	GoalHours      float64 `json:"goal_hours"`
+	DoneHours      float64 `json:"done_hours"`
+	RemainingHours float64 `json:"remaining_hours"`
+	Frames         int     `json:"frames"`
+	Percent        float64 `json:"percent"` // Capped at 100
+}
+
+// ProjectProgress is the integration collected for a project, counting
+// every frame once and leaving out rejected frames
+type ProjectProgress struct {
+	Project        Project          `json:"project"`
+	Filters        []FilterProgress `json:"filters"`
+	GoalHours      float64          `json:"goal_hours"`
+	DoneHours      float64          `json:"done_hours"` // Capped per filter at its goal
+	RemainingHours float64          `json:"remaining_hours"`
+	Percent        float64          `json:"percent"`
+	LastImaged     string           `json:"last_imaged,omitempty"` // Observation date of the newest frame
+}
+
+// CalibrationRequest selects light frames and the tolerances used to find
+// their calibration frames
+type CalibrationRequest struct {
+	SessionID         int64                  // Optional: lights of this session
+	Filters           map[string]interface{} // query_fits_archive filters
+	TempTolerance     float64                // Dark temperature tolerance in °C
+	ExposureTolerance float64                // Dark exposure tolerance in seconds
+	FlatWindowDays    int                    // Flats may be this many days before or after the lights
+}
+
+// CalibrationMatch is the calibration found for one group of lights that
+// share the same requirements
+type CalibrationMatch struct {
+	Criteria   string   `json:"criteria"`   // The requirements, e.g. "ASI2600MM gain 100 offset 50 bin 1x1 300s -10°C"
+	Lights     int      `json:"lights"`     // Light frames with these requirements
+	Candidates int      `json:"candidates"` // Matching calibration frames
+	Masters    int      `json:"masters"`    // Of which master frames
+	Files      []string `json:"files"`      // Best candidates first (masters, then closest in time), at most 20
+}
+
+// CalibrationReport lists the darks, flats and bias frames matching a set of
+// lights and the requirements that no calibration frame satisfies
+type CalibrationReport struct {
+	Lights int                `json:"lights"`
+	Darks  []CalibrationMatch `json:"darks"`
+	Flats  []CalibrationMatch `json:"flats"`
+	Bias   []CalibrationMatch `json:"bias"`
+	Gaps   []string           `json:"gaps"` // e.g. "flat: RC8 ASI2600MM Ha bin 1x1 night 2025-09-01 ±7 days"
+}
+
+// ExportRequest selects the lights of a stacking set and what to write
+type ExportRequest struct {
+	SessionID int64                  // Optional: lights of this session
+	Filters   map[string]interface{} // query_fits_archive filters
+	Name      string                 // Export directory name (default: target and time stamp)
+	Symlinks  bool                   // Write a lights/<filter> symlink tree (WBPP, Siril)
+	Siril     bool                   // Write a Siril stacking script (needs the symlink tree)
+}
+
+// ExportFilter is the part of a stacking set taken through one filter
+type ExportFilter struct {
+	Filter      string  `json:"filter"`
+	Frames      int     `json:"frames"`
+	Integration float64 `json:"integration_hours"`
+	List        string  `json:"list"` // File with the absolute paths of the frames
+}
+
+// ExportResult describes a written stacking set
+type ExportResult struct {
+	Directory   string         `json:"directory"`
+	Frames      int            `json:"frames"`
+	Filters     []ExportFilter `json:"filters"`
+	LightsDir   string         `json:"lights_dir,omitempty"`   // Symlink tree, one folder per filter
+	SirilScript string         `json:"siril_script,omitempty"` // Run with: siril-cli -s <script>
+	Errors      []string       `json:"errors"`
+}
+
+// DataExportRequest is a query, or a set of query_fits_archive filters, to
+// write to a file
+type DataExportRequest struct {
+	SQL     string                 // Read-only SELECT; takes precedence over Filters
+	Filters map[string]interface{} // query_fits_archive filters
+	Format  string                 // "csv", "jsonl" or "parquet"
+	Name    string                 // File name without extension (default: export and time stamp)
+}
+
+// DataExportResult describes a written export file
+type DataExportResult struct {
+	Path    string   `json:"path"`
+	Format  string   `json:"format"`
+	Rows    int      `json:"rows"`
+	Columns []string `json:"columns"`
+	Bytes   int64    `json:"bytes"`
+}
+
+// AstroBinSession is one row of an AstroBin acquisition CSV: the lights of
+// one night with the same filter, exposure, gain and binning
+type AstroBinSession struct {
+	Date          string   `json:"date"`
+	Filter        string   `json:"filter"`
+	FilterID      int      `json:"filter_id,omitempty"` // AstroBin filter id from the config, 0 = not mapped
+	Number        int      `json:"number"`
+	Duration      float64  `json:"duration"` // Exposure per frame in seconds
+	Binning       int      `json:"binning,omitempty"`
+	Gain          *float64 `json:"gain,omitempty"`
+	SensorCooling *float64 `json:"sensor_cooling,omitempty"` // Mean set point (or sensor temperature) in °C
+	MeanFWHM      *float64 `json:"mean_fwhm,omitempty"`      // Arcseconds; needs analyzed and plate-solved frames
+}
+
+// AstroBinResult is the acquisition table of a target in AstroBin's CSV
+// import format
+type AstroBinResult struct {
+	Target           string            `json:"target"`
+	Project          string            `json:"project,omitempty"`
+	Sessions         []AstroBinSession `json:"sessions"`
+	Frames           int               `json:"frames"`
+	IntegrationHours float64           `json:"integration_hours"`
+	UnmappedFilters  []string          `json:"unmapped_filters,omitempty"` // Filters without an AstroBin id in the config
+	CSV              string            `json:"csv"`
+}
+
+// GuideImportResult summarises a PHD2 guide log import
+type GuideImportResult struct {
+	Logs     int      `json:"logs"`    // Logs imported
+	Skipped  int      `json:"skipped"` // Unchanged logs
+	Sessions int      `json:"sessions"`
+	Samples  int      `json:"samples"`
+	Frames   int      `json:"frames"` // Light frames correlated with guiding data
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// GuidingReportRequest selects and groups guide sessions for a report
+type GuidingReportRequest struct {
+	GroupBy  string // "night" (default: per mount and night) or "mount"
+	Mount    string // Partial match
+	DateFrom string // First night (YYYY-MM-DD)
+	DateTo   string // Last night (YYYY-MM-DD)
+}
+
+// GuidingSummary is the guiding performance of a mount, on one night or
+// overall. RMS values are in arcseconds.
+type GuidingSummary struct {
+	Mount         string  `json:"mount"`
+	Night         string  `json:"night,omitempty"`
+	Sessions      int     `json:"sessions"`
+	Hours         float64 `json:"hours"`
+	Samples       int     `json:"samples"`
+	Dithers       int     `json:"dithers"`
+	RMSRA         float64 `json:"rms_ra"`
+	RMSDec        float64 `json:"rms_dec"`
+	RMSTotal      float64 `json:"rms_total"`
+	Frames        int     `json:"frames"` // Light frames exposed while guiding
+	FrameRMSMean  float64 `json:"frame_rms_mean"`
+	FrameRMSWorst float64 `json:"frame_rms_worst"`
+}
+
+// ConditionsRequest selects sessions for a conditions report. A threshold
+// keeps only sessions with at least one frame beyond it.
+type ConditionsRequest struct {
+	DateFrom        string // First observation date (YYYY-MM-DD)
+	DateTo          string // Last observation date (YYYY-MM-DD)
+	Telescope       string // Partial match
+	Camera          string // Partial match
+	HumidityAbove   *float64
+	SkyQualityBelow *float64
+	CloudCoverAbove *float64
+	WindSpeedAbove  *float64
+}
+
+// SessionConditions summarises the observing conditions of a session's
+// frames. Values are nil when no frame recorded them.
+type SessionConditions struct {
+	Frames         int      `json:"frames"` // Frames with conditions in their headers
+	AmbientTempAvg *float64 `json:"ambient_temp_avg,omitempty"`
+	AmbientTempMin *float64 `json:"ambient_temp_min,omitempty"`
+	AmbientTempMax *float64 `json:"ambient_temp_max,omitempty"`
+	HumidityAvg    *float64 `json:"humidity_avg,omitempty"`
+	HumidityMax    *float64 `json:"humidity_max,omitempty"`
+	DewPointAvg    *float64 `json:"dew_point_avg,omitempty"`
+	PressureAvg    *float64 `json:"pressure_avg,omitempty"`
+	SkyQualityAvg  *float64 `json:"sky_quality_avg,omitempty"`
+	SkyQualityMin  *float64 `json:"sky_quality_min,omitempty"` // Brightest sky, mag/arcsec²
+	CloudCoverAvg  *float64 `json:"cloud_cover_avg,omitempty"`
+	CloudCoverMax  *float64 `json:"cloud_cover_max,omitempty"`
+	WindSpeedAvg   *float64 `json:"wind_speed_avg,omitempty"`
+	WindSpeedMax   *float64 `json:"wind_speed_max,omitempty"`
+}
+
+// ConditionsSession is a session with its frame quality and conditions
+type ConditionsSession struct {
+	SessionID        int64             `json:"session_id"`
+	ObservationDate  string            `json:"observation_date"`
+	Telescope        string            `json:"telescope"`
+	Camera           string            `json:"camera"`
+	Frames           int               `json:"frames"`
+	IntegrationHours float64           `json:"integration_hours"`
+	RejectedFrames   int               `json:"rejected_frames"`
+	RejectedHours    float64           `json:"rejected_hours"` // Integration lost to frames graded as rejected
+	MeanFWHM         *float64          `json:"mean_fwhm,omitempty"`
+	Conditions       SessionConditions `json:"conditions"`
+}
+
+// ConditionsReport lists the selected sessions with their totals
+type ConditionsReport struct {
+	Sessions         []ConditionsSession `json:"sessions"`
+	Frames           int                 `json:"frames"`
+	IntegrationHours float64             `json:"integration_hours"`
+	RejectedFrames   int                 `json:"rejected_frames"`
+	RejectedHours    float64             `json:"rejected_hours"`
+}
+
+// EfficiencyRequest selects sessions for an imaging efficiency report
+type EfficiencyRequest struct {
+	DateFrom  string // First observation date (YYYY-MM-DD)
+	DateTo    string // Last observation date (YYYY-MM-DD)
+	Telescope string // Partial match
+	Camera    string // Partial match
+}
+
+// EfficiencySession is how a session used the astronomical darkness of its
+// night. Darkness values are 0 for sessions without a site.
+type EfficiencySession struct {
+	SessionID            int64    `json:"session_id"`
+	ObservationDate      string   `json:"observation_date"`
+	Telescope            string   `json:"telescope"`
+	Camera               string   `json:"camera"`
+	HasSite              bool     `json:"has_site"`
+	DarkStart            string   `json:"dark_start,omitempty"` // UTC
+	DarkEnd              string   `json:"dark_end,omitempty"`
+	DarkHours            float64  `json:"dark_hours"`
+	IntegrationHours     float64  `json:"integration_hours"`      // Sum of exposures
+	DarkIntegrationHours float64  `json:"dark_integration_hours"` // Exposure time within darkness
+	Efficiency           *float64 `json:"efficiency,omitempty"`   // Dark integration / darkness, 0-1
+	Gaps                 int      `json:"gaps"`
+	LostHours            float64  `json:"lost_hours"` // Gap time within darkness
+	LongestGapMinutes    float64  `json:"longest_gap_minutes"`
+}
+
+// EfficiencyReport lists sessions with totals over those with a site
+type EfficiencyReport struct {
+	Sessions             []EfficiencySession `json:"sessions"`
+	DarkHours            float64             `json:"dark_hours"`
+	IntegrationHours     float64             `json:"integration_hours"` // All sessions
+	DarkIntegrationHours float64             `json:"dark_integration_hours"`
+	Efficiency           *float64            `json:"efficiency,omitempty"`
+	LostHours            float64             `json:"lost_hours"`
+	WithoutSite          int                 `json:"without_site"` // Sessions left out of the darkness totals
+}
+
+// PlanRequest describes the night to plan for
+type PlanRequest struct {
+	Date           string   // Evening date (YYYY-MM-DD), default: tonight
+	Latitude       *float64 // Site, default: the configured site
+	Longitude      *float64
+	MinAltitude    float64 // Degrees
+	IncludeArchive bool    // Also list archived targets without a project
+	Limit          int
+}
+
+// PlannedFilter is the integration of a planned target in one filter
+type PlannedFilter struct {
+	Filter         string  `json:"filter"`
+	DoneHours      float64 `json:"done_hours"`
+	GoalHours      float64 `json:"goal_hours,omitempty"`
+	RemainingHours float64 `json:"remaining_hours,omitempty"`
+}
+
+// PlannedTarget is a target that is above the minimum altitude during
+// darkness. Times are UTC; angles are in degrees.
+type PlannedTarget struct {
+	Target          string          `json:"target"`
+	Project         string          `json:"project,omitempty"`
+	RA              float64         `json:"ra"`
+	Dec             float64         `json:"dec"`
+	Position        string          `json:"position"` // "archive" (indexed frames) or "catalog"
+	WindowStart     string          `json:"window_start"`
+	WindowEnd       string          `json:"window_end"`
+	ObservableHours float64         `json:"observable_hours"`
+	MaxAltitude     float64         `json:"max_altitude"`
+	MoonSeparation  float64         `json:"moon_separation"` // At the middle of the window
+	DoneHours       float64         `json:"done_hours"`
+	RemainingHours  float64         `json:"remaining_hours"` // Towards the project goals, 0 without a project
+	Filters         []PlannedFilter `json:"filters"`         // Most needed first
+}
+
+// NightPlan lists the targets worth imaging on a night, most needed first
+type NightPlan struct {
+	Date             string          `json:"date"`
+	Latitude         float64         `json:"latitude"`
+	Longitude        float64         `json:"longitude"`
+	DarkStart        string          `json:"dark_start,omitempty"` // UTC
+	DarkEnd          string          `json:"dark_end,omitempty"`
+	DarkHours        float64         `json:"dark_hours"`
+	MoonIllumination float64         `json:"moon_illumination"` // 0 = new, 1 = full
+	MinAltitude      float64         `json:"min_altitude"`
+	Targets          []PlannedTarget `json:"targets"`
+	NotVisible       int             `json:"not_visible"`          // Candidates below the minimum altitude all night
+	Unresolved       []string        `json:"unresolved,omitempty"` // Targets without a known position
+}
+
 // ScanResult holds scan operation results
 type ScanResult struct {
 	FilesAdded   int
@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+func RegisterListSessions(s *mcp.Server, db Database) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "list_sessions",
+		Description: "List imaging sessions, newest first. A session is one night (observation_date) with one telescope and camera, with start/end time (UTC), targets, filters, frame count, integration seconds and acquisition gaps longer than 10 minutes. Use get_session for the per-target/filter breakdown of a session.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"date_from": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: first observation date (YYYY-MM-DD)",
+				},
+				"date_to": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: last observation date (YYYY-MM-DD)",
+				},
+				"target": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: sessions that imaged this target (partial match)",
+				},
+				"filter": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: sessions that used this filter (exact match)",
+				},
+				"telescope": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: telescope identifier (partial match)",
+				},
+				"camera": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: camera identifier (partial match)",
+				},
+				"limit": map[string]interface{}{
+					"type":        "number",
+					"description": "Maximum number of sessions to return (default: 50, max: 1000)",
+					"default":     50,
+				},
+				"offset": map[string]interface{}{
+					"type":        "number",
+					"description": "Number of sessions to skip for pagination (default: 0)",
+					"default":     0,
+				},
+			},
+		},
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
+		log.Info().Str("tool", "list_sessions").Interface("params", args).Msg("Tool called")
+
+		limit := 50
+		offset := 0
+		if l, ok := args["limit"].(float64); ok && l > 0 {
+			limit = min(int(l), 1000)
+		}
+		if o, ok := args["offset"].(float64); ok {
+			offset = int(o)
+		}
+
+		sessions, err := db.ListSessions(args, limit, offset)
+		if err != nil {
+			log.Error().Err(err).Str("tool", "list_sessions").Msg("Tool failed")
+			return nil, nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+
+		count := 0
+		if v := reflect.ValueOf(sessions); v.Kind() == reflect.Slice {
+			count = v.Len()
+		}
+
+		response := map[string]interface{}{
+			"sessions": sessions,
+			"count":    count,
+			"limit":    limit,
+			"offset":   offset,
+		}
+
+		log.Trace().Str("tool", "list_sessions").Interface("response", response).Msg("Tool response")
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Found %d sessions", count)},
+			},
+		}, response, nil
+	})
+}
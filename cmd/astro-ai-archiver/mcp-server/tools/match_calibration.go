@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+func RegisterMatchCalibration(s *mcp.Server, db Database) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "match_calibration",
+		Description: "Find darks, flats and bias frames for a set of light frames selected by target, session or query filters. Darks match camera, gain, offset, binning, exposure and temperature within tolerance; bias frames camera, gain, offset and binning; flats telescope, camera, filter and binning within a window of days around each night. Lights are grouped by their requirements; 'gaps' lists the requirements without any matching calibration frame. Calibration frames are indexed during scans from their IMAGETYP.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"target": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: target object name (partial match)",
+				},
+				"session_id": map[string]interface{}{
+					"type":        "number",
+					"description": "Optional: session id from list_sessions",
+				},
+				"filter": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: filter name (exact match)",
+				},
+				"telescope": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: telescope identifier (partial match)",
+				},
+				"camera": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: camera identifier (partial match)",
+				},
+				"date_from": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: start date in ISO8601 format",
+				},
+				"date_to": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: end date in ISO8601 format",
+				},
+				"temp_tolerance": map[string]interface{}{
+					"type":        "number",
+					"description": "Dark temperature tolerance in °C (default: 2)",
+					"default":     2,
+				},
+				"exposure_tolerance": map[string]interface{}{
+					"type":        "number",
+					"description": "Dark exposure tolerance in seconds (default: 0.5)",
+					"default":     0.5,
+				},
+				"flat_window_days": map[string]interface{}{
+					"type":        "number",
+					"description": "Accept flats taken up to this many days before or after a night (default: 7)",
+					"default":     7,
+				},
+			},
+		},
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
+		log.Info().Str("tool", "match_calibration").Interface("params", args).Msg("Tool called")
+
+		request := CalibrationRequest{Filters: args}
+		if id, ok := args["session_id"].(float64); ok {
+			request.SessionID = int64(id)
+		}
+		if t, ok := args["temp_tolerance"].(float64); ok {
+			request.TempTolerance = t
+		}
+		if t, ok := args["exposure_tolerance"].(float64); ok {
+			request.ExposureTolerance = t
+		}
+		if d, ok := args["flat_window_days"].(float64); ok {
+			request.FlatWindowDays = int(d)
+		}
+
+		report, err := db.MatchCalibration(request)
+		if err != nil {
+			log.Error().Err(err).Str("tool", "match_calibration").Msg("Tool failed")
+			return nil, nil, fmt.Errorf("calibration matching failed: %w", err)
+		}
+
+		log.Trace().Str("tool", "match_calibration").Interface("response", report).Msg("Tool response")
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("%d lights need %d dark, %d flat and %d bias sets; %d without calibration",
+					report.Lights, len(report.Darks), len(report.Flats), len(report.Bias), len(report.Gaps))},
+			},
+		}, report, nil
+	})
+}
@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+func RegisterPlanNight(s *mcp.Server, db Database) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "plan_night",
+		Description: "Plan a night of imaging, computed offline: lists projects and archived targets that rise above a minimum altitude during astronomical darkness at a site, with their observable window (UTC), highest altitude, Moon separation and the integration they still need per filter. Projects are ranked by remaining hours towards their goals, archived targets without a project by how long they can be imaged. Positions come from the indexed frames of a target, else from a bundled catalog of Messier, NGC, IC and Sharpless objects.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"date": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: evening date of the night (YYYY-MM-DD, default: tonight)",
+				},
+				"latitude": map[string]interface{}{
+					"type":        "number",
+					"description": "Optional: site latitude in degrees, north positive (default: the configured site)",
+				},
+				"longitude": map[string]interface{}{
+					"type":        "number",
+					"description": "Optional: site longitude in degrees, east positive (default: the configured site)",
+				},
+				"min_altitude": map[string]interface{}{
+					"type":        "number",
+					"description": "Minimum altitude in degrees (default: 30)",
+					"default":     30,
+				},
+				"include_archive": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Also list archived targets that have no project (default: true)",
+					"default":     true,
+				},
+				"limit": map[string]interface{}{
+					"type":        "number",
+					"description": "Maximum number of targets to return (default: 25)",
+					"default":     25,
+				},
+			},
+		},
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
+		log.Info().Str("tool", "plan_night").Interface("params", args).Msg("Tool called")
+
+		request := PlanRequest{MinAltitude: 30, IncludeArchive: true, Limit: 25}
+		request.Date, _ = args["date"].(string)
+		if v, ok := args["latitude"].(float64); ok {
+			request.Latitude = &v
+		}
+		if v, ok := args["longitude"].(float64); ok {
+			request.Longitude = &v
+		}
+		if (request.Latitude == nil) != (request.Longitude == nil) {
+			err := fmt.Errorf("latitude and longitude must be given together")
+			log.Error().Err(err).Str("tool", "plan_night").Msg("Tool failed")
+			return nil, nil, err
+		}
+		if v, ok := args["min_altitude"].(float64); ok {
+			request.MinAltitude = v
+		}
+		if v, ok := args["include_archive"].(bool); ok {
+			request.IncludeArchive = v
+		}
+		if v, ok := args["limit"].(float64); ok && v > 0 {
+			request.Limit = int(v)
+		}
+
+		plan, err := db.PlanNight(request)
+		if err != nil {
+			log.Error().Err(err).Str("tool", "plan_night").Msg("Tool failed")
+			return nil, nil, fmt.Errorf("night plan failed: %w", err)
+		}
+
+		log.Trace().Str("tool", "plan_night").Interface("response", plan).Msg("Tool response")
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: formatNightPlan(plan)},
+			},
+		}, plan, nil
+	})
+}
+
+// formatNightPlan renders a night plan as plain text, one line per target
+func formatNightPlan(plan *NightPlan) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Night of %s at %.2f°, %.2f°: ", plan.Date, plan.Latitude, plan.Longitude)
+	if plan.DarkHours == 0 {
+		b.WriteString("no astronomical darkness\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "%.2f h of darkness (%s - %s UTC), Moon %.0f%% illuminated\n",
+		plan.DarkHours, clockTime(plan.DarkStart), clockTime(plan.DarkEnd), plan.MoonIllumination*100)
+
+	if len(plan.Targets) == 0 {
+		fmt.Fprintf(&b, "No targets above %.0f°\n", plan.MinAltitude)
+	}
+	for _, t := range plan.Targets {
+		b.WriteString(t.Target)
+		if t.Project != "" {
+			fmt.Fprintf(&b, " (project %s)", t.Project)
+		}
+		fmt.Fprintf(&b, ": above %.0f° %s - %s UTC (%.2f h, max %.0f°), Moon %.0f° away",
+			plan.MinAltitude, clockTime(t.WindowStart), clockTime(t.WindowEnd), t.ObservableHours, t.MaxAltitude, t.MoonSeparation)
+		if t.Project != "" {
+			fmt.Fprintf(&b, ", %.2f h remaining", t.RemainingHours)
+		} else {
+			fmt.Fprintf(&b, ", %.2f h in the archive", t.DoneHours)
+		}
+		for i, f := range t.Filters {
+			if i == 0 {
+				b.WriteString(" [")
+			} else {
+				b.WriteString(", ")
+			}
+			if f.GoalHours > 0 {
+				fmt.Fprintf(&b, "%s %.2f/%.2f h", f.Filter, f.DoneHours, f.GoalHours)
+			} else {
+				fmt.Fprintf(&b, "%s %.2f h", f.Filter, f.DoneHours)
+			}
+			if i == len(t.Filters)-1 {
+				b.WriteString("]")
+			}
+		}
+		b.WriteString("\n")
+	}
+	if plan.NotVisible > 0 {
+		fmt.Fprintf(&b, "%d targets stay below %.0f° in darkness\n", plan.NotVisible, plan.MinAltitude)
+	}
+	if len(plan.Unresolved) > 0 {
+		fmt.Fprintf(&b, "No position known for: %s\n", strings.Join(plan.Unresolved, ", "))
+	}
+	return b.String()
+}
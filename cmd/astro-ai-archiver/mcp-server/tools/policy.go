@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ToolPolicy limits the tools a server offers. A tool is offered when it is
+// in Allow (or Allow is empty), not in Deny, and read-only when ReadOnly is
+// set.
+type ToolPolicy struct {
+	ReadOnly bool
+	Allow    []string
+	Deny     []string
+}
+
+// IsZero reports whether the policy allows every tool
+func (p ToolPolicy) IsZero() bool {
+	return !p.ReadOnly && len(p.Allow) == 0 && len(p.Deny) == 0
+}
+
+// Allows reports whether the policy offers a tool
+func (p ToolPolicy) Allows(name string) bool {
+	if p.ReadOnly && !isReadOnlyTool(name) {
+		return false
+	}
+	if len(p.Allow) > 0 && !containsTool(p.Allow, name) {
+		return false
+	}
+	return !containsTool(p.Deny, name)
+}
+
+// Validate rejects tool names that do not exist, so that a typo in a deny
+// list does not leave a tool enabled
+func (p ToolPolicy) Validate() error {
+	for _, name := range append(append([]string{}, p.Allow...), p.Deny...) {
+		if registrationOf(name) == nil {
+			return fmt.Errorf("unknown tool %q (tools: %s)", name, strings.Join(ToolNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// ToolAccess is what get_configuration reports about the tool policies of
+// a server
+type ToolAccess struct {
+	ReadOnly bool     `json:"read_only"`
+	Allow    []string `json:"allow,omitempty"`
+	Deny     []string `json:"deny,omitempty"`
+	Enabled  []string `json:"enabled"`
+	Disabled []string `json:"disabled"`
+}
+
+// toolAccess applies policies, which must all allow a tool
+func toolAccess(policies []ToolPolicy) ToolAccess {
+	access := ToolAccess{Enabled: []string{}, Disabled: []string{}}
+	for _, p := range policies {
+		access.ReadOnly = access.ReadOnly || p.ReadOnly
+		access.Allow = append(access.Allow, p.Allow...)
+		access.Deny = append(access.Deny, p.Deny...)
+	}
+	for _, name := range ToolNames() {
+		allowed := true
+		for _, p := range policies {
+			allowed = allowed && p.Allows(name)
+		}
+		if allowed {
+			access.Enabled = append(access.Enabled, name)
+		} else {
+			access.Disabled = append(access.Disabled, name)
+		}
+	}
+	return access
+}
+
+// ToolNames returns the names of all tools, sorted
+func ToolNames() []string {
+	names := make([]string, 0, len(registrations))
+	for _, r := range registrations {
+		names = append(names, r.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isReadOnlyTool(name string) bool {
+	r := registrationOf(name)
+	return r != nil && r.readOnly
+}
+
+func registrationOf(name string) *toolRegistration {
+	for i := range registrations {
+		if registrations[i].name == name {
+			return &registrations[i]
+		}
+	}
+	return nil
+}
+
+func containsTool(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
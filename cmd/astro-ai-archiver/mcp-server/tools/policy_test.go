@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToolPolicy(t *testing.T) {
+	if len(ToolNames()) != len(registrations) {
+		t.Fatalf("tool names are not unique: %v", ToolNames())
+	}
+
+	readOnly := ToolPolicy{ReadOnly: true}
+	for name, want := range map[string]bool{
+		"query_fits_archive":    true,
+		"execute_sql_query":     true,
+		"reset_database":        false,
+		"rescan_fits_directory": false,
+		"grade_frames":          false,
+		"export":                false,
+	} {
+		if got := readOnly.Allows(name); got != want {
+			t.Errorf("read-only policy allows %s = %v, want %v", name, got, want)
+		}
+	}
+
+	if (ToolPolicy{}).Allows("reset_database") != true || !(ToolPolicy{}).IsZero() {
+		t.Error("the empty policy must allow every tool")
+	}
+	deny := ToolPolicy{Deny: []string{"reset_database"}}
+	if deny.Allows("reset_database") || !deny.Allows("rescan_fits_directory") {
+		t.Error("deny list not applied")
+	}
+	allow := ToolPolicy{Allow: []string{"query_fits_archive", "reset_database"}, ReadOnly: true}
+	if !allow.Allows("query_fits_archive") || allow.Allows("get_archive_summary") || allow.Allows("reset_database") {
+		t.Error("allow list not applied together with read-only")
+	}
+
+	if err := (ToolPolicy{Deny: []string{"reset_databse"}}).Validate(); err == nil {
+		t.Error("a misspelled tool name was accepted")
+	}
+	if err := allow.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	// Every policy must allow a tool; an API key can only narrow the server's
+	access := toolAccess([]ToolPolicy{deny, {Allow: []string{"reset_database", "get_configuration", "list_sessions"}}})
+	if want := []string{"get_configuration", "list_sessions"}; !reflect.DeepEqual(access.Enabled, want) {
+		t.Errorf("enabled = %v, want %v", access.Enabled, want)
+	}
+	if len(access.Enabled)+len(access.Disabled) != len(registrations) || access.ReadOnly {
+		t.Errorf("access = %+v", access)
+	}
+}
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+func RegisterProjectProgress(s *mcp.Server, db Database) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "project_progress",
+		Description: "Integration collected for imaging projects versus their per-filter goals: done and remaining hours, frame counts and percentage per filter and overall, and the last night the target was imaged. Rejected frames are left out and duplicate copies of a frame count once. Without a name all projects are listed, which is the starting point for deciding what to shoot tonight.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"name": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: project name (default: all projects)",
+				},
+			},
+		},
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
+		log.Info().Str("tool", "project_progress").Interface("params", args).Msg("Tool called")
+
+		name, _ := args["name"].(string)
+		projects, err := db.ProjectProgress(name)
+		if err != nil {
+			log.Error().Err(err).Str("tool", "project_progress").Msg("Tool failed")
+			return nil, nil, fmt.Errorf("failed to compute progress: %w", err)
+		}
+
+		response := map[string]interface{}{
+			"projects": projects,
+			"count":    len(projects),
+		}
+
+		log.Trace().Str("tool", "project_progress").Interface("response", response).Msg("Tool response")
+
+		var text strings.Builder
+		if len(projects) == 0 {
+			text.WriteString("No projects defined")
+		}
+		for _, p := range projects {
+			fmt.Fprintf(&text, "%s (%s): %.1f%% done, %.2f h of %.2f h, %.2f h left\n",
+				p.Project.Name, p.Project.Target, p.Percent, p.DoneHours, p.GoalHours, p.RemainingHours)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: strings.TrimSpace(text.String())},
+			},
+		}, response, nil
+	})
+}
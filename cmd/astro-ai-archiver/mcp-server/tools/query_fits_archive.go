@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"reflect"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/rs/zerolog/log"
@@ -59,6 +60,130 @@ func RegisterQueryFitsArchive(s *mcp.Server, db Database) {
 					"type":        "number",
 					"description": "Maximum gain value",
 				},
+				"binning": map[string]interface{}{
+					"type":        "number",
+					"description": "Binning factor (exact match on XBINNING, e.g. 1 or 2)",
+				},
+				"ccd_temp_min": map[string]interface{}{
+					"type":        "number",
+					"description": "Minimum sensor temperature in °C (CCD-TEMP)",
+				},
+				"ccd_temp_max": map[string]interface{}{
+					"type":        "number",
+					"description": "Maximum sensor temperature in °C (CCD-TEMP)",
+				},
+				"set_temp": map[string]interface{}{
+					"type":        "number",
+					"description": "Cooler set point in °C (exact match on SET-TEMP)",
+				},
+				"airmass_min": map[string]interface{}{
+					"type":        "number",
+					"description": "Minimum airmass (AIRMASS header, else computed)",
+				},
+				"airmass_max": map[string]interface{}{
+					"type":        "number",
+					"description": "Maximum airmass (AIRMASS header, else computed)",
+				},
+				"altitude_min": map[string]interface{}{
+					"type":        "number",
+					"description": "Minimum target altitude in degrees (OBJCTALT header, else computed at mid-exposure)",
+				},
+				"bayer_pattern": map[string]interface{}{
+					"type":        "string",
+					"description": "Bayer pattern of colour sensors (exact match, e.g. 'RGGB')",
+				},
+				"pier_side": map[string]interface{}{
+					"type":        "string",
+					"description": "Pier side: 'EAST' or 'WEST'",
+				},
+				"readout_mode": map[string]interface{}{
+					"type":        "string",
+					"description": "Camera readout mode (partial match)",
+				},
+				"fwhm_min": map[string]interface{}{
+					"type":        "number",
+					"description": "Minimum median star FWHM in pixels (analyzed frames only, see analyze_frames)",
+				},
+				"fwhm_max": map[string]interface{}{
+					"type":        "number",
+					"description": "Maximum median star FWHM in pixels (analyzed frames only)",
+				},
+				"hfr_max": map[string]interface{}{
+					"type":        "number",
+					"description": "Maximum median half-flux radius in pixels (analyzed frames only)",
+				},
+				"eccentricity_max": map[string]interface{}{
+					"type":        "number",
+					"description": "Maximum median star eccentricity, 0 = round (analyzed frames only)",
+				},
+				"eccentricity_min": map[string]interface{}{
+					"type":        "number",
+					"description": "Minimum median star eccentricity (analyzed frames only)",
+				},
+				"star_count_min": map[string]interface{}{
+					"type":        "number",
+					"description": "Minimum number of detected stars (analyzed frames only)",
+				},
+				"star_count_max": map[string]interface{}{
+					"type":        "number",
+					"description": "Maximum number of detected stars (analyzed frames only)",
+				},
+				"moon_separation_min": map[string]interface{}{
+					"type":        "number",
+					"description": "Minimum angle between the Moon and the target in degrees (frames with ephemeris only)",
+				},
+				"moon_separation_max": map[string]interface{}{
+					"type":        "number",
+					"description": "Maximum angle between the Moon and the target in degrees, e.g. 40 for frames shot with the Moon close by",
+				},
+				"moon_illumination_max": map[string]interface{}{
+					"type":        "number",
+					"description": "Maximum illuminated fraction of the Moon, 0 = new to 1 = full",
+				},
+				"moon_alt_max": map[string]interface{}{
+					"type":        "number",
+					"description": "Maximum Moon altitude in degrees; 0 selects frames shot with the Moon below the horizon",
+				},
+				"humidity_max": map[string]interface{}{
+					"type":        "number",
+					"description": "Maximum relative humidity in % (HUMIDITY header; frames without it are left out)",
+				},
+				"sky_quality_min": map[string]interface{}{
+					"type":        "number",
+					"description": "Minimum sky quality in mag/arcsec² (SKYQUAL or MPSAS header; frames without it are left out)",
+				},
+				"cloud_cover_max": map[string]interface{}{
+					"type":        "number",
+					"description": "Maximum cloud cover in % (CLOUDCVR header; frames without it are left out)",
+				},
+				"wind_speed_max": map[string]interface{}{
+					"type":        "number",
+					"description": "Maximum wind speed (WINDSPD header; frames without it are left out)",
+				},
+				"grade": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"accepted", "rejected", "ungraded"},
+					"description": "Only frames with this grade (see grade_frames)",
+				},
+				"include_rejected": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Include frames graded as rejected (default: false)",
+					"default":     false,
+				},
+				"analyzed": map[string]interface{}{
+					"type":        "boolean",
+					"description": "true: only frames measured by analyze_frames, false: only frames not analyzed yet (including frames with N.I.N.A. metrics only)",
+				},
+				"sort_by": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"time", "exposure", "fwhm", "hfr", "eccentricity", "star_count", "background", "noise"},
+					"description": "Sort order (default: time, newest first). Quality metrics sort best first; frames without metrics come last.",
+				},
+				"sort_order": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"asc", "desc"},
+					"description": "Optional: override the sort direction",
+				},
 				"limit": map[string]interface{}{
 					"type":        "number",
 					"description": "Maximum number of results to return (default: 100, max: 1000)",
@@ -94,13 +219,8 @@ func RegisterQueryFitsArchive(s *mcp.Server, db Database) {
 
 		// Get count from the result (files can be a slice of any type)
 		var count int
-		if filesSlice, ok := files.([]interface{}); ok {
-			count = len(filesSlice)
-		} else {
-			// Try to get length via reflection as fallback
-			if v := fmt.Sprintf("%v", files); v != "" {
-				count = 0 // Default if we can't determine
-			}
+		if v := reflect.ValueOf(files); v.Kind() == reflect.Slice {
+			count = v.Len()
 		}
 
 		response := map[string]interface{}{
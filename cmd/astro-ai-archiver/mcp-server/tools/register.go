@@ -13,19 +13,76 @@ var (
 	scanState = &ScanState{}
 )
 
-// RegisterAll registers all MCP tools
-func RegisterAll(s *mcp.Server, db Database, cfg Config, scanDirs []string, recursive bool, version string) {
-	RegisterQueryFitsArchive(s, db)
-	RegisterGetFileDetails(s, db)
-	RegisterGetArchiveSummary(s, db)
-	RegisterRescanFitsDirectory(s, db, scanDirs, recursive)
-	RegisterGetScanStatus(s)
-	RegisterGetConfiguration(s, cfg, db.GetFilePath(), version)
-	RegisterExecuteSqlQuery(s, db)
-	RegisterGetDatabaseSchema(s)
-	RegisterResetDatabase(s, db)
+// registerContext holds what the tools are registered with
+type registerContext struct {
+	db        Database
+	cfg       Config
+	scanDirs  []string
+	recursive bool
+	version   string
+	access    ToolAccess
+}
+
+// toolRegistration registers one tool. Read-only tools neither change the
+// archive nor write files outside caches, so they stay available in
+// read-only mode.
+type toolRegistration struct {
+	name     string
+	readOnly bool
+	register func(s *mcp.Server, c *registerContext)
+}
+
+var registrations = []toolRegistration{
+	{"query_fits_archive", true, func(s *mcp.Server, c *registerContext) { RegisterQueryFitsArchive(s, c.db) }},
+	{"get_file_details", true, func(s *mcp.Server, c *registerContext) { RegisterGetFileDetails(s, c.db) }},
+	{"get_archive_summary", true, func(s *mcp.Server, c *registerContext) { RegisterGetArchiveSummary(s, c.db) }},
+	{"rescan_fits_directory", false, func(s *mcp.Server, c *registerContext) {
+		RegisterRescanFitsDirectory(s, c.db, c.scanDirs, c.recursive)
+	}},
+	{"get_scan_status", true, func(s *mcp.Server, c *registerContext) { RegisterGetScanStatus(s) }},
+	{"get_configuration", true, func(s *mcp.Server, c *registerContext) {
+		RegisterGetConfiguration(s, c.cfg, c.db.GetFilePath(), c.version, c.access)
+	}},
+	{"execute_sql_query", true, func(s *mcp.Server, c *registerContext) { RegisterExecuteSqlQuery(s, c.db) }},
+	{"get_database_schema", true, func(s *mcp.Server, c *registerContext) { RegisterGetDatabaseSchema(s) }},
+	{"reset_database", false, func(s *mcp.Server, c *registerContext) { RegisterResetDatabase(s, c.db) }},
+	{"cone_search", true, func(s *mcp.Server, c *registerContext) { RegisterConeSearch(s, c.db) }},
+	{"analyze_frames", false, func(s *mcp.Server, c *registerContext) { RegisterAnalyzeFrames(s, c.db) }},
+	{"get_frame_preview", true, func(s *mcp.Server, c *registerContext) { RegisterGetFramePreview(s, c.db) }},
+	{"grade_frames", false, func(s *mcp.Server, c *registerContext) { RegisterGradeFrames(s, c.db) }},
+	{"list_sessions", true, func(s *mcp.Server, c *registerContext) { RegisterListSessions(s, c.db) }},
+	{"get_session", true, func(s *mcp.Server, c *registerContext) { RegisterGetSession(s, c.db) }},
+	{"save_project", false, func(s *mcp.Server, c *registerContext) { RegisterSaveProject(s, c.db) }},
+	{"delete_project", false, func(s *mcp.Server, c *registerContext) { RegisterDeleteProject(s, c.db) }},
+	{"project_progress", true, func(s *mcp.Server, c *registerContext) { RegisterProjectProgress(s, c.db) }},
+	{"match_calibration", true, func(s *mcp.Server, c *registerContext) { RegisterMatchCalibration(s, c.db) }},
+	{"export_stacking_set", false, func(s *mcp.Server, c *registerContext) { RegisterExportStackingSet(s, c.db) }},
+	{"export", false, func(s *mcp.Server, c *registerContext) { RegisterExport(s, c.db) }},
+	{"astrobin_acquisitions", true, func(s *mcp.Server, c *registerContext) { RegisterAstroBinAcquisitions(s, c.db) }},
+	{"guiding_report", true, func(s *mcp.Server, c *registerContext) { RegisterGuidingReport(s, c.db) }},
+	{"conditions_report", true, func(s *mcp.Server, c *registerContext) { RegisterConditionsReport(s, c.db) }},
+	{"imaging_efficiency", true, func(s *mcp.Server, c *registerContext) { RegisterImagingEfficiency(s, c.db) }},
+	{"plan_night", true, func(s *mcp.Server, c *registerContext) { RegisterPlanNight(s, c.db) }},
+}
+
+// RegisterAll registers the MCP tools that the tool policy of the
+// configuration and the extra policies (e.g. of an API key) all allow
+func RegisterAll(s *mcp.Server, db Database, cfg Config, scanDirs []string, recursive bool, version string, policies ...ToolPolicy) {
+	c := &registerContext{
+		db:        db,
+		cfg:       cfg,
+		scanDirs:  scanDirs,
+		recursive: recursive,
+		version:   version,
+		access:    toolAccess(append([]ToolPolicy{cfg.GetToolPolicy()}, policies...)),
+	}
+	for _, r := range registrations {
+		if containsTool(c.access.Enabled, r.name) {
+			r.register(s, c)
+		}
+	}
 
-	log.Info().Int("tools", 9).Msg("MCP tools registered")
+	log.Info().Int("tools", len(c.access.Enabled)).Strs("disabled", c.access.Disabled).Msg("MCP tools registered")
 }
 
 // GetScanState returns the current scan state (for external access)
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/rs/zerolog/log"
@@ -11,7 +12,7 @@ import (
 func RegisterResetDatabase(s *mcp.Server, db Database) {
 	mcp.AddTool(s, &mcp.Tool{
 		Name:        "reset_database",
-		Description: "Delete records from the FITS archive database. When 'year' is provided only records for that observation year are removed; otherwise all records are deleted. The schema is preserved. A rescan is required afterwards to repopulate.",
+		Description: "Delete records from the FITS archive database. When 'year' is provided only records for that observation year are removed; otherwise all records are deleted. The schema is preserved. A rescan is required afterwards to repopulate. This takes two steps: a call without confirm_token is a dry run that returns the number of records that would be deleted and a confirm_token valid for 5 minutes; show the count to the user and only call again with the same year and the confirm_token when they agree. Clients that support elicitation ask the user directly instead. The database is backed up before anything is deleted.",
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
@@ -19,32 +20,104 @@ func RegisterResetDatabase(s *mcp.Server, db Database) {
 					"type":        "integer",
 					"description": "Optional: four-digit observation year to delete (e.g. 2023). When omitted all records are deleted.",
 				},
+				"confirm_token": map[string]interface{}{
+					"type":        "string",
+					"description": "Token from the dry run, to delete the records it counted",
+				},
 			},
 		},
 	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
 		log.Info().Str("tool", "reset_database").Interface("params", args).Msg("Tool called")
 
-		var n int64
-		var err error
-		var scope string
-
+		year := 0
+		scope := "all years"
 		if yearVal, ok := args["year"]; ok && yearVal != nil {
 			// JSON numbers arrive as float64
 			yearFloat, ok := yearVal.(float64)
 			if !ok {
 				return nil, nil, fmt.Errorf("year must be an integer")
 			}
-			year := int(yearFloat)
+			year = int(yearFloat)
 			if year < 1900 || year > 2100 {
 				return nil, nil, fmt.Errorf("year %d is out of range (1900-2100)", year)
 			}
-			n, err = db.DeleteFilesByYear(year)
 			scope = fmt.Sprintf("year %d", year)
+		}
+
+		if token, _ := args["confirm_token"].(string); token != "" {
+			if err := useConfirmation(req, token, "reset_database", scope); err != nil {
+				log.Error().Err(err).Msg("Tool failed")
+				return nil, nil, fmt.Errorf("reset_database: %w", err)
+			}
 		} else {
-			n, err = db.DeleteAllFiles()
-			scope = "all years"
+			var n int64
+			var err error
+			if year != 0 {
+				n, err = db.CountFilesByYear(year)
+			} else {
+				n, err = db.CountAllFiles()
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("Tool failed")
+				return nil, nil, fmt.Errorf("reset_database: %w", err)
+			}
+			if n == 0 {
+				result := map[string]interface{}{
+					"status":          "nothing_to_delete",
+					"records_deleted": 0,
+					"scope":           scope,
+				}
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("No records for %s; nothing deleted.", scope)}},
+				}, result, nil
+			}
+
+			confirmed, asked := elicitConfirmation(ctx, req, fmt.Sprintf("Delete %d record(s) for %s from the FITS archive? A backup is written first.", n, scope))
+			if asked && !confirmed {
+				result := map[string]interface{}{
+					"status":          "cancelled",
+					"records_deleted": 0,
+					"scope":           scope,
+				}
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{&mcp.TextContent{Text: "Reset cancelled by the user; nothing deleted."}},
+				}, result, nil
+			}
+			if !asked {
+				token, expires, err := newConfirmation(req, "reset_database", scope)
+				if err != nil {
+					log.Error().Err(err).Msg("Tool failed")
+					return nil, nil, fmt.Errorf("reset_database: %w", err)
+				}
+				result := map[string]interface{}{
+					"status":            "confirmation_required",
+					"records_to_delete": n,
+					"scope":             scope,
+					"confirm_token":     token,
+					"expires_at":        expires.UTC().Format(time.RFC3339),
+				}
+				log.Trace().Str("tool", "reset_database").Interface("response", result).Msg("Tool response")
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: fmt.Sprintf("Dry run: %d record(s) for %s would be deleted. Ask the user to confirm, then call reset_database again with the same year and confirm_token %q within %s.", n, scope, token, confirmationTTL)},
+					},
+				}, result, nil
+			}
+		}
+
+		backup, err := db.Backup("pre-reset")
+		if err != nil {
+			log.Error().Err(err).Msg("Tool failed")
+			return nil, nil, fmt.Errorf("reset_database: nothing deleted: %w", err)
 		}
+		log.Info().Str("backup", backup).Msg("Database backed up before reset")
 
+		var n int64
+		if year != 0 {
+			n, err = db.DeleteFilesByYear(year)
+		} else {
+			n, err = db.DeleteAllFiles()
+		}
 		if err != nil {
 			log.Error().Err(err).Msg("reset_database failed")
 			return nil, nil, fmt.Errorf("reset_database: %w", err)
@@ -54,6 +127,7 @@ func RegisterResetDatabase(s *mcp.Server, db Database) {
 			"status":          "ok",
 			"records_deleted": n,
 			"scope":           scope,
+			"backup":          backup,
 			"message":         fmt.Sprintf("Deleted %d record(s) for %s. Use rescan_fits_directory to repopulate.", n, scope),
 		}
 
@@ -61,7 +135,7 @@ func RegisterResetDatabase(s *mcp.Server, db Database) {
 
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
-				&mcp.TextContent{Text: fmt.Sprintf("Database reset (%s): %d record(s) deleted.", scope, n)},
+				&mcp.TextContent{Text: fmt.Sprintf("Database reset (%s): %d record(s) deleted. Backup: %s", scope, n, backup)},
 			},
 		}, result, nil
 	})
@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+func RegisterSaveProject(s *mcp.Server, db Database) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "save_project",
+		Description: "Create or replace an imaging project: a target with integration goals in hours per filter, e.g. name 'NGC7000 SHO', target 'NGC7000', goals {\"Ha\": 15, \"OIII\": 10, \"SII\": 10}. Saving an existing name replaces its target, equipment and all goals. Track progress with project_progress.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"name": map[string]interface{}{
+					"type":        "string",
+					"description": "Unique project name",
+				},
+				"target": map[string]interface{}{
+					"type":        "string",
+					"description": "Target object name as stored in the archive (exact match, case-insensitive)",
+				},
+				"goals": map[string]interface{}{
+					"type":                 "object",
+					"additionalProperties": map[string]interface{}{"type": "number"},
+					"description":          "Integration goal in hours per filter name",
+				},
+				"telescope": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: only count frames taken with this telescope (partial match)",
+				},
+				"camera": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: only count frames taken with this camera (partial match)",
+				},
+				"notes": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional: free-form notes",
+				},
+			},
+			"required": []string{"name", "target", "goals"},
+		},
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]interface{}) (*mcp.CallToolResult, interface{}, error) {
+		log.Info().Str("tool", "save_project").Interface("params", args).Msg("Tool called")
+
+		project := Project{Goals: map[string]float64{}}
+		project.Name, _ = args["name"].(string)
+		project.Target, _ = args["target"].(string)
+		project.Telescope, _ = args["telescope"].(string)
+		project.Camera, _ = args["camera"].(string)
+		project.Notes, _ = args["notes"].(string)
+		if goals, ok := args["goals"].(map[string]interface{}); ok {
+			for filter, v := range goals {
+				hours, ok := v.(float64)
+				if !ok {
+					log.Error().Str("tool", "save_project").Str("filter", filter).Msg("Tool failed: goal is not a number")
+					return nil, nil, fmt.Errorf("goal for filter %s must be a number of hours", filter)
+				}
+				project.Goals[filter] = hours
+			}
+		}
+
+		saved, err := db.SaveProject(project)
+		if err != nil {
+			log.Error().Err(err).Str("tool", "save_project").Msg("Tool failed")
+			return nil, nil, fmt.Errorf("failed to save project: %w", err)
+		}
+
+		response := map[string]interface{}{
+			"project": saved,
+		}
+
+		log.Trace().Str("tool", "save_project").Interface("response", response).Msg("Tool response")
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Project %q saved with %d filter goals", saved.Name, len(saved.Goals))},
+			},
+		}, response, nil
+	})
+}
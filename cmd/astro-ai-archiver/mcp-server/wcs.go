@@ -0,0 +1,121 @@
+package mcpserver
+
+import (
+	"strings"
+
+	"github.com/astrogo/fitsio"
+	"github.com/rs/zerolog/log"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server/astro"
+)
+
+// extractWCS parses the plate-solve keywords of a FITS header and returns the
+// solved field geometry. It returns nil when the header carries no usable WCS.
+//
+// Supported keyword conventions:
+//   - CD matrix: CD1_1, CD1_2, CD2_1, CD2_2
+//   - PC matrix with CDELT1/CDELT2
+//   - CDELT1/CDELT2 with optional CROTA2
+func (s *Scanner) extractWCS(header *fitsio.Header) *FrameWCS {
+	// Only celestial TAN-type projections are supported (TAN, TAN-SIP, TPV)
+	if ctype := strings.ToUpper(getStringHeader(header, "CTYPE1")); ctype != "" {
+		tangent := strings.Contains(ctype, "TAN") || strings.Contains(ctype, "TPV")
+		if !strings.HasPrefix(ctype, "RA") || !tangent {
+			log.Debug().Str("ctype1", ctype).Msg("Unsupported WCS projection, ignoring")
+			return nil
+		}
+	}
+
+	crval1 := getFloatHeader(header, "CRVAL1")
+	crval2 := getFloatHeader(header, "CRVAL2")
+	if crval1 == nil || crval2 == nil {
+		return nil
+	}
+
+	width := getIntHeader(header, "NAXIS1")
+	height := getIntHeader(header, "NAXIS2")
+	if width == nil || height == nil || *width <= 0 || *height <= 0 {
+		return nil
+	}
+
+	// Reference pixel defaults to the image centre when absent
+	crpix1 := float64(*width+1) / 2
+	if v := getFloatHeader(header, "CRPIX1"); v != nil {
+		crpix1 = *v
+	}
+	crpix2 := float64(*height+1) / 2
+	if v := getFloatHeader(header, "CRPIX2"); v != nil {
+		crpix2 = *v
+	}
+
+	var w astro.WCS
+	cd11 := getFloatHeader(header, "CD1_1")
+	cd22 := getFloatHeader(header, "CD2_2")
+	cdelt1 := getFloatHeader(header, "CDELT1")
+	cdelt2 := getFloatHeader(header, "CDELT2")
+
+	switch {
+	case cd11 != nil && cd22 != nil:
+		w = astro.WCS{CRVAL1: *crval1, CRVAL2: *crval2, CRPIX1: crpix1, CRPIX2: crpix2}
+		w.CD[0][0] = *cd11
+		w.CD[1][1] = *cd22
+		if v := getFloatHeader(header, "CD1_2"); v != nil {
+			w.CD[0][1] = *v
+		}
+		if v := getFloatHeader(header, "CD2_1"); v != nil {
+			w.CD[1][0] = *v
+		}
+
+	case cdelt1 != nil && cdelt2 != nil && getFloatHeader(header, "PC1_1") != nil:
+		w = astro.WCS{CRVAL1: *crval1, CRVAL2: *crval2, CRPIX1: crpix1, CRPIX2: crpix2}
+		pc := [2][2]float64{{1, 0}, {0, 1}}
+		for i, row := range [][]string{{"PC1_1", "PC1_2"}, {"PC2_1", "PC2_2"}} {
+			for j, key := range row {
+				if v := getFloatHeader(header, key); v != nil {
+					pc[i][j] = *v
+				}
+			}
+		}
+		w.CD[0][0] = *cdelt1 * pc[0][0]
+		w.CD[0][1] = *cdelt1 * pc[0][1]
+		w.CD[1][0] = *cdelt2 * pc[1][0]
+		w.CD[1][1] = *cdelt2 * pc[1][1]
+
+	case cdelt1 != nil && cdelt2 != nil:
+		crota2 := 0.0
+		if v := getFloatHeader(header, "CROTA2", "CROTA1"); v != nil {
+			crota2 = *v
+		}
+		w = astro.NewWCSFromCDELT(*crval1, *crval2, crpix1, crpix2, *cdelt1, *cdelt2, crota2)
+
+	default:
+		return nil
+	}
+
+	if w.PixelScale() == 0 {
+		return nil
+	}
+
+	return frameWCSFromSolution(w, *width, *height)
+}
+
+// frameWCSFromSolution derives the stored field geometry from a WCS solution
+// for an image of the given dimensions.
+func frameWCSFromSolution(w astro.WCS, width, height int) *FrameWCS {
+	fw := &FrameWCS{
+		Rotation:   w.Rotation(),
+		PixelScale: w.PixelScale(),
+	}
+
+	fw.CenterRA, fw.CenterDec = w.PixelToSky(float64(width+1)/2, float64(height+1)/2)
+
+	// Corners are taken at the outer pixel edges (pixel centres are integers)
+	right := float64(width) + 0.5
+	top := float64(height) + 0.5
+	fw.Corner1RA, fw.Corner1Dec = w.PixelToSky(0.5, 0.5)
+	fw.Corner2RA, fw.Corner2Dec = w.PixelToSky(right, 0.5)
+	fw.Corner3RA, fw.Corner3Dec = w.PixelToSky(right, top)
+	fw.Corner4RA, fw.Corner4Dec = w.PixelToSky(0.5, top)
+
+	return fw
+}
@@ -0,0 +1,123 @@
+package mcpserver
+
+import (
+	"math"
+	"testing"
+
+	"github.com/astrogo/fitsio"
+)
+
+func newTestHeader(cards ...fitsio.Card) *fitsio.Header {
+	return fitsio.NewHeader(cards, fitsio.IMAGE_HDU, 16, []int{1000, 800})
+}
+
+func assertClose(t *testing.T, name string, got, want, tol float64) {
+	t.Helper()
+	if math.Abs(got-want) > tol {
+		t.Errorf("%s = %.6f, expected %.6f (±%g)", name, got, want, tol)
+	}
+}
+
+func TestExtractWCSFromCDMatrix(t *testing.T) {
+	s := &Scanner{}
+	header := newTestHeader(
+		fitsio.Card{Name: "CTYPE1", Value: "RA---TAN"},
+		fitsio.Card{Name: "CTYPE2", Value: "DEC--TAN"},
+		fitsio.Card{Name: "CRVAL1", Value: 83.8},
+		fitsio.Card{Name: "CRVAL2", Value: -5.4},
+		fitsio.Card{Name: "CRPIX1", Value: 500.5},
+		fitsio.Card{Name: "CRPIX2", Value: 400.5},
+		fitsio.Card{Name: "CD1_1", Value: -1.0 / 3600},
+		fitsio.Card{Name: "CD1_2", Value: 0.0},
+		fitsio.Card{Name: "CD2_1", Value: 0.0},
+		fitsio.Card{Name: "CD2_2", Value: 1.0 / 3600},
+	)
+
+	wcs := s.extractWCS(header)
+	if wcs == nil {
+		t.Fatal("extractWCS returned nil for a valid CD matrix header")
+	}
+
+	assertClose(t, "center_ra", wcs.CenterRA, 83.8, 1e-9)
+	assertClose(t, "center_dec", wcs.CenterDec, -5.4, 1e-9)
+	assertClose(t, "pixel_scale", wcs.PixelScale, 1.0, 1e-9)
+	assertClose(t, "rotation", wcs.Rotation, 0, 1e-9)
+
+	// CD1_1 < 0, so low x is east (higher RA) and low y is south.
+	assertClose(t, "corner1_dec", wcs.Corner1Dec, -5.4-400.0/3600, 1e-4)
+	if wcs.Corner1RA <= 83.8 {
+		t.Errorf("corner1_ra = %.6f, expected east of the centre (> 83.8)", wcs.Corner1RA)
+	}
+	if wcs.Corner2RA >= 83.8 {
+		t.Errorf("corner2_ra = %.6f, expected west of the centre (< 83.8)", wcs.Corner2RA)
+	}
+	assertClose(t, "corner3_dec", wcs.Corner3Dec, -5.4+400.0/3600, 1e-4)
+}
+
+func TestExtractWCSFromCDELT(t *testing.T) {
+	s := &Scanner{}
+	header := newTestHeader(
+		fitsio.Card{Name: "CRVAL1", Value: 359.9},
+		fitsio.Card{Name: "CRVAL2", Value: 41.27},
+		fitsio.Card{Name: "CDELT1", Value: -2.0 / 3600},
+		fitsio.Card{Name: "CDELT2", Value: 2.0 / 3600},
+		fitsio.Card{Name: "CROTA2", Value: 30.0},
+	)
+
+	wcs := s.extractWCS(header)
+	if wcs == nil {
+		t.Fatal("extractWCS returned nil for a valid CDELT/CROTA2 header")
+	}
+
+	// CRPIX defaults to the image centre
+	assertClose(t, "center_ra", wcs.CenterRA, 359.9, 1e-9)
+	assertClose(t, "center_dec", wcs.CenterDec, 41.27, 1e-9)
+	assertClose(t, "pixel_scale", wcs.PixelScale, 2.0, 1e-9)
+	assertClose(t, "rotation", wcs.Rotation, 30, 1e-9)
+
+	// Corners near RA 0 must stay normalized to [0, 360)
+	for _, ra := range []float64{wcs.Corner1RA, wcs.Corner2RA, wcs.Corner3RA, wcs.Corner4RA} {
+		if ra < 0 || ra >= 360 {
+			t.Errorf("corner RA %.6f is not normalized to [0, 360)", ra)
+		}
+	}
+}
+
+func TestExtractWCSMissingOrUnsupported(t *testing.T) {
+	s := &Scanner{}
+
+	tests := []struct {
+		name   string
+		header *fitsio.Header
+	}{
+		{
+			name:   "No WCS keywords",
+			header: newTestHeader(fitsio.Card{Name: "OBJECT", Value: "M31"}),
+		},
+		{
+			name: "Reference point without scale",
+			header: newTestHeader(
+				fitsio.Card{Name: "CRVAL1", Value: 10.0},
+				fitsio.Card{Name: "CRVAL2", Value: 41.0},
+			),
+		},
+		{
+			name: "Non-celestial projection",
+			header: newTestHeader(
+				fitsio.Card{Name: "CTYPE1", Value: "GLON-CAR"},
+				fitsio.Card{Name: "CRVAL1", Value: 10.0},
+				fitsio.Card{Name: "CRVAL2", Value: 41.0},
+				fitsio.Card{Name: "CDELT1", Value: -0.001},
+				fitsio.Card{Name: "CDELT2", Value: 0.001},
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if wcs := s.extractWCS(tt.header); wcs != nil {
+				t.Errorf("extractWCS() = %+v, expected nil", wcs)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server"
+)
+
+var queryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "List frames matching filters",
+	Long: `Print the frames matching the filter flags, with the filters of the
+query_fits_archive tool. The table shows the main columns; CSV and JSON carry
+every file column plus quality metrics and grade. Rejected frames are left out
+unless --include-rejected is set.`,
+	Example: `  astro-ai-archiver query --target M31 --filter Ha
+  astro-ai-archiver query --fwhm-max 3 --moon-separation-min 60 --format csv > subs.csv`,
+	SilenceUsage: true,
+	RunE:         mcpserver.RunQuery,
+}
+
+func init() {
+	queryCmd.Flags().StringP("config", "c", "config.yaml", "Path to configuration file")
+	queryCmd.Flags().String("format", "table", "Output format: table, csv, json or jsonl")
+	queryCmd.Flags().Int("limit", 100, "Maximum number of frames (-1 for all)")
+	queryCmd.Flags().Int("offset", 0, "Number of frames to skip")
+
+	queryCmd.Flags().StringP("target", "t", "", "Target object name (partial match)")
+	queryCmd.Flags().StringP("filter", "f", "", "Filter name (exact match)")
+	queryCmd.Flags().String("telescope", "", "Telescope identifier (partial match)")
+	queryCmd.Flags().String("camera", "", "Camera identifier (partial match)")
+	queryCmd.Flags().String("software", "", "Acquisition software (partial match)")
+	queryCmd.Flags().String("date-from", "", "Start date (YYYY-MM-DD or YYYY-MM-DD HH:MM:SS)")
+	queryCmd.Flags().String("date-to", "", "End date (YYYY-MM-DD or YYYY-MM-DD HH:MM:SS)")
+	queryCmd.Flags().String("pier-side", "", "Pier side: EAST or WEST")
+	queryCmd.Flags().String("readout-mode", "", "Camera readout mode (partial match)")
+	queryCmd.Flags().String("bayer-pattern", "", "Bayer pattern (e.g. RGGB)")
+	queryCmd.Flags().String("grade", "", "Frame grade: accepted, rejected or ungraded")
+	queryCmd.Flags().String("sort-by", "", "Sort order: time (default), exposure, fwhm, hfr, eccentricity, star_count, background or noise")
+	queryCmd.Flags().String("sort-order", "", "Sort order: asc or desc")
+
+	queryCmd.Flags().Float64("min-exposure", 0, "Minimum exposure time in seconds")
+	queryCmd.Flags().Float64("max-exposure", 0, "Maximum exposure time in seconds")
+	queryCmd.Flags().Float64("gain-min", 0, "Minimum gain")
+	queryCmd.Flags().Float64("gain-max", 0, "Maximum gain")
+	queryCmd.Flags().Float64("binning", 0, "Binning factor (e.g. 1 or 2)")
+	queryCmd.Flags().Float64("ccd-temp-min", 0, "Minimum sensor temperature in °C")
+	queryCmd.Flags().Float64("ccd-temp-max", 0, "Maximum sensor temperature in °C")
+	queryCmd.Flags().Float64("set-temp", 0, "Cooler set point in °C")
+	queryCmd.Flags().Float64("airmass-min", 0, "Minimum airmass")
+	queryCmd.Flags().Float64("airmass-max", 0, "Maximum airmass")
+	queryCmd.Flags().Float64("altitude-min", 0, "Minimum target altitude in degrees")
+	queryCmd.Flags().Float64("fwhm-min", 0, "Minimum median star FWHM in pixels")
+	queryCmd.Flags().Float64("fwhm-max", 0, "Maximum median star FWHM in pixels")
+	queryCmd.Flags().Float64("hfr-max", 0, "Maximum median star HFR in pixels")
+	queryCmd.Flags().Float64("eccentricity-min", 0, "Minimum median star eccentricity")
+	queryCmd.Flags().Float64("eccentricity-max", 0, "Maximum median star eccentricity")
+	queryCmd.Flags().Float64("star-count-min", 0, "Minimum number of detected stars")
+	queryCmd.Flags().Float64("star-count-max", 0, "Maximum number of detected stars")
+	queryCmd.Flags().Float64("humidity-max", 0, "Maximum relative humidity in %")
+	queryCmd.Flags().Float64("sky-quality-min", 0, "Minimum sky quality in mag/arcsec²")
+	queryCmd.Flags().Float64("cloud-cover-max", 0, "Maximum cloud cover in %")
+	queryCmd.Flags().Float64("wind-speed-max", 0, "Maximum wind speed in m/s")
+	queryCmd.Flags().Float64("moon-separation-min", 0, "Minimum Moon separation in degrees")
+	queryCmd.Flags().Float64("moon-separation-max", 0, "Maximum Moon separation in degrees")
+	queryCmd.Flags().Float64("moon-illumination-max", 0, "Maximum Moon illumination (0-1)")
+	queryCmd.Flags().Float64("moon-alt-max", 0, "Maximum Moon altitude in degrees")
+
+	queryCmd.Flags().Bool("include-rejected", false, "Include rejected frames")
+	queryCmd.Flags().Bool("analyzed", false, "Only frames with (true) or without (false) quality metrics")
+}
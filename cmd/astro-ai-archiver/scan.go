@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server"
+)
+
+var scanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Scan the configured directories for FITS files",
+	Long: `Index new and changed FITS files, N.I.N.A. metadata and PHD2 guide logs
+in the scan directories from the configuration, then refresh sessions, the same
+way the MCP server does. A progress bar is drawn on a terminal. The command
+exits with a non-zero status when files could not be read.`,
+	Example: `  astro-ai-archiver scan --config config.yaml
+  astro-ai-archiver scan --force --no-progress >> scan.log`,
+	SilenceUsage: true,
+	RunE:         mcpserver.RunScan,
+}
+
+func init() {
+	scanCmd.Flags().StringP("config", "c", "config.yaml", "Path to configuration file")
+	scanCmd.Flags().Bool("force", false, "Rescan all files, ignoring modification times")
+	scanCmd.Flags().Bool("no-progress", false, "Do not draw the progress bar")
+}
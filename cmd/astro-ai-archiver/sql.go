@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server"
+)
+
+var sqlCmd = &cobra.Command{
+	Use:   "sql <query>",
+	Short: "Run a read-only SQL query",
+	Long: `Run a SELECT query against the archive database and print its rows. The
+same validation as the execute_sql_query tool applies: only SELECT queries, no
+statements that change data.`,
+	Example: `  astro-ai-archiver sql "SELECT object, filter, SUM(exposure)/3600.0 AS hours FROM usable_files GROUP BY 1, 2"
+  astro-ai-archiver sql --format json "SELECT * FROM sessions ORDER BY observation_date DESC LIMIT 5"`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE:         mcpserver.RunSQL,
+}
+
+func init() {
+	sqlCmd.Flags().StringP("config", "c", "config.yaml", "Path to configuration file")
+	sqlCmd.Flags().String("format", "table", "Output format: table, csv, json or jsonl")
+}
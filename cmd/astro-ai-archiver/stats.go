@@ -0,0 +1,24 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/yourusername/astro-ai-archiver/cmd/astro-ai-archiver/mcp-server"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show archive statistics",
+	Long: `Print the archive summary of the get_archive_summary tool: number of
+files, total integration, date range and the targets, filters, telescopes and
+cameras in the archive. Rejected frames are left out unless
+--include-rejected is set.`,
+	SilenceUsage: true,
+	RunE:         mcpserver.RunStats,
+}
+
+func init() {
+	statsCmd.Flags().StringP("config", "c", "config.yaml", "Path to configuration file")
+	statsCmd.Flags().String("format", "text", "Output format: text or json")
+	statsCmd.Flags().Bool("include-rejected", false, "Include rejected frames in the totals")
+}